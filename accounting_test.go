@@ -0,0 +1,36 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+)
+
+func TestIORegistry(t *testing.T) {
+	reg := NewIORegistry()
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(NewLabeledWriter(buf, reg, "tenant-a"), 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890"))
+	cw.Close()
+	w := NewLabeledWriter(&bytes.Buffer{}, reg, "tenant-b")
+	w.Write([]byte("abc"))
+	w.Write([]byte("def"))
+	v, err := ioutil.ReadAll(NewLabeledReader(bytes.NewReader(buf.Bytes()), reg, "tenant-b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := reg.Snapshot()
+	// The ChecksummedWriter writes the first interval's content and checksum
+	// separately, then the trailing partial interval.
+	if s["tenant-a"] != (IOCounts{WriteBytes: 24, WriteOps: 3}) {
+		t.Fatalf("%#v", s["tenant-a"])
+	}
+	if b := s["tenant-b"]; b.WriteBytes != 6 || b.WriteOps != 2 || b.ReadBytes != uint64(len(v)) || b.ReadOps == 0 {
+		t.Fatalf("%#v", b)
+	}
+	reg.Reset()
+	if len(reg.Snapshot()) != 0 {
+		t.Fatal(reg.Snapshot())
+	}
+}
@@ -0,0 +1,71 @@
+package brimio
+
+import "fmt"
+
+// PadFinal determines how an Alignment treats a final partial block.
+type PadFinal int
+
+const (
+	// PadFinalNone leaves a final partial block short.
+	PadFinalNone PadFinal = iota
+	// PadFinalBlock pads a final partial block out to the full block size.
+	PadFinalBlock
+)
+
+// Alignment describes the physical geometry content is laid out with: the
+// size blocks must be multiples of, the byte used to pad them, and whether a
+// final partial block is padded. Layers sharing one Alignment will agree on
+// where blocks fall rather than each having separate knobs.
+type Alignment struct {
+	// BlockSize is the size blocks are aligned to; 0 or 1 means no alignment.
+	BlockSize int
+	// PadByte is the value used for any padding.
+	PadByte byte
+	// PadFinal is how a final partial block is treated.
+	PadFinal PadFinal
+}
+
+// Validate returns an error if the Alignment is not usable.
+func (a Alignment) Validate() error {
+	if a.BlockSize < 0 {
+		return fmt.Errorf("invalid alignment block size %d", a.BlockSize)
+	}
+	if a.PadFinal != PadFinalNone && a.PadFinal != PadFinalBlock {
+		return fmt.Errorf("invalid alignment pad final %d", a.PadFinal)
+	}
+	return nil
+}
+
+// Align returns n rounded up to the next multiple of the BlockSize.
+func (a Alignment) Align(n int64) int64 {
+	if a.BlockSize <= 1 {
+		return n
+	}
+	if r := n % int64(a.BlockSize); r != 0 {
+		n += int64(a.BlockSize) - r
+	}
+	return n
+}
+
+// Aligned returns true if n is a multiple of the BlockSize.
+func (a Alignment) Aligned(n int64) bool {
+	return a.Align(n) == n
+}
+
+// Padding returns how many bytes of padding follow n bytes of content that
+// end the content, according to PadFinal.
+func (a Alignment) Padding(n int64) int {
+	if a.PadFinal == PadFinalNone {
+		return 0
+	}
+	return int(a.Align(n) - n)
+}
+
+// Pad appends the padding for the final block of content, len(v) bytes long,
+// to v and returns the result.
+func (a Alignment) Pad(v []byte) []byte {
+	for i := a.Padding(int64(len(v))); i > 0; i-- {
+		v = append(v, a.PadByte)
+	}
+	return v
+}
@@ -0,0 +1,29 @@
+package brimio
+
+import "testing"
+
+func TestAlignment(t *testing.T) {
+	a := Alignment{BlockSize: 8, PadByte: '-', PadFinal: PadFinalBlock}
+	if err := a.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Align(0) != 0 || a.Align(1) != 8 || a.Align(8) != 8 || a.Align(9) != 16 {
+		t.Fatal(a.Align(1), a.Align(8), a.Align(9))
+	}
+	if !a.Aligned(16) || a.Aligned(15) {
+		t.Fatal(a.Aligned(16), a.Aligned(15))
+	}
+	if v := a.Pad([]byte("12345")); string(v) != "12345---" {
+		t.Fatalf("%#v", string(v))
+	}
+	a.PadFinal = PadFinalNone
+	if v := a.Pad([]byte("12345")); string(v) != "12345" {
+		t.Fatalf("%#v", string(v))
+	}
+	if (Alignment{}).Align(5) != 5 {
+		t.Fatal((Alignment{}).Align(5))
+	}
+	if err := (Alignment{BlockSize: -1}).Validate(); err == nil {
+		t.Fatal(err)
+	}
+}
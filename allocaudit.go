@@ -0,0 +1,54 @@
+package brimio
+
+import "sync/atomic"
+
+// AllocStats gives the number of Read, Write, and Verify calls made on the
+// package's checksummed readers and writers along with the number of heap
+// allocations made during those calls.
+//
+// The counts are only gathered when the package is built with the
+// brimioallocaudit build tag; see AllocAudit. Allocation counts are taken
+// from process wide memory statistics, so allocations made concurrently by
+// other goroutines will be included; use this in controlled, production-like
+// tests rather than production itself. Each stream's share is also given by
+// the Allocs of its ChecksummedReaderStats or ChecksummedWriterStats.
+type AllocStats struct {
+	ReadCalls    uint64
+	ReadAllocs   uint64
+	WriteCalls   uint64
+	WriteAllocs  uint64
+	VerifyCalls  uint64
+	VerifyAllocs uint64
+}
+
+type allocCounter struct {
+	calls  uint64
+	allocs uint64
+}
+
+var allocAudit struct {
+	read   allocCounter
+	write  allocCounter
+	verify allocCounter
+}
+
+// CurrentAllocStats returns the AllocStats gathered so far; always zeroes
+// unless built with the brimioallocaudit build tag.
+func CurrentAllocStats() AllocStats {
+	return AllocStats{
+		ReadCalls:    atomic.LoadUint64(&allocAudit.read.calls),
+		ReadAllocs:   atomic.LoadUint64(&allocAudit.read.allocs),
+		WriteCalls:   atomic.LoadUint64(&allocAudit.write.calls),
+		WriteAllocs:  atomic.LoadUint64(&allocAudit.write.allocs),
+		VerifyCalls:  atomic.LoadUint64(&allocAudit.verify.calls),
+		VerifyAllocs: atomic.LoadUint64(&allocAudit.verify.allocs),
+	}
+}
+
+// ResetAllocStats zeroes the AllocStats gathered so far.
+func ResetAllocStats() {
+	for _, c := range []*allocCounter{&allocAudit.read, &allocAudit.write, &allocAudit.verify} {
+		atomic.StoreUint64(&c.calls, 0)
+		atomic.StoreUint64(&c.allocs, 0)
+	}
+}
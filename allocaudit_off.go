@@ -0,0 +1,15 @@
+//go:build !brimioallocaudit
+// +build !brimioallocaudit
+
+package brimio
+
+// AllocAudit indicates whether the package was built with the
+// brimioallocaudit build tag, enabling the gathering of AllocStats.
+const AllocAudit = false
+
+func allocAuditStart() uint64 {
+	return 0
+}
+
+func allocAuditEnd(c *allocCounter, stream *uint64, start uint64) {
+}
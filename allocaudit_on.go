@@ -0,0 +1,29 @@
+//go:build brimioallocaudit
+// +build brimioallocaudit
+
+package brimio
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// AllocAudit indicates whether the package was built with the
+// brimioallocaudit build tag, enabling the gathering of AllocStats.
+const AllocAudit = true
+
+func allocAuditStart() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Mallocs
+}
+
+// allocAuditEnd counts a call against c and the allocations since start
+// against both c and the stream's own count.
+func allocAuditEnd(c *allocCounter, stream *uint64, start uint64) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	atomic.AddUint64(&c.calls, 1)
+	atomic.AddUint64(&c.allocs, ms.Mallocs-start)
+	atomic.AddUint64(stream, ms.Mallocs-start)
+}
@@ -0,0 +1,43 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+)
+
+func TestAllocStats(t *testing.T) {
+	ResetAllocStats()
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE)
+	ioutil.ReadAll(cr)
+	cr.Seek(0, 0)
+	cr.Verify()
+	stats := CurrentAllocStats()
+	if !AllocAudit {
+		if stats != (AllocStats{}) || cw.Stats().Allocs != 0 || cr.Stats().Allocs != 0 {
+			t.Fatalf("%#v", stats)
+		}
+		return
+	}
+	if stats.WriteCalls != 1 {
+		t.Fatal(stats.WriteCalls)
+	}
+	if stats.ReadCalls == 0 {
+		t.Fatal(stats.ReadCalls)
+	}
+	if stats.VerifyCalls != 1 {
+		t.Fatal(stats.VerifyCalls)
+	}
+	if a := cw.Stats().Allocs + cr.Stats().Allocs; a != stats.WriteAllocs+stats.ReadAllocs+stats.VerifyAllocs {
+		t.Fatal(a, stats)
+	}
+	ResetAllocStats()
+	if stats = CurrentAllocStats(); stats != (AllocStats{}) {
+		t.Fatalf("%#v", stats)
+	}
+}
@@ -0,0 +1,86 @@
+package brimio
+
+import "os"
+
+// BarrierMechanism identifies how a Barrier pushes written data toward stable
+// storage.
+type BarrierMechanism int
+
+const (
+	// BarrierFsync uses a full fsync of the file, the portable fallback.
+	BarrierFsync BarrierMechanism = iota
+	// BarrierSyncRange uses sync_file_range to write back just the range
+	// given and wait for it to reach the device. This orders writes relative
+	// to one another but, unlike fsync, does not flush file metadata or any
+	// volatile device cache, so it is only used with NewSyncRangeBarrier.
+	BarrierSyncRange
+	// BarrierFua relies on the file having been opened with O_DSYNC on a
+	// device supporting Forced Unit Access, making each write durable as it
+	// completes; the barrier itself then has nothing left to do.
+	BarrierFua
+)
+
+func (m BarrierMechanism) String() string {
+	switch m {
+	case BarrierFsync:
+		return "fsync"
+	case BarrierSyncRange:
+		return "sync_file_range"
+	case BarrierFua:
+		return "fua"
+	}
+	return "unknown"
+}
+
+// Barrier is a best-effort write barrier for a file, using the cheapest
+// mechanism the platform, file system, and device support. Mechanism reports
+// the choice made so operators can tell what guarantees are in effect.
+type Barrier struct {
+	f         *os.File
+	mechanism BarrierMechanism
+}
+
+// NewBarrier returns a Barrier for f, probing for the mechanism to use. As
+// BarrierSyncRange is weaker than fsync, it is never chosen; use
+// NewSyncRangeBarrier to allow it.
+func NewBarrier(f *os.File) *Barrier {
+	return &Barrier{f: f, mechanism: probeBarrierMechanism(f, false)}
+}
+
+// NewSyncRangeBarrier is NewBarrier but also allows BarrierSyncRange where
+// supported, for callers that only need their writes ordered and accept
+// that, unlike fsync, file metadata and any volatile device cache are not
+// flushed.
+func NewSyncRangeBarrier(f *os.File) *Barrier {
+	return &Barrier{f: f, mechanism: probeBarrierMechanism(f, true)}
+}
+
+// Mechanism returns the BarrierMechanism in effect.
+func (b *Barrier) Mechanism() BarrierMechanism {
+	return b.mechanism
+}
+
+// Sync pushes the length bytes written at offset toward stable storage, as
+// far as the Barrier's mechanism allows. A length of 0 means through the end
+// of the file.
+func (b *Barrier) Sync(offset int64, length int64) error {
+	switch b.mechanism {
+	case BarrierFua:
+		return nil
+	case BarrierSyncRange:
+		return syncRange(b.f, offset, length)
+	}
+	return b.f.Sync()
+}
+
+// SupportsFua returns true if the device holding path reports support for
+// Forced Unit Access writes; always false where that can't be determined.
+func SupportsFua(path string) (bool, error) {
+	return supportsFua(path)
+}
+
+// SupportsSyncRange returns true if the file at path supports
+// sync_file_range; always false on platforms without it.
+func SupportsSyncRange(path string) (bool, error) {
+	return supportsSyncRange(path)
+}
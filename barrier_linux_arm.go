@@ -0,0 +1,14 @@
+package brimio
+
+import "syscall"
+
+// syncFileRange calls sync_file_range2, as 32 bit arm has no
+// sync_file_range; it differs only in taking flags before the range, so its
+// 64 bit arguments fall on the register pairs the ABI requires.
+func syncFileRange(fd int, offset int64, length int64, flags int) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_ARM_SYNC_FILE_RANGE, uintptr(fd), uintptr(flags), uintptr(offset), uintptr(offset>>32), uintptr(length), uintptr(length>>32))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
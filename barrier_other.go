@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package brimio
+
+import "os"
+
+func supportsFua(path string) (bool, error) {
+	_, err := os.Stat(path)
+	return false, err
+}
+
+func supportsSyncRange(path string) (bool, error) {
+	_, err := os.Stat(path)
+	return false, err
+}
+
+func probeBarrierMechanism(f *os.File, allowSyncRange bool) BarrierMechanism {
+	return BarrierFsync
+}
+
+func syncRange(f *os.File, offset int64, length int64) error {
+	return f.Sync()
+}
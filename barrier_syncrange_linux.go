@@ -0,0 +1,10 @@
+//go:build linux && !arm
+// +build linux,!arm
+
+package brimio
+
+import "syscall"
+
+func syncFileRange(fd int, offset int64, length int64, flags int) error {
+	return syscall.SyncFileRange(fd, offset, length, flags)
+}
@@ -0,0 +1,44 @@
+package brimio
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestBarrier(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err = SupportsFua(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := SupportsSyncRange(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok && runtime.GOOS != "linux" {
+		t.Fatal(ok)
+	}
+	b := NewBarrier(f)
+	if b.Mechanism() == BarrierSyncRange {
+		t.Fatal(b.Mechanism())
+	}
+	if ok && NewSyncRangeBarrier(f).Mechanism() != BarrierSyncRange {
+		t.Fatal(ok)
+	}
+	if b.Mechanism().String() == "unknown" {
+		t.Fatal(b.Mechanism())
+	}
+	f.Write([]byte("12345"))
+	if err = b.Sync(0, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = SupportsSyncRange(f.Name() + ".missing"); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,185 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// BatchPlanner merges logical ranges of checksummed content, such as those
+// found by an index lookup, into fewer, larger physical reads and dispatches
+// them in parallel, cutting the number of requests made of delegates where
+// each one is costly, such as object storage.
+type BatchPlanner struct {
+	// Config is the layout of the checksummed content.
+	Config ChecksummedConfig
+	// MaxWaste is the most physical bytes wanted by no range that may be
+	// read to merge two reads into one.
+	MaxWaste int64
+	// MaxRead, if greater than 0, limits how long a merged read may grow;
+	// a single range needing more is still read whole.
+	MaxRead int64
+	// Workers is how many reads are made at once; 1 if less than 1.
+	Workers int
+}
+
+// BatchRead is a physical read planned by a BatchPlanner: Length bytes of
+// whole intervals and their checksums starting at Offset, serving the
+// ranges at the indexes given.
+type BatchRead struct {
+	Offset int64
+	Length int64
+	Ranges []int
+}
+
+// Plan returns the reads covering the ranges given, in offset order. Ranges
+// of 0 length need no read and are left out.
+func (bp *BatchPlanner) Plan(ranges []Range) ([]BatchRead, error) {
+	if err := bp.Config.Validate(); err != nil {
+		return nil, err
+	}
+	interval := int64(bp.Config.Interval)
+	blockSize := interval + int64(bp.Config.NewHash().Size())
+	var order []int
+	for i, r := range ranges {
+		if r.Offset < 0 || r.Length < 0 {
+			return nil, fmt.Errorf("invalid range %d+%d", r.Offset, r.Length)
+		}
+		if r.Length > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i int, j int) bool { return ranges[order[i]].Offset < ranges[order[j]].Offset })
+	var reads []BatchRead
+	var last int64
+	for _, i := range order {
+		first := ranges[i].Offset / interval
+		end := (ranges[i].Offset + ranges[i].Length - 1) / interval
+		if len(reads) > 0 {
+			br := &reads[len(reads)-1]
+			merged := br.Offset + br.Length
+			if end > last {
+				merged = (end + 1) * blockSize
+			}
+			if (first-last-1)*blockSize <= bp.MaxWaste && (bp.MaxRead <= 0 || merged-br.Offset <= bp.MaxRead) {
+				br.Length = merged - br.Offset
+				br.Ranges = append(br.Ranges, i)
+				if end > last {
+					last = end
+				}
+				continue
+			}
+		}
+		reads = append(reads, BatchRead{Offset: first * blockSize, Length: (end - first + 1) * blockSize, Ranges: []int{i}})
+		last = end
+	}
+	return reads, nil
+}
+
+// ReadAt returns the content of each of the ranges given, read from ra
+// with the reads Plan returns. Each interval serving a range is verified,
+// returning a *ChecksumMismatchError if one is not checksum valid; any
+// trailing partial interval has no checksum and is returned unverified. A
+// range extending past the end of the content results in
+// io.ErrUnexpectedEOF. The first error stops further reads and is returned.
+func (bp *BatchPlanner) ReadAt(ra io.ReaderAt, ranges []Range) ([][]byte, error) {
+	reads, err := bp.Plan(ranges)
+	if err != nil {
+		return nil, err
+	}
+	workers := bp.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	contents := make([][]byte, len(ranges))
+	for i, r := range ranges {
+		contents[i] = make([]byte, r.Length)
+	}
+	readChan := make(chan BatchRead, len(reads))
+	for _, br := range reads {
+		readChan <- br
+	}
+	close(readChan)
+	var lock sync.Mutex
+	var firstErr error
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for br := range readChan {
+				lock.Lock()
+				failed := firstErr != nil
+				lock.Unlock()
+				if failed {
+					return
+				}
+				if err := bp.read(ra, br, ranges, contents); err != nil {
+					lock.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					lock.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return contents, nil
+}
+
+// read makes a single planned read, verifying the intervals its ranges need
+// and copying out their content.
+func (bp *BatchPlanner) read(ra io.ReaderAt, br BatchRead, ranges []Range, contents [][]byte) error {
+	buf := make([]byte, br.Length)
+	n, err := ra.ReadAt(buf, br.Offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	buf = buf[:n]
+	interval := int64(bp.Config.Interval)
+	hash := bp.Config.NewHash()
+	blockSize := interval + int64(hash.Size())
+	firstBlock := br.Offset / blockSize
+	verified := make([]bool, (br.Length+blockSize-1)/blockSize)
+	for _, i := range br.Ranges {
+		r := ranges[i]
+		for done := int64(0); done < r.Length; {
+			offset := r.Offset + done
+			index := offset/interval - firstBlock
+			if index*blockSize >= int64(len(buf)) {
+				return io.ErrUnexpectedEOF
+			}
+			block := buf[index*blockSize:]
+			if int64(len(block)) > blockSize {
+				block = block[:blockSize]
+			}
+			available := int64(len(block))
+			if available > interval {
+				if !verified[index] {
+					hash.Reset()
+					setChecksumIndex(hash, firstBlock+index)
+					hash.Write(block[:interval])
+					// A checksum cut short can't match.
+					if available < blockSize || !checksumMatches(hash, block[interval:], nil) {
+						hash.Reset()
+						return newChecksumMismatchError(firstBlock+index, bp.Config.Interval, hash, block)
+					}
+					verified[index] = true
+				}
+				available = interval
+			}
+			start := offset % interval
+			if start >= available {
+				return io.ErrUnexpectedEOF
+			}
+			done += int64(copy(contents[i][done:], block[start:available]))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync/atomic"
+	"testing"
+)
+
+type countingReaderAt struct {
+	io.ReaderAt
+	reads int32
+}
+
+func (cra *countingReaderAt) ReadAt(v []byte, offset int64) (int, error) {
+	atomic.AddInt32(&cra.reads, 1)
+	return cra.ReaderAt.ReadAt(v, offset)
+}
+
+func TestBatchPlanner(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz1234567890ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write(content)
+	cw.Close()
+	b := buf.Bytes()
+	bp := &BatchPlanner{Config: ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}, Workers: 2}
+	ranges := []Range{{70, 6}, {1, 2}, {5, 0}, {18, 4}, {40, 8}}
+	reads, err := bp.Plan(ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Intervals 0-2 together as adjacent, 4 alone.
+	if len(reads) != 2 || reads[0].Offset != 0 || reads[0].Length != 60 || len(reads[0].Ranges) != 3 || reads[1].Offset != 80 || reads[1].Length != 20 || reads[1].Ranges[0] != 0 {
+		t.Fatalf("%#v", reads)
+	}
+	bp.MaxWaste = 20
+	if reads, _ = bp.Plan(ranges); len(reads) != 1 || reads[0].Length != 100 {
+		t.Fatalf("%#v", reads)
+	}
+	bp.MaxRead = 40
+	if reads, _ = bp.Plan(ranges); len(reads) != 3 || reads[0].Length != 40 || reads[1].Offset != 40 || reads[2].Offset != 80 {
+		t.Fatalf("%#v", reads)
+	}
+	bp.MaxRead = 0
+	cra := &countingReaderAt{ReaderAt: bytes.NewReader(b)}
+	contents, err := bp.ReadAt(cra, ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cra.reads != 1 {
+		t.Fatal(cra.reads)
+	}
+	for i, r := range ranges {
+		if !bytes.Equal(contents[i], content[r.Offset:r.Offset+r.Length]) {
+			t.Fatal(i, string(contents[i]))
+		}
+	}
+	if _, err = bp.ReadAt(cra, []Range{{70, 7}}); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if _, err = bp.ReadAt(cra, []Range{{200, 1}}); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if _, err = bp.Plan([]Range{{-1, 1}}); err == nil {
+		t.Fatal(err)
+	}
+	b[45] = 'X'
+	var mismatch *ChecksumMismatchError
+	if _, err = bp.ReadAt(cra, ranges); !errors.As(err, &mismatch) || mismatch.BlockIndex != 2 {
+		t.Fatal(err)
+	}
+	// Interval 2 is not needed.
+	if _, err = bp.ReadAt(cra, []Range{{1, 2}, {70, 6}}); err != nil {
+		t.Fatal(err)
+	}
+}
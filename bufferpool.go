@@ -0,0 +1,86 @@
+package brimio
+
+import (
+	"runtime"
+	"sync"
+)
+
+// BufferPool hands out reusable byte slices of a fixed size, split into
+// partitions so that workers with affinity to a CPU or NUMA node can keep
+// their buffers local to it instead of bouncing memory across nodes; see
+// VerifyParallelPooled.
+//
+// Go doesn't expose which P or node a goroutine is running on, so callers
+// choose the partition, typically one per worker locked to its OS thread.
+// Unlike a sync.Pool, each partition keeps the buffers put back to it for
+// good, so a buffer first touched by a worker, and so placed on its node by
+// the operating system, stays with that worker rather than being collected
+// and reallocated elsewhere.
+type BufferPool struct {
+	size       int
+	partitions []bufferPartition
+}
+
+type bufferPartition struct {
+	lock sync.Mutex
+	free [][]byte
+}
+
+// NewBufferPool returns a BufferPool of buffers size bytes long spread over
+// the number of partitions given, or runtime.GOMAXPROCS(0) partitions if
+// partitions is less than 1.
+func NewBufferPool(size int, partitions int) *BufferPool {
+	if partitions < 1 {
+		partitions = runtime.GOMAXPROCS(0)
+	}
+	return &BufferPool{size: size, partitions: make([]bufferPartition, partitions)}
+}
+
+// Partitions returns the number of partitions in the BufferPool.
+func (bp *BufferPool) Partitions() int {
+	return len(bp.partitions)
+}
+
+// Size returns the length of the buffers handed out.
+func (bp *BufferPool) Size() int {
+	return bp.size
+}
+
+// Get returns a buffer from the partition given, which is taken modulo the
+// number of partitions, allocating one if the partition has none free.
+func (bp *BufferPool) Get(partition int) []byte {
+	p := bp.partition(partition)
+	p.lock.Lock()
+	if l := len(p.free); l > 0 {
+		b := p.free[l-1]
+		p.free[l-1] = nil
+		p.free = p.free[:l-1]
+		p.lock.Unlock()
+		return b
+	}
+	p.lock.Unlock()
+	return make([]byte, bp.size)
+}
+
+// Put returns a buffer to the partition given, which should be the partition
+// it was gotten from. Buffers of the wrong capacity are dropped.
+func (bp *BufferPool) Put(partition int, b []byte) {
+	if cap(b) != bp.size {
+		return
+	}
+	p := bp.partition(partition)
+	p.lock.Lock()
+	p.free = append(p.free, b[:bp.size])
+	p.lock.Unlock()
+}
+
+// partition returns the partition given modulo the number of partitions,
+// wrapping negative ones around rather than negating them, which would
+// overflow for the most negative int.
+func (bp *BufferPool) partition(partition int) *bufferPartition {
+	i := partition % len(bp.partitions)
+	if i < 0 {
+		i += len(bp.partitions)
+	}
+	return &bp.partitions[i]
+}
@@ -0,0 +1,47 @@
+package brimio
+
+import "testing"
+
+func TestBufferPool(t *testing.T) {
+	bp := NewBufferPool(16, 0)
+	if bp.Partitions() < 1 {
+		t.Fatal(bp.Partitions())
+	}
+	bp = NewBufferPool(16, 2)
+	if bp.Partitions() != 2 {
+		t.Fatal(bp.Partitions())
+	}
+	b := bp.Get(3)
+	if len(b) != 16 {
+		t.Fatal(len(b))
+	}
+	bp.Put(3, b[:4])
+	b = bp.Get(-1)
+	if len(b) != 16 {
+		t.Fatal(len(b))
+	}
+	bp.Put(1, make([]byte, 8))
+	// The most negative partition can't be negated, but still wraps.
+	minInt := -int(^uint(0)>>1) - 1
+	bp.Put(minInt, bp.Get(minInt))
+}
+
+func TestBufferPoolPartitionsKeepBuffers(t *testing.T) {
+	bp := NewBufferPool(16, 3)
+	a := bp.Get(0)
+	b := bp.Get(1)
+	a[0] = 'a'
+	b[0] = 'b'
+	bp.Put(0, a)
+	bp.Put(1, b)
+	// Each partition hands back its own buffer, and -2 wraps around to 1.
+	if v := bp.Get(-2); v[0] != 'b' {
+		t.Fatal(string(v[:1]))
+	}
+	if v := bp.Get(3); v[0] != 'a' {
+		t.Fatal(string(v[:1]))
+	}
+	if v := bp.Get(0); v[0] != 0 {
+		t.Fatal(string(v[:1]))
+	}
+}
@@ -0,0 +1,165 @@
+package brimio
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"sync/atomic"
+)
+
+// checkpointVersion is the format version of ChecksummedWriterCheckpoint's
+// MarshalBinary.
+const checkpointVersion = 1
+
+// ChecksummedWriterCheckpoint is the state of a ChecksummedWriter as of a
+// call to its Checkpoint method, such as for persisting alongside crash
+// resumable ingest. It implements encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler.
+type ChecksummedWriterCheckpoint struct {
+	// Offset is the length of the underlying content written as of the
+	// checkpoint; anything written after it must be discarded, such as by
+	// truncating, before restoring.
+	Offset int64
+	// Interval is the checksum interval being written.
+	Interval int
+	// IntervalOffset is how much of the current interval's content had been
+	// written to the underlying content, and HashState the marshaled state
+	// of its hash.
+	IntervalOffset int
+	HashState      []byte
+	// Pending is content accepted but not yet written to the underlying
+	// content, which is written when restoring.
+	Pending []byte
+	// Stats are the ChecksummedWriterStats as of the checkpoint.
+	Stats ChecksummedWriterStats
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (cp ChecksummedWriterCheckpoint) MarshalBinary() ([]byte, error) {
+	var u [binary.MaxVarintLen64]byte
+	b := []byte{checkpointVersion}
+	for _, v := range []uint64{uint64(cp.Offset), uint64(cp.Interval), uint64(cp.IntervalOffset), cp.Stats.BytesWritten, cp.Stats.ChecksumsEmitted} {
+		b = append(b, u[:binary.PutUvarint(u[:], v)]...)
+	}
+	for _, v := range [][]byte{cp.HashState, cp.Pending} {
+		b = append(b, u[:binary.PutUvarint(u[:], uint64(len(v)))]...)
+		b = append(b, v...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (cp *ChecksummedWriterCheckpoint) UnmarshalBinary(b []byte) error {
+	if len(b) == 0 || b[0] != checkpointVersion {
+		return fmt.Errorf("unsupported checkpoint")
+	}
+	b = b[1:]
+	var vs [5]uint64
+	for i := range vs {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("truncated checkpoint")
+		}
+		vs[i] = v
+		b = b[n:]
+	}
+	var bs [2][]byte
+	for i := range bs {
+		l, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < l {
+			return fmt.Errorf("truncated checkpoint")
+		}
+		bs[i] = append([]byte(nil), b[n:n+int(l)]...)
+		b = b[n+int(l):]
+	}
+	*cp = ChecksummedWriterCheckpoint{
+		Offset:         int64(vs[0]),
+		Interval:       int(vs[1]),
+		IntervalOffset: int(vs[2]),
+		HashState:      bs[0],
+		Pending:        bs[1],
+		Stats:          ChecksummedWriterStats{BytesWritten: vs[3], ChecksumsEmitted: vs[4]},
+	}
+	return nil
+}
+
+// RestoreChecksummedWriter returns a ChecksummedWriter continuing from the
+// ChecksummedWriterCheckpoint given, using the same hashing function as the
+// original. The underlying io.Writer must be positioned at the checkpoint's
+// Offset, such as a file truncated to it and opened for appending.
+func RestoreChecksummedWriter(delegate io.Writer, cp ChecksummedWriterCheckpoint, newHash func() hash.Hash) (ChecksummedWriter, error) {
+	if err := validateChecksummed(cp.Interval, newHash); err != nil {
+		return nil, err
+	}
+	if cp.IntervalOffset < 0 || cp.IntervalOffset >= cp.Interval || uint64(len(cp.Pending)) > cp.Stats.BytesWritten {
+		return nil, fmt.Errorf("invalid checkpoint")
+	}
+	cwi := newChecksummedWriterImpl(delegate, cp.Interval, newHash)
+	u, ok := cwi.hash.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil, fmt.Errorf("hash %T does not support checkpoints", cwi.hash)
+	}
+	if err := u.UnmarshalBinary(cp.HashState); err != nil {
+		return nil, err
+	}
+	cwi.checksumOffset = cp.IntervalOffset
+	cwi.stats.BytesWritten = cp.Stats.BytesWritten - uint64(len(cp.Pending))
+	cwi.stats.ChecksumsEmitted = cp.Stats.ChecksumsEmitted
+	cwi.base = cp.Offset - int64(cwi.stats.BytesWritten+cwi.stats.ChecksumsEmitted*uint64(len(cwi.checksum)))
+	if len(cp.Pending) > 0 {
+		if _, err := cwi.Write(cp.Pending); err != nil {
+			return nil, err
+		}
+	}
+	return cwi, nil
+}
+
+// marshalHash returns the marshaled state of h.
+func marshalHash(h hash.Hash) ([]byte, error) {
+	m, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("hash %T does not support checkpoints", h)
+	}
+	return m.MarshalBinary()
+}
+
+func (cwi *checksummedWriterImpl) Checkpoint() (ChecksummedWriterCheckpoint, error) {
+	state, err := marshalHash(cwi.hash)
+	if err != nil {
+		return ChecksummedWriterCheckpoint{}, err
+	}
+	stats := cwi.Stats()
+	return ChecksummedWriterCheckpoint{
+		Offset:         cwi.physical(),
+		Interval:       cwi.checksumInterval,
+		IntervalOffset: cwi.checksumOffset,
+		HashState:      state,
+		Stats:          stats,
+	}, nil
+}
+
+func (cwi *multiCoreChecksummedWriter) Checkpoint() (ChecksummedWriterCheckpoint, error) {
+	if err := cwi.drain(); err != nil {
+		return ChecksummedWriterCheckpoint{}, err
+	}
+	// With the full intervals written, what remains is the buffered content
+	// of the interval in progress, none of which has been written or hashed.
+	state, err := marshalHash(cwi.newHash())
+	if err != nil {
+		return ChecksummedWriterCheckpoint{}, err
+	}
+	stats := ChecksummedWriterStats{
+		BytesWritten:     atomic.LoadUint64(&cwi.stats.BytesWritten),
+		ChecksumsEmitted: atomic.LoadUint64(&cwi.stats.ChecksumsEmitted),
+	}
+	pending := append([]byte(nil), cwi.buffer.buf...)
+	return ChecksummedWriterCheckpoint{
+		Offset:    cwi.buffer.offset,
+		Interval:  cwi.checksumInterval,
+		HashState: state,
+		Pending:   pending,
+		Stats:     stats,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
+	"testing"
+)
+
+func TestChecksummedWriterCheckpoint(t *testing.T) {
+	newCRC := func() hash.Hash { return crc32.NewIEEE() }
+	newHashes := []func() hash.Hash{newCRC, NewMultiHash(newCRC, func() hash.Hash { return fnv.New64a() })}
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	for _, newHash := range newHashes {
+		expected := &bytes.Buffer{}
+		cw := NewChecksummedWriterHash(expected, 16, newHash)
+		cw.Write(content)
+		cw.Close()
+		for _, multiCore := range []bool{false, true} {
+			buf := &bytes.Buffer{}
+			cw := NewChecksummedWriterHash(buf, 16, newHash)
+			if multiCore {
+				cw = NewMultiCoreChecksummedWriterHash(buf, 16, newHash, 2)
+			}
+			cw.Write(content[:25])
+			cp, err := cw.Checkpoint()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := cp.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Writes after the checkpoint are lost in the crash.
+			cw.Write([]byte("lost"))
+			cw.CloseWithoutDelegate()
+			var cp2 ChecksummedWriterCheckpoint
+			if err = cp2.UnmarshalBinary(b); err != nil {
+				t.Fatal(err)
+			}
+			buf.Truncate(int(cp2.Offset))
+			cw, err = RestoreChecksummedWriter(buf, cp2, newHash)
+			if err != nil {
+				t.Fatal(multiCore, err)
+			}
+			cw.Write(content[25:])
+			if s := cw.Stats(); s.BytesWritten != uint64(len(content)) {
+				t.Fatalf("%v %#v", multiCore, s)
+			}
+			cw.Close()
+			if !bytes.Equal(buf.Bytes(), expected.Bytes()) {
+				t.Fatalf("%v %#v", multiCore, buf.String())
+			}
+		}
+	}
+	var cp ChecksummedWriterCheckpoint
+	if err := cp.UnmarshalBinary([]byte{checkpointVersion, 1}); err == nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build go1.16
+// +build go1.16
+
+package brimio
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// NewChecksummedFS returns an fs.FS presenting the checksummed files of fsys,
+// all with the layout cfg describes, by their content, so they can be served
+// by code that only understands io/fs, such as http.FileServer with
+// http.FS. Each file opened is as NewChecksummedFile returns; directories
+// are passed through unchanged, so the sizes their entries report are those
+// of the underlying files, checksums included.
+func NewChecksummedFS(fsys fs.FS, cfg ChecksummedConfig) fs.FS {
+	return &checksummedFS{fsys: fsys, cfg: cfg}
+}
+
+type checksummedFS struct {
+	fsys fs.FS
+	cfg  ChecksummedConfig
+}
+
+func (cfs *checksummedFS) Open(name string) (fs.File, error) {
+	f, err := cfs.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		return f, nil
+	}
+	cf, err := NewChecksummedFile(f, cfs.cfg)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return cf, nil
+}
+
+// NewChecksummedFile returns an fs.File presenting the content of the
+// checksummed file f, which has the layout cfg describes and must also be
+// an io.Seeker, as files from os.DirFS are. The fs.File returned is also an
+// io.Seeker, its Stat reports the size of the content, not including
+// checksums, and its Reads verify each interval, returning a
+// *ChecksumMismatchError for one that is not checksum valid. Closing it
+// closes f.
+func NewChecksummedFile(f fs.File, cfg ChecksummedConfig) (fs.File, error) {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return nil, fmt.Errorf("file %T is not an io.Seeker", f)
+	}
+	cr, err := NewChecksummedReaderWith(rs, WithInterval(cfg.Interval), WithHash(cfg.NewHash), WithAutoVerify())
+	if err != nil {
+		return nil, err
+	}
+	return &checksummedFile{ChecksummedReader: cr, f: f}, nil
+}
+
+type checksummedFile struct {
+	ChecksummedReader
+	f fs.File
+}
+
+func (cf *checksummedFile) Stat() (fs.FileInfo, error) {
+	info, err := cf.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size, err := cf.Size()
+	if err != nil {
+		return nil, err
+	}
+	return &checksummedFileInfo{FileInfo: info, size: size}, nil
+}
+
+// checksummedFileInfo is the fs.FileInfo of a checksummed file with the size
+// of its content.
+type checksummedFileInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (cfi *checksummedFileInfo) Size() int64 {
+	return cfi.size
+}
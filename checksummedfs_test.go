@@ -0,0 +1,59 @@
+//go:build go1.16
+// +build go1.16
+
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+)
+
+func TestChecksummedFS(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	bad := append([]byte(nil), buf.Bytes()...)
+	bad[25] = 'X'
+	fsys := NewChecksummedFS(fstest.MapFS{
+		"dir/good": &fstest.MapFile{Data: buf.Bytes()},
+		"dir/bad":  &fstest.MapFile{Data: bad},
+	}, ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }})
+	f, err := fsys.Open("dir/good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := f.Stat()
+	if err != nil || info.Size() != 40 || info.Name() != "good" {
+		t.Fatal(info, err)
+	}
+	if o, err := f.(io.Seeker).Seek(-6, io.SeekEnd); err != nil || o != 34 {
+		t.Fatal(o, err)
+	}
+	v, err := ioutil.ReadAll(f)
+	if err != nil || string(v) != "uvwxyz" {
+		t.Fatal(string(v), err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err = fs.ReadFile(fsys, "dir/bad"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(string(v), err)
+	}
+	if f, err = fsys.Open("dir"); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := f.(fs.ReadDirFile).ReadDir(-1); err != nil || len(entries) != 2 {
+		t.Fatal(entries, err)
+	}
+	if _, err = fsys.Open("missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+}
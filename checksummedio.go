@@ -1,12 +1,13 @@
 package brimio
 
 import (
-	"bytes"
-	"encoding/binary"
+	"context"
 	"fmt"
 	"hash"
 	"io"
+	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 // ChecksummedReader reads content written by ChecksummedWriter, verifying
@@ -16,16 +17,31 @@ import (
 // Any errors from Read or Verify should make no assumptions about any
 // resulting position and should Seek before continuing to use the
 // ChecksummedReader.
+//
+// Intervals ended early by ChecksummedWriter.Flush are padded out to whole
+// intervals and marked as such by their checksums, which every method
+// verifying intervals accepts. Reading their content without the padding
+// requires ChecksummedReaderOptions.Flushed, as then where content lies
+// depends on each interval before it.
 type ChecksummedReader interface {
 	// Read implements the io.Reader interface.
 	//
 	// Any error should make no assumption about any resulting position and
 	// should Seek before continuing to use the ChecksummedReader.
 	Read(v []byte) (n int, err error)
+	// ReadByte implements the io.ByteReader interface, so the
+	// ChecksummedReader may be given directly to byte oriented decoders
+	// such as binary.ReadUvarint, keeping the position exact where a
+	// bufio.Reader would read ahead. With AutoVerify set bytes are served
+	// from the verified interval in memory; otherwise each is a Read of the
+	// underlying io.ReadSeeker.
+	ReadByte() (byte, error)
 	// Seek implements the io.Seeker interface.
 	Seek(offset int64, whence int) (n int64, err error)
 	// Verify verifies the checksum for the section of the content containing
-	// the current read position.
+	// the current read position. With a Cache, a section already held as
+	// verified is reported valid without rereading it, and one found valid is
+	// added.
 	//
 	// If there is an error, whether the section is checksum valid is
 	// indeterminate by this routine and the caller should decide what to do
@@ -37,16 +53,311 @@ type ChecksummedReader interface {
 	//
 	// With no error, the bool indicates whether the content is checksum valid
 	// and the position within the ChecksummedReader will not have changed.
+	// If the underlying io.ReadSeeker is also an io.ReaderAt, such as an
+	// *os.File, the section is read with ReadAt and the position is never
+	// moved at all, even with an error.
 	Verify() (bool, error)
+	// VerifyDetail is Verify but also returns the Range of content the
+	// section verified covers, such as for refetching exactly those bytes
+	// from a peer when it is not checksum valid. The Range is returned even
+	// with an error, if the position could be determined.
+	VerifyDetail() (bool, Range, error)
+	// VerifyAll verifies the checksum of every interval of the content,
+	// returning the indexes of the intervals that are not checksum valid, or
+	// nil if all are valid. Any trailing partial interval has no checksum and
+	// is not verified.
+	//
+	// If progress is not nil it will be called after each interval with the
+	// count of intervals verified so far and the total to verify.
+	//
+	// Any error should make no assumption about any resulting position and
+	// should Seek before continuing to use the ChecksummedReader. With no
+	// error, the position within the ChecksummedReader will not have changed.
+	VerifyAll(progress func(verified int64, total int64)) ([]int64, error)
+	// VerifyRange verifies the checksum of just the intervals overlapping the
+	// length bytes of content starting at offset, returning the indexes of
+	// those that are not checksum valid, or nil if all are valid. As with
+	// VerifyAll, any trailing partial interval is not verified.
+	//
+	// Any error should make no assumption about any resulting position and
+	// should Seek before continuing to use the ChecksummedReader. With no
+	// error, the position within the ChecksummedReader will not have changed.
+	VerifyRange(offset int64, length int64) ([]int64, error)
+	// ReadContext is Read, filling as much of v as it can, but checks ctx
+	// before each interval and stops with ctx.Err() once it is done. The
+	// position is then just after the n bytes returned, so reading may
+	// continue from there.
+	ReadContext(ctx context.Context, v []byte) (n int, err error)
+	// VerifyAllContext is VerifyAll but checks ctx before each interval,
+	// stopping with ctx.Err() once it is done; the position is then left
+	// unchanged and the intervals found not checksum valid so far are
+	// returned.
+	VerifyAllContext(ctx context.Context, progress func(verified int64, total int64)) ([]int64, error)
+	// VerifyRangeContext is VerifyRange but cancelled as VerifyAllContext
+	// is.
+	VerifyRangeContext(ctx context.Context, offset int64, length int64) ([]int64, error)
+	// Warm reads and verifies the intervals overlapping the ranges given in
+	// the background, storing them in the reader's VerifiedBlockCache so
+	// later Reads are served from memory. This requires a Cache to have been
+	// given in the ChecksummedReaderOptions and the underlying io.ReadSeeker
+	// to also be an io.ReaderAt, leaving the current position undisturbed.
+	//
+	// The returned channel receives the first error encountered, or nil,
+	// once warming finishes. Intervals found not checksum valid are left out
+	// of the cache and reported to any OnCorruption callback, which may then
+	// be called from another goroutine.
+	Warm(ranges []Range) <-chan error
 	// Close implements the io.Closer interface.
 	Close() error
+	// Size returns the length of the content, not including checksums, by
+	// seeking to the end of the underlying io.ReadSeeker and back.
+	Size() (int64, error)
+	// Stats returns the ChecksummedReaderStats gathered so far.
+	Stats() ChecksummedReaderStats
+	// Describe returns the ChecksummedOverhead of the reading so far,
+	// comparing the underlying content read with the content returned. It
+	// remains available after Close.
+	Describe() ChecksummedOverhead
+	// VerifyAt verifies just the intervals a ChecksummedWriter had sealed as
+	// of the ChecksummedGeneration given, so content still being written past
+	// that point is not reported as corrupt when scrubbing a live file. The
+	// position is restored afterward.
+	VerifyAt(generation ChecksummedGeneration) ([]int64, error)
+	// CloseWithoutDelegate closes the ChecksummedReader but leaves the
+	// underlying io.ReadSeeker open, such as a long-lived file managed
+	// separately.
+	CloseWithoutDelegate() error
+	// Skipped returns the ranges of content, by offset and length, that
+	// SkipCorrupted has skipped or zeroed so far, in the order encountered.
+	Skipped() []Range
+	// Unwrap implements the Unwrapper interface, returning the underlying
+	// io.ReadSeeker, such as for DelegateAs to find an *os.File beneath
+	// other layers.
+	Unwrap() interface{}
+}
+
+// ChecksummedReaderStats gives counts of a ChecksummedReader's activity.
+type ChecksummedReaderStats struct {
+	// BytesRead is the amount of content returned, not including checksums.
+	BytesRead uint64
+	// BlocksVerified is the number of intervals checked against their
+	// checksums, by Verify calls, AutoVerify, Warm, and so on.
+	BlocksVerified uint64
+	// VerifyFailures is the number of those intervals found not checksum
+	// valid.
+	VerifyFailures uint64
+	// PhysicalBytesRead is the amount of underlying content read, checksums
+	// included, such as whole intervals read to verify them.
+	PhysicalBytesRead uint64
+	// ChecksumBytesRead is the part of PhysicalBytesRead that was checksums.
+	ChecksumBytesRead uint64
+	// Allocs is the number of heap allocations made during Read and Verify
+	// calls, as with AllocStats; always 0 unless built with the
+	// brimioallocaudit build tag.
+	Allocs uint64
+}
+
+// ChecksummedWriterStats gives counts of a ChecksummedWriter's activity.
+type ChecksummedWriterStats struct {
+	// BytesWritten is the amount of content accepted, not including
+	// checksums.
+	BytesWritten uint64
+	// ChecksumsEmitted is the number of checksums written out.
+	ChecksumsEmitted uint64
+	// Allocs is the number of heap allocations made during Write calls, as
+	// with AllocStats; always 0 unless built with the brimioallocaudit build
+	// tag.
+	Allocs uint64
+}
+
+// ChecksummedOverhead compares the underlying content of a checksummed
+// stream with the content it carries, to quantify the cost of the interval
+// and hashing function chosen.
+type ChecksummedOverhead struct {
+	// LogicalBytes is the content read or written, not including checksums.
+	LogicalBytes uint64
+	// PhysicalBytes is the underlying content read or written.
+	PhysicalBytes uint64
+	// ChecksumBytes is the part of PhysicalBytes that was checksums.
+	ChecksumBytes uint64
+}
+
+// Amplification returns PhysicalBytes per LogicalByte, or 0 if there were no
+// LogicalBytes. For readers this includes intervals read in full to verify
+// them, or verified without being returned at all.
+func (o ChecksummedOverhead) Amplification() float64 {
+	if o.LogicalBytes == 0 {
+		return 0
+	}
+	return float64(o.PhysicalBytes) / float64(o.LogicalBytes)
+}
+
+// ChecksummedGeneration identifies the point reached by a ChecksummedWriter
+// as of a Flush. Generation counts the Flush calls, increasing by one with
+// each, and Sealed is the length of the underlying content, checksums
+// included, written by then; everything before Sealed has its checksums in
+// place.
+type ChecksummedGeneration struct {
+	Generation uint64
+	Sealed     int64
+}
+
+// generationTracker holds a ChecksummedWriter's latest ChecksummedGeneration
+// for concurrent readers.
+type generationTracker struct {
+	lock       sync.Mutex
+	generation ChecksummedGeneration
+}
+
+func (gt *generationTracker) seal(sealed int64) {
+	gt.lock.Lock()
+	gt.generation.Generation++
+	gt.generation.Sealed = sealed
+	gt.lock.Unlock()
+}
+
+func (gt *generationTracker) Generation() ChecksummedGeneration {
+	gt.lock.Lock()
+	defer gt.lock.Unlock()
+	return gt.generation
+}
+
+// ContentSize returns the length of the content within checksummed content
+// of physicalSize bytes, written with the interval and checksumSize given.
+// A trailing checksum that was cut short is not counted.
+func ContentSize(physicalSize int64, interval int, checksumSize int) int64 {
+	blockSize := int64(interval + checksumSize)
+	partial := physicalSize % blockSize
+	if partial > int64(interval) {
+		partial = int64(interval)
+	}
+	return physicalSize/blockSize*int64(interval) + partial
+}
+
+// PhysicalSize returns the length of the checksummed content
+// ChecksummedWriter would write for contentSize bytes of content, with the
+// interval and checksumSize given. Any trailing partial interval has no
+// checksum.
+func PhysicalSize(contentSize int64, interval int, checksumSize int) int64 {
+	return contentSize + contentSize/int64(interval)*int64(checksumSize)
 }
 
 // NewChecksummedReader returns a ChecksummedReader that delegates requests to
 // an underlying io.ReadSeeker expecting checksums of the content at given
 // intervals using the hashing function given.
+//
+// Like the other constructors, it panics if the interval or hashing function
+// are not valid; see ChecksummedConfig.Validate.
 func NewChecksummedReader(delegate io.ReadSeeker, interval int, newHash func() hash.Hash32) ChecksummedReader {
-	return newChecksummedReaderImpl(delegate, interval, newHash)
+	return mustChecksummedReader(NewChecksummedReaderWith(delegate, WithInterval(interval), WithHash(hash32(newHash))))
+}
+
+// ChecksummedReaderOptions are the optional behaviors of a ChecksummedReader
+// given to NewChecksummedReaderWithOptions.
+type ChecksummedReaderOptions struct {
+	// AutoVerify will have Read verify each interval's checksum before
+	// returning any of its content, returning ErrChecksumMismatch rather than
+	// corrupt content. Any trailing partial interval has no checksum and is
+	// returned unverified.
+	//
+	// Each interval is read in full and verified once when Read first enters
+	// it, with subsequent Reads within the same interval served from memory.
+	AutoVerify bool
+	// OnCorruption, if not nil, is called whenever Verify, VerifyAll,
+	// VerifyRange, or AutoVerify detect an interval that is not checksum
+	// valid. The blockIndex counts intervals from 0 and offset is where the
+	// interval starts within the underlying content, such as for repairing it.
+	OnCorruption func(blockIndex int64, offset int64)
+	// Cache, if not nil, holds verified intervals for reuse, such as ones
+	// read ahead of time with Warm, letting Read and Verify skip rereading
+	// them. If the content is changed, such as with a ChecksummedWriterAt,
+	// the intervals changed must be invalidated in the Cache. Setting Cache
+	// implies AutoVerify.
+	Cache *VerifiedBlockCache
+	// SkipCorrupted, for salvaging what content remains intact, has Read and
+	// WriteTo pass over intervals that are not checksum valid rather than
+	// returning ErrChecksumMismatch; Skipped reports the ranges passed over.
+	// Setting SkipCorrupted implies AutoVerify.
+	SkipCorrupted bool
+	// ZeroCorrupted, with SkipCorrupted, returns zeros in place of each
+	// corrupted interval rather than leaving it out, keeping the remaining
+	// content at its original offsets.
+	ZeroCorrupted bool
+	// ReplaceCorrupted, if not nil, is given the chance to supply
+	// replacement content, such as from a replica, whenever Read or WriteTo
+	// find an interval that is not checksum valid. It is called with the
+	// interval's index and offset as with OnCorruption and with block, the
+	// interval and its checksum as read, to overwrite with a good copy of
+	// both, returning whether it did. A replacement is only used if it is
+	// checksum valid; otherwise, or if it returns false, the corruption is
+	// handled as if ReplaceCorrupted were not set. Either way OnCorruption is
+	// still called and the failure counted. Setting ReplaceCorrupted implies
+	// AutoVerify. See ReplaceFromReplica.
+	ReplaceCorrupted func(blockIndex int64, offset int64, block []byte) bool
+	// Placement is where the checksums are stored; ChecksumTrailing if not
+	// set.
+	Placement ChecksumPlacement
+	// Flushed has Read, Seek, and Size follow intervals ended early by
+	// ChecksummedWriter.Flush, returning just their content rather than
+	// their padding. Since where content lies then depends on every interval
+	// before it, intervals not yet read are read and verified in order, once
+	// each, whenever Seek or Size need to know past them; one not checksum
+	// valid is taken to be whole, so content after it may be misplaced.
+	// Setting Flushed implies AutoVerify.
+	Flushed bool
+}
+
+// ErrChecksumMismatch is returned when content read does not match its
+// checksum. A ChecksummedReader with AutoVerify set returns a
+// *ChecksumMismatchError, which errors.Is reports as ErrChecksumMismatch.
+var ErrChecksumMismatch = fmt.Errorf("checksum mismatch")
+
+// ChecksumMismatchError details an interval found not checksum valid, as
+// returned by a ChecksummedReader with AutoVerify set; use errors.As to
+// retrieve it.
+type ChecksumMismatchError struct {
+	// BlockIndex counts intervals from 0.
+	BlockIndex int64
+	// Offset and Length are the range of the interval within the
+	// checksummed content.
+	Offset int64
+	Length int64
+	// Expected is the checksum stored with the interval, which may be cut
+	// short, and Computed is the checksum of the interval as read.
+	Expected []byte
+	Computed []byte
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch in interval %d at %d+%d: expected %x, computed %x", e.BlockIndex, e.Offset, e.Length, e.Expected, e.Computed)
+}
+
+// Is reports whether target is ErrChecksumMismatch, for errors.Is.
+func (e *ChecksumMismatchError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// NewChecksummedReaderWithOptions returns a ChecksummedReader like
+// NewChecksummedReaderHash does but with the optional behaviors given.
+func NewChecksummedReaderWithOptions(delegate io.ReadSeeker, interval int, newHash func() hash.Hash, opts *ChecksummedReaderOptions) ChecksummedReader {
+	return mustChecksummedReader(NewChecksummedReaderWith(delegate, WithInterval(interval), WithHash(newHash), withReaderOptions(opts)))
+}
+
+// NewChecksummedReader64 returns a ChecksummedReader that delegates requests
+// to an underlying io.ReadSeeker expecting 8 byte checksums of the content at
+// given intervals using the 64 bit hashing function given.
+func NewChecksummedReader64(delegate io.ReadSeeker, interval int, newHash func() hash.Hash64) ChecksummedReader {
+	return mustChecksummedReader(NewChecksummedReaderWith(delegate, WithInterval(interval), WithHash(hash64(newHash))))
+}
+
+// NewChecksummedReaderHash returns a ChecksummedReader that delegates
+// requests to an underlying io.ReadSeeker expecting checksums of the content
+// at given intervals using the hashing function given, such as sha256.New.
+//
+// Each interval of content is expected to be followed by a checksum of
+// newHash().Size() bytes, the layout NewChecksummedWriterHash produces.
+func NewChecksummedReaderHash(delegate io.ReadSeeker, interval int, newHash func() hash.Hash) ChecksummedReader {
+	return mustChecksummedReader(NewChecksummedReaderWith(delegate, WithInterval(interval), WithHash(newHash)))
 }
 
 // ChecksummedWriter writes content with additional checksums embedded in the
@@ -55,105 +366,665 @@ func NewChecksummedReader(delegate io.ReadSeeker, interval int, newHash func() h
 // Implements the io.WriteCloser interface.
 //
 // Note that this generally only works for brand new writers starting at offset
-// 0. Appending to existing files or starting at offsets other than 0 requires
-// special care when working with ChecksummedReader later and is beyond the
-// basic usage described here.
+// 0. To append to existing checksummed content use
+// NewAppendingChecksummedWriter; starting at other offsets requires special
+// care when working with ChecksummedReader later and is beyond the basic usage
+// described here.
 //
 // Also, note that the trailing bytes may not be covered by a checksum unless
 // it happens to just fall on a checksum interval.
 type ChecksummedWriter interface {
 	// Write implements the io.Writer interface.
 	Write(v []byte) (n int, err error)
+	// WriteContext is Write but checks ctx before each interval's worth of
+	// v, stopping with ctx.Err() once it is done. The n bytes accepted by
+	// then are written as by Write, and the ChecksummedWriter remains usable
+	// to continue or Close.
+	WriteContext(ctx context.Context, v []byte) (n int, err error)
+	// ReadFrom implements the io.ReaderFrom interface, giving io.Copy a fast
+	// path that reads whole intervals at a time from r directly into the
+	// ChecksummedWriter's buffer. Since each read waits for an entire
+	// interval, or the end of r, this suits bulk ingest rather than latency
+	// sensitive streams.
+	ReadFrom(r io.Reader) (n int64, err error)
+	// Flush ends the current interval early, padding it out to a whole
+	// interval with a checksum marking it as ended early, and starts a new
+	// interval with any further content. If the underlying io.Writer has a
+	// Flush method (such as *bufio.Writer does), it is called afterward.
+	//
+	// Content so flushed no longer lies at fixed offsets, so it must be read
+	// with NewStreamingChecksummedReader or a ChecksummedReader with
+	// ChecksummedReaderOptions.Flushed set.
+	Flush() error
 	// Close implements the io.Closer interface.
 	Close() error
+	// CloseWithError closes the ChecksummedWriter the same as Close but, if
+	// err is not nil and the underlying io.Writer has a CloseWithError method
+	// (such as *io.PipeWriter does), the underlying io.Writer is closed with
+	// that method and err instead; downstream readers will receive err rather
+	// than io.EOF.
+	CloseWithError(err error) error
+	// Stats returns the ChecksummedWriterStats gathered so far.
+	Stats() ChecksummedWriterStats
+	// Describe returns the ChecksummedOverhead of the writing so far,
+	// comparing the underlying content written with the content accepted.
+	// It remains available after Close.
+	Describe() ChecksummedOverhead
+	// Checkpoint returns the state of the ChecksummedWriter, including that
+	// of the interval in progress, for RestoreChecksummedWriter to continue
+	// from after a restart. This requires the hashing function's hashes to
+	// implement encoding.BinaryMarshaler, as those of the standard library
+	// do. It should not be called concurrently with writes.
+	Checkpoint() (ChecksummedWriterCheckpoint, error)
+	// Generation returns the ChecksummedGeneration of the most recent Flush,
+	// identifying the content sealed by it for ChecksummedReader.VerifyAt.
+	// It is safe to call concurrently with the other methods.
+	Generation() ChecksummedGeneration
+	// CloseWithoutDelegate closes the ChecksummedWriter the same as Close,
+	// writing out any remaining content, but leaves the underlying io.Writer
+	// open, such as a long-lived file or socket managed separately.
+	CloseWithoutDelegate() error
+	// Sync commits the content written so far to stable storage, if the
+	// underlying io.Writer has a Sync method as *os.File does, and otherwise
+	// does nothing. Content the ChecksummedWriter is still holding back,
+	// such as an interval a multi-core writer is buffering, is not included.
+	Sync() error
+	// Unwrap implements the Unwrapper interface, returning the underlying
+	// io.Writer.
+	Unwrap() interface{}
 }
 
 // NewChecksummedWriter returns a ChecksummedWriter that delegates requests to
 // an underlying io.Writer and embeds checksums of the content at given
 // intervals using the hashing function given.
+//
+// Like the other constructors, it panics if the interval or hashing function
+// are not valid; see ChecksummedConfig.Validate.
 func NewChecksummedWriter(delegate io.Writer, checksumInterval int, newHash func() hash.Hash32) ChecksummedWriter {
-	return newChecksummedWriterImpl(delegate, checksumInterval, newHash)
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(hash32(newHash))))
+}
+
+// NewChecksummedWriter64 returns a ChecksummedWriter that delegates requests
+// to an underlying io.Writer and embeds 8 byte checksums of the content at
+// given intervals using the 64 bit hashing function given.
+func NewChecksummedWriter64(delegate io.Writer, checksumInterval int, newHash func() hash.Hash64) ChecksummedWriter {
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(hash64(newHash))))
+}
+
+// NewChecksummedWriterHash returns a ChecksummedWriter that delegates
+// requests to an underlying io.Writer and embeds checksums of the content at
+// given intervals using the hashing function given, such as sha256.New.
+//
+// Each interval of content will be followed by a checksum of
+// newHash().Size() bytes.
+func NewChecksummedWriterHash(delegate io.Writer, checksumInterval int, newHash func() hash.Hash) ChecksummedWriter {
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(newHash)))
+}
+
+// NewAppendingChecksummedWriter returns a ChecksummedWriter that continues
+// the checksummed content already in delegate, as if the original
+// ChecksummedWriter had never been closed.
+//
+// The delegate is seeked to its end and any trailing partial interval is
+// re-read to restore the in progress checksum, so the next checksum written
+// will cover both the existing and new content of that interval.
+func NewAppendingChecksummedWriter(delegate io.ReadWriteSeeker, checksumInterval int, newHash func() hash.Hash32) (ChecksummedWriter, error) {
+	return newAppendingChecksummedWriterImpl(delegate, checksumInterval, hash32(newHash))
+}
+
+// NewAppendingChecksummedWriterHash is the same as
+// NewAppendingChecksummedWriter but for checksums of newHash().Size() bytes
+// using the hashing function given.
+func NewAppendingChecksummedWriterHash(delegate io.ReadWriteSeeker, checksumInterval int, newHash func() hash.Hash) (ChecksummedWriter, error) {
+	return newAppendingChecksummedWriterImpl(delegate, checksumInterval, newHash)
 }
 
 type checksummedReaderImpl struct {
+	// stats are updated atomically as Warm may run concurrently; kept first
+	// for 64 bit alignment.
+	stats            ChecksummedReaderStats
 	delegate         io.ReadSeeker
 	checksumInterval int
 	checksumOffset   int
-	newHash          func() hash.Hash32
+	checksumSize     int
+	newHash          func() hash.Hash
 	checksum         []byte
+	onCorruption     func(blockIndex int64, offset int64)
+	cache            *VerifiedBlockCache
+	skipCorrupted    bool
+	zeroCorrupted    bool
+	replaceCorrupted func(blockIndex int64, offset int64, block []byte) bool
+	skipped          []Range
+	// block is only set with AutoVerify and holds the verified content of the
+	// interval at blockIndex, blockLength bytes long.
+	block       []byte
+	blockIndex  int64
+	blockLength int
+	// verifyBlock and verifyHash are reused by Verify and the like so hot
+	// scrub loops don't allocate.
+	verifyBlock []byte
+	verifyHash  hash.Hash
+	// oneByte is ReadByte's buffer, kept here so it doesn't allocate.
+	oneByte [1]byte
+	// flushed is set with ChecksummedReaderOptions.Flushed; flushes holds
+	// the intervals ended early among the first mapped, in order.
+	flushed bool
+	flushes []flushedInterval
+	mapped  int64
+}
+
+// flushedInterval is an interval at index ended early by
+// ChecksummedWriter.Flush, holding length bytes of content from start.
+type flushedInterval struct {
+	index  int64
+	start  int64
+	length int
 }
 
-func newChecksummedReaderImpl(delegate io.ReadSeeker, interval int, newHash func() hash.Hash32) ChecksummedReader {
+func newChecksummedReaderImpl(delegate io.ReadSeeker, interval int, newHash func() hash.Hash) *checksummedReaderImpl {
+	mustValidateChecksummed(interval, newHash)
+	checksumSize := newHash().Size()
 	return &checksummedReaderImpl{
 		delegate:         delegate,
 		checksumInterval: interval,
+		checksumSize:     checksumSize,
 		newHash:          newHash,
-		checksum:         make([]byte, 4),
+		checksum:         make([]byte, checksumSize),
 	}
 }
 
 func (cri *checksummedReaderImpl) Read(v []byte) (int, error) {
+	defer allocAuditEnd(&allocAudit.read, &cri.stats.Allocs, allocAuditStart())
+	if cri.block != nil {
+		n, err := cri.readVerified(v)
+		atomic.AddUint64(&cri.stats.BytesRead, uint64(n))
+		return n, err
+	}
 	if cri.checksumOffset+len(v) > cri.checksumInterval {
 		v = v[:cri.checksumInterval-cri.checksumOffset]
 	}
 	n, err := cri.delegate.Read(v)
+	cri.countPhysical(cri.checksumOffset, n)
 	cri.checksumOffset += n
+	atomic.AddUint64(&cri.stats.BytesRead, uint64(n))
 	if err == nil {
 		if cri.checksumOffset == cri.checksumInterval {
-			io.ReadFull(cri.delegate, cri.checksum)
+			c, _ := io.ReadFull(cri.delegate, cri.checksum)
+			cri.countPhysical(cri.checksumInterval, c)
 			cri.checksumOffset = 0
 		}
 	}
 	return n, err
 }
 
-func (cri *checksummedReaderImpl) Seek(offset int64, whence int) (int64, error) {
-	switch whence {
-	case 0:
-	case 1:
+func (cri *checksummedReaderImpl) ReadByte() (byte, error) {
+	for {
+		n, err := cri.Read(cri.oneByte[:])
+		if n == 1 {
+			return cri.oneByte[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readVerified is Read for AutoVerify, reading and verifying entire intervals
+// at a time and serving content from the verified copy.
+func (cri *checksummedReaderImpl) readVerified(v []byte) (int, error) {
+	for {
 		o, err := cri.delegate.Seek(0, 1)
-		cri.checksumOffset = int(o % (int64(cri.checksumInterval) + 4))
 		if err != nil {
-			return o - (o / (int64(cri.checksumInterval) + 4) * 4), err
+			return 0, err
+		}
+		index := o / cri.blockSize()
+		offset := int(o % cri.blockSize())
+		if index != cri.blockIndex && cri.cache != nil {
+			if n, ok := cri.cache.get(index, cri.block); ok {
+				cri.blockIndex = index
+				cri.blockLength = n
+				cri.mapInterval(index, n, true)
+			}
+		}
+		if index != cri.blockIndex {
+			cri.blockIndex = -1
+			if _, err = cri.delegate.Seek(index*cri.blockSize(), 0); err != nil {
+				return 0, err
+			}
+			n, err := io.ReadFull(cri.delegate, cri.block)
+			cri.countPhysical(0, n)
+			whole := err == nil
+			if err == io.ErrUnexpectedEOF {
+				if n > cri.checksumInterval {
+					// The checksum itself was cut short, so the content
+					// can't be trusted.
+					atomic.AddUint64(&cri.stats.BlocksVerified, 1)
+					err = cri.mismatch(index, cri.block[:n])
+				} else {
+					err = nil
+				}
+			} else if err == nil {
+				atomic.AddUint64(&cri.stats.BlocksVerified, 1)
+				var ok bool
+				if n, ok = checkInterval(cri.newHash(), cri.checksumInterval, cri.block, index, cri.checksum); !ok {
+					err = cri.mismatch(index, cri.block)
+				} else if n < cri.checksumInterval && !cri.flushed {
+					err = endedEarly(index)
+				}
+			}
+			zeroed := false
+			if _, ok := err.(*ChecksumMismatchError); ok {
+				cri.corrupted(index)
+				if length, ok := cri.replace(index, cri.block); ok && (length == cri.checksumInterval || cri.flushed) {
+					n = length
+					err = nil
+				} else if cri.skipCorrupted {
+					cri.skip(index)
+					if !cri.zeroCorrupted {
+						if whole {
+							cri.mapInterval(index, 0, false)
+						}
+						if _, err = cri.delegate.Seek((index+1)*cri.blockSize(), 0); err != nil {
+							return 0, err
+						}
+						continue
+					}
+					n = cri.checksumInterval
+					for i := range cri.block[:n] {
+						cri.block[i] = 0
+					}
+					zeroed = true
+					err = nil
+				}
+			}
+			if err != nil {
+				cri.delegate.Seek(o, 0)
+				return 0, err
+			}
+			if whole {
+				cri.mapInterval(index, n, true)
+			}
+			cri.blockIndex = index
+			cri.blockLength = n
+			if cri.cache != nil && n == cri.checksumInterval && !zeroed {
+				cri.cache.put(index, cri.block[:n])
+			}
+		}
+		if offset >= cri.blockLength {
+			if _, err = cri.delegate.Seek(o, 0); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		n := copy(v, cri.block[offset:cri.blockLength])
+		offset += n
+		if offset == cri.checksumInterval || (cri.flushed && offset == cri.blockLength && index < cri.mapped) {
+			// The rest of the interval is its checksum, or the padding of
+			// one ended early and then its checksum.
+			offset = int(cri.blockSize())
+		}
+		o = index*cri.blockSize() + int64(offset)
+		if _, err = cri.delegate.Seek(o, 0); err != nil {
+			return n, err
+		}
+		cri.checksumOffset = int(o % cri.blockSize())
+		return n, nil
+	}
+}
+
+func (cri *checksummedReaderImpl) ReadContext(ctx context.Context, v []byte) (int, error) {
+	var n int
+	for len(v) > 0 {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		piece := v
+		if len(piece) > cri.checksumInterval {
+			piece = piece[:cri.checksumInterval]
 		}
-		offset = o - (o / (int64(cri.checksumInterval) + 4) * 4) + offset
-	case 2:
-		o, err := cri.delegate.Seek(0, 2)
-		cri.checksumOffset = int(o % (int64(cri.checksumInterval) + 4))
+		m, err := cri.Read(piece)
+		n += m
 		if err != nil {
-			return o - (o / (int64(cri.checksumInterval) + 4) * 4), err
+			return n, err
+		}
+		v = v[m:]
+	}
+	return n, nil
+}
+
+// WriteTo implements the io.WriterTo interface, giving io.Copy a fast path
+// that writes the content from the current position onward an interval at a
+// time, verifying each interval first if AutoVerify is set.
+func (cri *checksummedReaderImpl) WriteTo(w io.Writer) (int64, error) {
+	if cri.flushed {
+		return cri.writeToFlushed(w)
+	}
+	var total int64
+	block := cri.block
+	if block == nil {
+		block = make([]byte, cri.blockSize())
+	} else {
+		cri.blockIndex = -1
+	}
+	for {
+		start := cri.checksumOffset
+		if start > cri.checksumInterval {
+			return total, nil
+		}
+		from := start
+		if cri.block != nil && start > 0 {
+			// Verifying requires the whole interval.
+			if _, err := cri.delegate.Seek(-int64(start), 1); err != nil {
+				return total, err
+			}
+			from = 0
+		}
+		n, err := io.ReadFull(cri.delegate, block[from:])
+		cri.countPhysical(from, n)
+		n += from
+		end := n
+		switch err {
+		case nil:
+			end = cri.checksumInterval
+			if cri.block != nil {
+				atomic.AddUint64(&cri.stats.BlocksVerified, 1)
+				hash := cri.newHash()
+				index := int64(-1)
+				if _, ok := hash.(checksumIndexer); ok {
+					// Only worth finding the index for hashes that use it.
+					o, err := cri.delegate.Seek(0, 1)
+					if err != nil {
+						return total, err
+					}
+					index = o/cri.blockSize() - 1
+				}
+				length, ok := checkInterval(hash, cri.checksumInterval, block, index, cri.checksum)
+				if !ok || length < end {
+					o, err := cri.delegate.Seek(0, 1)
+					if err != nil {
+						return total, ErrChecksumMismatch
+					}
+					index = o/cri.blockSize() - 1
+					if ok {
+						cri.delegate.Seek(int64(start-n), 1)
+						return total, endedEarly(index)
+					}
+					cri.corrupted(index)
+					mismatch := cri.mismatch(index, block)
+					if length, ok := cri.replace(index, block); !ok || length < end {
+						if !cri.skipCorrupted {
+							cri.delegate.Seek(int64(start-n), 1)
+							return total, mismatch
+						}
+						cri.skip(index)
+						if !cri.zeroCorrupted {
+							cri.checksumOffset = 0
+							continue
+						}
+						for i := range block[:end] {
+							block[i] = 0
+						}
+					}
+				}
+			}
+		case io.EOF, io.ErrUnexpectedEOF:
+			if n > cri.checksumInterval {
+				if cri.block != nil {
+					o, _ := cri.delegate.Seek(0, 1)
+					return total, cri.mismatch(o/cri.blockSize(), block[:n])
+				}
+				end = cri.checksumInterval
+			}
+		default:
+			return total, err
+		}
+		cri.checksumOffset = n % int(cri.blockSize())
+		if end > start {
+			w2, err2 := w.Write(block[start:end])
+			total += int64(w2)
+			atomic.AddUint64(&cri.stats.BytesRead, uint64(w2))
+			if err2 == nil && w2 < end-start {
+				err2 = io.ErrShortWrite
+			}
+			if err2 != nil {
+				return total, err2
+			}
+		}
+		if err != nil {
+			return total, nil
+		}
+	}
+}
+
+// writeToFlushed is WriteTo for Flushed, going through Read as that follows
+// the intervals ended early.
+func (cri *checksummedReaderImpl) writeToFlushed(w io.Writer) (int64, error) {
+	var total int64
+	buf := make([]byte, cri.checksumInterval)
+	for {
+		n, err := cri.Read(buf)
+		if n > 0 {
+			w2, err2 := w.Write(buf[:n])
+			total += int64(w2)
+			if err2 == nil && w2 < n {
+				err2 = io.ErrShortWrite
+			}
+			if err2 != nil {
+				return total, err2
+			}
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// endedEarly returns the error for an interval at index ended early by
+// ChecksummedWriter.Flush, read without Flushed set.
+func endedEarly(index int64) error {
+	return fmt.Errorf("interval %d ended early by Flush; read with Flushed", index)
+}
+
+func (cri *checksummedReaderImpl) Seek(offset int64, whence int) (int64, error) {
+	o, err := cri.delegate.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	current := ContentSize(o, cri.checksumInterval, cri.checksumSize)
+	if cri.flushed {
+		current = cri.logical(o)
+	}
+	var base int64
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		base = current
+	case io.SeekEnd:
+		end, err := cri.delegate.Seek(0, io.SeekEnd)
+		if err == nil {
+			_, err = cri.delegate.Seek(o, io.SeekStart)
+		}
+		if err == nil {
+			base, err = cri.contentSize(end)
+		}
+		if err != nil {
+			return current, err
 		}
-		offset = o - (o / (int64(cri.checksumInterval) + 4) * 4) + offset
 	default:
-		o, _ := cri.delegate.Seek(0, 1)
-		return o, fmt.Errorf("invalid whence %d", whence)
+		return current, fmt.Errorf("invalid whence %d", whence)
+	}
+	if base+offset < 0 {
+		return current, fmt.Errorf("negative position %d", base+offset)
+	}
+	offset += base
+	if o, err = cri.physical(offset); err != nil {
+		return current, err
+	}
+	o, err = cri.delegate.Seek(o, io.SeekStart)
+	cri.checksumOffset = int(o % cri.blockSize())
+	return cri.logical(o), err
+}
+
+// blockSize returns the size of each interval plus its checksum within the
+// underlying content.
+func (cri *checksummedReaderImpl) blockSize() int64 {
+	return int64(cri.checksumInterval + cri.checksumSize)
+}
+
+// logical translates an offset within the underlying content to the offset
+// within the checksummed content.
+func (cri *checksummedReaderImpl) logical(o int64) int64 {
+	if !cri.flushed {
+		return o - (o / cri.blockSize() * int64(cri.checksumSize))
 	}
-	o, err := cri.delegate.Seek(offset+(offset/int64(cri.checksumInterval)*4), 0)
-	cri.checksumOffset = int(o % (int64(cri.checksumInterval) + 4))
-	return o - (o / (int64(cri.checksumInterval) + 4) * 4), err
+	index := o / cri.blockSize()
+	within := o % cri.blockSize()
+	if length := int64(cri.intervalLength(index)); within > length {
+		within = length
+	}
+	return cri.intervalStart(index) + within
+}
+
+// physical translates an offset within the checksummed content to the
+// offset within the underlying content, mapping intervals as needed with
+// Flushed set.
+func (cri *checksummedReaderImpl) physical(offset int64) (int64, error) {
+	if !cri.flushed {
+		return offset + offset/int64(cri.checksumInterval)*int64(cri.checksumSize), nil
+	}
+	if err := cri.mapIntervals(func(start int64) bool { return start <= offset }); err != nil {
+		return 0, err
+	}
+	// Past the last interval ended early, the rest are whole.
+	index := offset / int64(cri.checksumInterval)
+	i := sort.Search(len(cri.flushes), func(i int) bool { return cri.flushes[i].start > offset })
+	if i > 0 {
+		f := cri.flushes[i-1]
+		if end := f.start + int64(f.length); offset < end {
+			index = f.index
+		} else {
+			index = f.index + 1 + (offset-end)/int64(cri.checksumInterval)
+		}
+	}
+	return index*cri.blockSize() + offset - cri.intervalStart(index), nil
+}
+
+// intervalStart returns where the interval at index starts within the
+// checksummed content, given the intervals mapped so far; those not yet
+// mapped are taken to be whole.
+func (cri *checksummedReaderImpl) intervalStart(index int64) int64 {
+	i := sort.Search(len(cri.flushes), func(i int) bool { return cri.flushes[i].index >= index })
+	if i == 0 {
+		return index * int64(cri.checksumInterval)
+	}
+	f := cri.flushes[i-1]
+	return f.start + int64(f.length) + (index-f.index-1)*int64(cri.checksumInterval)
+}
+
+// intervalLength returns the length of the content of the interval at
+// index, given the intervals mapped so far.
+func (cri *checksummedReaderImpl) intervalLength(index int64) int {
+	i := sort.Search(len(cri.flushes), func(i int) bool { return cri.flushes[i].index >= index })
+	if i < len(cri.flushes) && cri.flushes[i].index == index {
+		return cri.flushes[i].length
+	}
+	return cri.checksumInterval
+}
+
+// mapIntervals reads and verifies the intervals after those already mapped,
+// for Flushed, for as long as more returns true given where the next one
+// starts and there are whole intervals left. The position is restored
+// afterward.
+func (cri *checksummedReaderImpl) mapIntervals(more func(start int64) bool) error {
+	if !more(cri.intervalStart(cri.mapped)) {
+		return nil
+	}
+	originalOffset, err := cri.delegate.Seek(0, 1)
+	if err != nil {
+		return err
+	}
+	if _, err = cri.delegate.Seek(cri.mapped*cri.blockSize(), 0); err != nil {
+		return err
+	}
+	block, hash := cri.verifyScratch()
+	for more(cri.intervalStart(cri.mapped)) {
+		n, err := io.ReadFull(cri.delegate, block)
+		cri.countPhysical(0, n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		hash.Reset()
+		n, ok := checkInterval(hash, cri.checksumInterval, block, cri.mapped, cri.checksum)
+		cri.mapInterval(cri.mapped, n, ok)
+	}
+	_, err = cri.delegate.Seek(originalOffset, 0)
+	return err
+}
+
+// mapInterval records the interval at index, if it is the next to map, as
+// holding length bytes of content, or as whole if it was not checksum
+// valid, since its true length can't be known.
+func (cri *checksummedReaderImpl) mapInterval(index int64, length int, ok bool) {
+	if !cri.flushed || index != cri.mapped {
+		return
+	}
+	if ok && length < cri.checksumInterval {
+		cri.flushes = append(cri.flushes, flushedInterval{index: index, start: cri.intervalStart(index), length: length})
+	}
+	cri.mapped++
 }
 
 func (cri *checksummedReaderImpl) Verify() (bool, error) {
+	defer allocAuditEnd(&allocAudit.verify, &cri.stats.Allocs, allocAuditStart())
 	originalOffset, err := cri.delegate.Seek(0, 1)
 	if err != nil {
 		return false, err
 	}
-	if cri.checksumOffset > 0 {
-		_, err = cri.delegate.Seek(-int64(cri.checksumOffset), 1)
-		if err != nil {
-			return false, err
+	if cri.cache != nil && cri.cache.has(originalOffset/cri.blockSize()) {
+		return true, nil
+	}
+	start := originalOffset - int64(cri.checksumOffset)
+	block, hash := cri.verifyScratch()
+	// With an io.ReaderAt, such as an *os.File, the block is read without
+	// moving the shared position, so other readers of the same file aren't
+	// disturbed and no seeking back is needed.
+	ra, readAt := cri.delegate.(io.ReaderAt)
+	var n int
+	if readAt {
+		n, err = ra.ReadAt(block, start)
+		if n == len(block) {
+			err = nil
+		} else if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
 		}
+	} else {
+		if cri.checksumOffset > 0 {
+			if _, err = cri.delegate.Seek(start, 0); err != nil {
+				return false, err
+			}
+		}
+		n, err = io.ReadFull(cri.delegate, block)
 	}
-	block := make([]byte, cri.checksumInterval+4)
-	checksum := block[cri.checksumInterval:]
-	_, err = io.ReadFull(cri.delegate, block)
+	cri.countPhysical(0, n)
 	if err != nil {
 		return false, err
 	}
-	block = block[:cri.checksumInterval]
-	hash := cri.newHash()
-	hash.Write(block)
-	verified := bytes.Equal(checksum, hash.Sum(cri.checksum[:0]))
+	atomic.AddUint64(&cri.stats.BlocksVerified, 1)
+	length, verified := checkInterval(hash, cri.checksumInterval, block, start/cri.blockSize(), cri.checksum)
+	if !verified {
+		cri.corrupted(start / cri.blockSize())
+	} else if cri.cache != nil && length == cri.checksumInterval {
+		cri.cache.put(start/cri.blockSize(), block[:length])
+	}
+	if readAt {
+		return verified, nil
+	}
 	_, err = cri.delegate.Seek(originalOffset, 0)
 	if err != nil {
 		return verified, err
@@ -161,6 +1032,254 @@ func (cri *checksummedReaderImpl) Verify() (bool, error) {
 	return verified, nil
 }
 
+func (cri *checksummedReaderImpl) VerifyDetail() (bool, Range, error) {
+	o, err := cri.delegate.Seek(0, 1)
+	if err != nil {
+		return false, Range{}, err
+	}
+	index := o / cri.blockSize()
+	r := Range{Offset: index * int64(cri.checksumInterval), Length: int64(cri.checksumInterval)}
+	if cri.flushed {
+		r = Range{Offset: cri.intervalStart(index), Length: int64(cri.intervalLength(index))}
+	}
+	verified, err := cri.Verify()
+	return verified, r, err
+}
+
+// verifyScratch returns the reusable block and reset hash for verifying an
+// interval, allocating them on first use.
+func (cri *checksummedReaderImpl) verifyScratch() ([]byte, hash.Hash) {
+	if cri.verifyBlock == nil {
+		cri.verifyBlock = make([]byte, cri.blockSize())
+		cri.verifyHash = cri.newHash()
+	} else {
+		cri.verifyHash.Reset()
+	}
+	return cri.verifyBlock, cri.verifyHash
+}
+
+func (cri *checksummedReaderImpl) VerifyAll(progress func(verified int64, total int64)) ([]int64, error) {
+	return cri.verifyBlocks(context.Background(), 0, -1, progress)
+}
+
+func (cri *checksummedReaderImpl) VerifyRange(offset int64, length int64) ([]int64, error) {
+	return cri.VerifyRangeContext(context.Background(), offset, length)
+}
+
+func (cri *checksummedReaderImpl) VerifyAllContext(ctx context.Context, progress func(verified int64, total int64)) ([]int64, error) {
+	return cri.verifyBlocks(ctx, 0, -1, progress)
+}
+
+func (cri *checksummedReaderImpl) VerifyRangeContext(ctx context.Context, offset int64, length int64) ([]int64, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range %d %d", offset, length)
+	}
+	if length == 0 {
+		return nil, nil
+	}
+	first, err := cri.physical(offset)
+	if err != nil {
+		return nil, err
+	}
+	last, err := cri.physical(offset + length - 1)
+	if err != nil {
+		return nil, err
+	}
+	return cri.verifyBlocks(ctx, first/cri.blockSize(), last/cri.blockSize(), nil)
+}
+
+func (cri *checksummedReaderImpl) VerifyAt(generation ChecksummedGeneration) ([]int64, error) {
+	// Flush pads the intervals it ends early, so everything sealed is whole
+	// intervals.
+	if generation.Sealed < cri.blockSize() {
+		return nil, nil
+	}
+	return cri.verifyBlocks(context.Background(), 0, generation.Sealed/cri.blockSize()-1, nil)
+}
+
+// verifyBlocks verifies the intervals first through last inclusive, or
+// through the final complete interval if last is negative, restoring the
+// position afterwards, including when ctx is done first.
+func (cri *checksummedReaderImpl) verifyBlocks(ctx context.Context, first int64, last int64, progress func(verified int64, total int64)) ([]int64, error) {
+	originalOffset, err := cri.delegate.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	end, err := cri.delegate.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	if complete := end/cri.blockSize() - 1; last < 0 || last > complete {
+		last = complete
+	}
+	if _, err = cri.delegate.Seek(first*cri.blockSize(), 0); err != nil {
+		return nil, err
+	}
+	var corrupted []int64
+	total := last - first + 1
+	block, hash := cri.verifyScratch()
+	for i := first; i <= last; i++ {
+		if err = ctx.Err(); err != nil {
+			if _, err2 := cri.delegate.Seek(originalOffset, 0); err2 != nil {
+				return corrupted, err2
+			}
+			return corrupted, err
+		}
+		var n int
+		n, err = io.ReadFull(cri.delegate, block)
+		cri.countPhysical(0, n)
+		if err != nil {
+			return corrupted, err
+		}
+		atomic.AddUint64(&cri.stats.BlocksVerified, 1)
+		hash.Reset()
+		if _, ok := checkInterval(hash, cri.checksumInterval, block, i, cri.checksum); !ok {
+			corrupted = append(corrupted, i)
+			cri.corrupted(i)
+		}
+		if progress != nil {
+			progress(i-first+1, total)
+		}
+	}
+	_, err = cri.delegate.Seek(originalOffset, 0)
+	return corrupted, err
+}
+
+func (cri *checksummedReaderImpl) Warm(ranges []Range) <-chan error {
+	errChan := make(chan error, 1)
+	if cri.cache == nil {
+		errChan <- fmt.Errorf("no cache to warm")
+		close(errChan)
+		return errChan
+	}
+	ra, ok := cri.delegate.(io.ReaderAt)
+	if !ok {
+		errChan <- fmt.Errorf("delegate is not an io.ReaderAt")
+		close(errChan)
+		return errChan
+	}
+	// The intervals are found before starting, as with Flushed that may
+	// read the content through the shared position.
+	spans := make([][2]int64, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Offset < 0 || r.Length <= 0 {
+			continue
+		}
+		first, err := cri.physical(r.Offset)
+		if err == nil {
+			var last int64
+			if last, err = cri.physical(r.Offset + r.Length - 1); err == nil {
+				spans = append(spans, [2]int64{first / cri.blockSize(), last / cri.blockSize()})
+			}
+		}
+		if err != nil {
+			errChan <- err
+			close(errChan)
+			return errChan
+		}
+	}
+	go func() {
+		defer close(errChan)
+		block := make([]byte, cri.blockSize())
+		checksum := make([]byte, cri.checksumSize)
+		for _, span := range spans {
+			for i := span[0]; i <= span[1]; i++ {
+				if cri.cache.has(i) {
+					continue
+				}
+				n, err := ra.ReadAt(block, i*cri.blockSize())
+				cri.countPhysical(0, n)
+				if err != nil {
+					if err == io.EOF {
+						// The rest has no checksum to verify.
+						break
+					}
+					errChan <- err
+					return
+				}
+				atomic.AddUint64(&cri.stats.BlocksVerified, 1)
+				length, ok := checkInterval(cri.newHash(), cri.checksumInterval, block, i, checksum)
+				if !ok {
+					cri.corrupted(i)
+					continue
+				}
+				if length == cri.checksumInterval {
+					// The cache holds whole intervals only.
+					cri.cache.put(i, block[:length])
+				}
+			}
+		}
+		errChan <- nil
+	}()
+	return errChan
+}
+
+// mismatch returns the ChecksumMismatchError for the interval at index,
+// block being the interval as read followed by its checksum, possibly cut
+// short.
+func (cri *checksummedReaderImpl) mismatch(index int64, block []byte) *ChecksumMismatchError {
+	e := newChecksumMismatchError(index, cri.checksumInterval, cri.newHash(), block)
+	e.Offset = cri.intervalStart(index)
+	return e
+}
+
+// newChecksumMismatchError returns the ChecksumMismatchError for the interval
+// at index, block being the interval as read followed by its checksum,
+// possibly cut short, and hash a new hash for computing its checksum.
+func newChecksumMismatchError(index int64, interval int, hash hash.Hash, block []byte) *ChecksumMismatchError {
+	setChecksumIndex(hash, index)
+	hash.Write(block[:interval])
+	return &ChecksumMismatchError{
+		BlockIndex: index,
+		Offset:     index * int64(interval),
+		Length:     int64(interval),
+		Expected:   append([]byte(nil), block[interval:]...),
+		Computed:   hash.Sum(nil),
+	}
+}
+
+// replace has ReplaceCorrupted, if set, overwrite block, a whole interval
+// and checksum, with a replacement for the interval at index, returning the
+// length of its content and whether the replacement is checksum valid.
+func (cri *checksummedReaderImpl) replace(index int64, block []byte) (int, bool) {
+	if cri.replaceCorrupted == nil || !cri.replaceCorrupted(index, index*cri.blockSize(), block) {
+		return 0, false
+	}
+	return checkInterval(cri.newHash(), cri.checksumInterval, block, index, cri.checksum)
+}
+
+// ReplaceFromReplica returns a function for
+// ChecksummedReaderOptions.ReplaceCorrupted that reads replacement intervals
+// from replica, which must have the same layout.
+func ReplaceFromReplica(replica io.ReaderAt) func(blockIndex int64, offset int64, block []byte) bool {
+	return func(blockIndex int64, offset int64, block []byte) bool {
+		n, _ := replica.ReadAt(block, offset)
+		return n == len(block)
+	}
+}
+
+// skip records the interval at index as passed over by SkipCorrupted.
+func (cri *checksummedReaderImpl) skip(index int64) {
+	offset := cri.intervalStart(index)
+	if l := len(cri.skipped); l > 0 && cri.skipped[l-1].Offset == offset {
+		return
+	}
+	cri.skipped = append(cri.skipped, Range{Offset: offset, Length: int64(cri.checksumInterval)})
+}
+
+func (cri *checksummedReaderImpl) Skipped() []Range {
+	return append([]Range(nil), cri.skipped...)
+}
+
+// corrupted reports the interval at index as not checksum valid to any
+// OnCorruption callback.
+func (cri *checksummedReaderImpl) corrupted(index int64) {
+	atomic.AddUint64(&cri.stats.VerifyFailures, 1)
+	if cri.onCorruption != nil {
+		cri.onCorruption(index, index*cri.blockSize())
+	}
+}
+
 func (cri *checksummedReaderImpl) Close() error {
 	var err error
 	if c, ok := cri.delegate.(io.Closer); ok {
@@ -170,26 +1289,133 @@ func (cri *checksummedReaderImpl) Close() error {
 	return err
 }
 
+func (cri *checksummedReaderImpl) Size() (int64, error) {
+	o, err := cri.delegate.Seek(0, 1)
+	if err != nil {
+		return 0, err
+	}
+	end, err := cri.delegate.Seek(0, 2)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = cri.delegate.Seek(o, 0); err != nil {
+		return 0, err
+	}
+	return cri.contentSize(end)
+}
+
+// contentSize returns the length of the content within end bytes of the
+// underlying content, mapping every interval with Flushed set.
+func (cri *checksummedReaderImpl) contentSize(end int64) (int64, error) {
+	if !cri.flushed {
+		return ContentSize(end, cri.checksumInterval, cri.checksumSize), nil
+	}
+	if err := cri.mapIntervals(func(int64) bool { return true }); err != nil {
+		return 0, err
+	}
+	partial := end - cri.mapped*cri.blockSize()
+	if partial > int64(cri.checksumInterval) {
+		partial = int64(cri.checksumInterval)
+	} else if partial < 0 {
+		partial = 0
+	}
+	return cri.intervalStart(cri.mapped) + partial, nil
+}
+
+func (cri *checksummedReaderImpl) Stats() ChecksummedReaderStats {
+	return ChecksummedReaderStats{
+		BytesRead:         atomic.LoadUint64(&cri.stats.BytesRead),
+		BlocksVerified:    atomic.LoadUint64(&cri.stats.BlocksVerified),
+		VerifyFailures:    atomic.LoadUint64(&cri.stats.VerifyFailures),
+		PhysicalBytesRead: atomic.LoadUint64(&cri.stats.PhysicalBytesRead),
+		ChecksumBytesRead: atomic.LoadUint64(&cri.stats.ChecksumBytesRead),
+		Allocs:            atomic.LoadUint64(&cri.stats.Allocs),
+	}
+}
+
+func (cri *checksummedReaderImpl) Describe() ChecksummedOverhead {
+	s := cri.Stats()
+	return ChecksummedOverhead{LogicalBytes: s.BytesRead, PhysicalBytes: s.PhysicalBytesRead, ChecksumBytes: s.ChecksumBytesRead}
+}
+
+// countPhysical counts n bytes of underlying content read starting
+// blockOffset bytes into an interval and its checksum.
+func (cri *checksummedReaderImpl) countPhysical(blockOffset int, n int) {
+	atomic.AddUint64(&cri.stats.PhysicalBytesRead, uint64(n))
+	if end := blockOffset + n; end > cri.checksumInterval {
+		if blockOffset < cri.checksumInterval {
+			blockOffset = cri.checksumInterval
+		}
+		atomic.AddUint64(&cri.stats.ChecksumBytesRead, uint64(end-blockOffset))
+	}
+}
+
+func (cri *checksummedReaderImpl) CloseWithoutDelegate() error {
+	cri.delegate = errDelegate
+	return nil
+}
+
+func (cri *checksummedReaderImpl) Unwrap() interface{} {
+	return unwrapDelegate(cri.delegate)
+}
+
 type checksummedWriterImpl struct {
+	stats ChecksummedWriterStats
+	// padded counts the bytes Flush padded intervals out with; kept near
+	// stats for 64 bit alignment.
+	padded uint64
+	generationTracker
+	// base is the length of any existing content appended to.
+	base             int64
 	delegate         io.Writer
 	checksumInterval int
 	checksumOffset   int
-	newHash          func() hash.Hash32
-	hash             hash.Hash32
+	newHash          func() hash.Hash
+	hash             hash.Hash
 	checksum         []byte
 }
 
-func newChecksummedWriterImpl(delegate io.Writer, checksumInterval int, newHash func() hash.Hash32) *checksummedWriterImpl {
+func newChecksummedWriterImpl(delegate io.Writer, checksumInterval int, newHash func() hash.Hash) *checksummedWriterImpl {
+	mustValidateChecksummed(checksumInterval, newHash)
+	h := newHash()
 	return &checksummedWriterImpl{
 		delegate:         delegate,
 		checksumInterval: checksumInterval,
 		newHash:          newHash,
-		hash:             newHash(),
-		checksum:         make([]byte, 4),
+		hash:             h,
+		checksum:         make([]byte, h.Size()),
 	}
 }
 
+func newAppendingChecksummedWriterImpl(delegate io.ReadWriteSeeker, checksumInterval int, newHash func() hash.Hash) (*checksummedWriterImpl, error) {
+	cwi := newChecksummedWriterImpl(delegate, checksumInterval, newHash)
+	end, err := delegate.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	partial := end % int64(checksumInterval+len(cwi.checksum))
+	if partial > int64(checksumInterval) {
+		return nil, fmt.Errorf("partial checksum at end of content at %d", end-partial+int64(checksumInterval))
+	}
+	if partial > 0 {
+		if _, err = delegate.Seek(end-partial, 0); err != nil {
+			return nil, err
+		}
+		if _, err = io.CopyN(cwi.hash, delegate, partial); err != nil {
+			return nil, err
+		}
+		if _, err = delegate.Seek(end, 0); err != nil {
+			return nil, err
+		}
+		cwi.checksumOffset = int(partial)
+	}
+	cwi.base = end
+	cwi.generation.Sealed = end - partial
+	return cwi, nil
+}
+
 func (cwi *checksummedWriterImpl) Write(v []byte) (int, error) {
+	defer allocAuditEnd(&allocAudit.write, &cwi.stats.Allocs, allocAuditStart())
 	var n int
 	var n2 int
 	var err error
@@ -201,13 +1427,15 @@ func (cwi *checksummedWriterImpl) Write(v []byte) (int, error) {
 			return n, err
 		}
 		cwi.hash.Write(v[:cwi.checksumInterval-cwi.checksumOffset])
+		atomic.AddUint64(&cwi.stats.BytesWritten, uint64(n2))
 		v = v[cwi.checksumInterval-cwi.checksumOffset:]
-		binary.BigEndian.PutUint32(cwi.checksum, cwi.hash.Sum32())
-		_, err = cwi.delegate.Write(cwi.checksum)
+		setChecksumIndex(cwi.hash, cwi.blockIndex(cwi.checksumInterval))
+		_, err = cwi.delegate.Write(cwi.hash.Sum(cwi.checksum[:0]))
 		if err != nil {
 			cwi.delegate = errDelegate
 			return n, err
 		}
+		atomic.AddUint64(&cwi.stats.ChecksumsEmitted, 1)
 		cwi.hash = cwi.newHash()
 		cwi.checksumOffset = 0
 	}
@@ -220,25 +1448,128 @@ func (cwi *checksummedWriterImpl) Write(v []byte) (int, error) {
 		}
 		cwi.hash.Write(v)
 		cwi.checksumOffset += n2
+		atomic.AddUint64(&cwi.stats.BytesWritten, uint64(n2))
 	}
 	return n, err
 }
 
-func (cwi *checksummedWriterImpl) Close() error {
-	var err error
-	if c, ok := cwi.delegate.(io.Closer); ok {
-		err = c.Close()
+// blockIndex returns the index of the interval being written, by where it
+// starts within the underlying content, given how much of its content has
+// been counted as written so far.
+func (cwi *checksummedWriterImpl) blockIndex(counted int) int64 {
+	return (cwi.physical() - int64(counted)) / int64(cwi.checksumInterval+len(cwi.checksum))
+}
+
+// physical returns the length of the underlying content written so far,
+// including any existing content appended to.
+func (cwi *checksummedWriterImpl) physical() int64 {
+	return cwi.base + int64(atomic.LoadUint64(&cwi.stats.BytesWritten)+atomic.LoadUint64(&cwi.stats.ChecksumsEmitted)*uint64(len(cwi.checksum))+atomic.LoadUint64(&cwi.padded))
+}
+
+func (cwi *checksummedWriterImpl) WriteContext(ctx context.Context, v []byte) (int, error) {
+	return writeContext(ctx, cwi, v, cwi.checksumInterval)
+}
+
+func (cwi *checksummedWriterImpl) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, cwi.checksumInterval+len(cwi.checksum))
+	var total int64
+	for {
+		n, err := io.ReadFull(r, buf[:cwi.checksumInterval-cwi.checksumOffset])
+		if n > 0 {
+			cwi.hash.Write(buf[:n])
+			w := buf[:n]
+			if cwi.checksumOffset+n == cwi.checksumInterval {
+				setChecksumIndex(cwi.hash, cwi.blockIndex(cwi.checksumOffset))
+				w = cwi.hash.Sum(w)
+			}
+			if _, err2 := cwi.delegate.Write(w); err2 != nil {
+				cwi.delegate = errDelegate
+				return total, err2
+			}
+			total += int64(n)
+			atomic.AddUint64(&cwi.stats.BytesWritten, uint64(n))
+			cwi.checksumOffset += n
+			if cwi.checksumOffset == cwi.checksumInterval {
+				atomic.AddUint64(&cwi.stats.ChecksumsEmitted, 1)
+				cwi.hash = cwi.newHash()
+				cwi.checksumOffset = 0
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
 	}
+}
+
+func (cwi *checksummedWriterImpl) Flush() error {
+	if cwi.checksumOffset > 0 {
+		setChecksumIndex(cwi.hash, cwi.blockIndex(cwi.checksumOffset))
+		padding := appendFlushPadding(nil, cwi.checksumInterval-cwi.checksumOffset)
+		cwi.hash.Write(padding)
+		cwi.hash.Write(flushMark)
+		if _, err := cwi.delegate.Write(cwi.hash.Sum(padding)); err != nil {
+			cwi.delegate = errDelegate
+			return err
+		}
+		atomic.AddUint64(&cwi.stats.ChecksumsEmitted, 1)
+		atomic.AddUint64(&cwi.padded, uint64(cwi.checksumInterval-cwi.checksumOffset))
+		cwi.hash = cwi.newHash()
+		cwi.checksumOffset = 0
+	}
+	cwi.seal(cwi.physical())
+	return flushDelegate(cwi.delegate)
+}
+
+func (cwi *checksummedWriterImpl) Close() error {
+	return cwi.CloseWithError(nil)
+}
+
+func (cwi *checksummedWriterImpl) CloseWithError(err error) error {
+	err = closeDelegate(cwi.delegate, err)
 	cwi.delegate = errDelegate
 	return err
 }
 
+func (cwi *checksummedWriterImpl) Stats() ChecksummedWriterStats {
+	return ChecksummedWriterStats{
+		BytesWritten:     atomic.LoadUint64(&cwi.stats.BytesWritten),
+		ChecksumsEmitted: atomic.LoadUint64(&cwi.stats.ChecksumsEmitted),
+		Allocs:           atomic.LoadUint64(&cwi.stats.Allocs),
+	}
+}
+
+func (cwi *checksummedWriterImpl) Describe() ChecksummedOverhead {
+	return describeWriter(cwi.Stats(), len(cwi.checksum), atomic.LoadUint64(&cwi.padded))
+}
+
+func (cwi *checksummedWriterImpl) CloseWithoutDelegate() error {
+	cwi.delegate = errDelegate
+	return nil
+}
+
+func (cwi *checksummedWriterImpl) Unwrap() interface{} {
+	return unwrapDelegate(cwi.delegate)
+}
+
+func (cwi *checksummedWriterImpl) Sync() error {
+	return syncDelegate(cwi.delegate)
+}
+
 type multiCoreChecksummedWriter struct {
+	stats ChecksummedWriterStats
+	// padded counts the bytes Flush padded intervals out with; kept near
+	// stats for 64 bit alignment.
+	padded uint64
+	generationTracker
 	delegate         io.Writer
 	checksumInterval int
+	checksumSize     int
 	cores            int
-	newHash          func() hash.Hash32
-	hash             hash.Hash32
+	buffers          int
+	newHash          func() hash.Hash
 	buffer           *multiCoreChecksummedWriterBuffer
 	freeChan         chan *multiCoreChecksummedWriterBuffer
 	checksumChan     chan *multiCoreChecksummedWriterBuffer
@@ -246,12 +1577,20 @@ type multiCoreChecksummedWriter struct {
 	doneChan         chan struct{}
 	lock             sync.Mutex
 	err              error
+	closeErr         error
+	noCloseDelegate  bool
 	closed           bool
 }
 
 type multiCoreChecksummedWriterBuffer struct {
 	seq int64
-	buf []byte
+	// offset is where buf starts within the underlying content, giving the
+	// interval's index for hashes from NewIndexedHash.
+	offset int64
+	buf    []byte
+	// flush indicates an interval ended early by Flush, padded out and to
+	// be checksummed as such.
+	flush bool
 }
 
 // NewMultiCoreChecksummedWriter returns a ChecksummedWriter that delegates
@@ -263,18 +1602,55 @@ type multiCoreChecksummedWriterBuffer struct {
 // checksum intervals (e.g. 65532). It can be quite a bit slower on single core
 // systems or when using tiny checksum intervals.
 func NewMultiCoreChecksummedWriter(delegate io.Writer, checksumInterval int, newHash func() hash.Hash32, cores int) ChecksummedWriter {
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(hash32(newHash)), WithCores(cores, cores)))
+}
+
+// NewMultiCoreChecksummedWriter64 is the same as NewMultiCoreChecksummedWriter
+// but embeds 8 byte checksums using the 64 bit hashing function given.
+func NewMultiCoreChecksummedWriter64(delegate io.Writer, checksumInterval int, newHash func() hash.Hash64, cores int) ChecksummedWriter {
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(hash64(newHash)), WithCores(cores, cores)))
+}
+
+// NewMultiCoreChecksummedWriterHash is the same as
+// NewMultiCoreChecksummedWriter but embeds checksums of newHash().Size() bytes
+// using the hashing function given.
+func NewMultiCoreChecksummedWriterHash(delegate io.Writer, checksumInterval int, newHash func() hash.Hash, cores int) ChecksummedWriter {
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(newHash), WithCores(cores, cores)))
+}
+
+// NewPooledChecksummedWriter is the same as NewMultiCoreChecksummedWriterHash
+// but with the number of hashing workers and the number of interval sized
+// buffers given separately. Writes continue to be accepted while earlier
+// intervals are hashed, until all the buffers are in use; so memory use is
+// bounded to about buffers intervals. Having a few more buffers than workers
+// keeps the workers busy while the delegate is being written to.
+func NewPooledChecksummedWriter(delegate io.Writer, checksumInterval int, newHash func() hash.Hash, workers int, buffers int) ChecksummedWriter {
+	if buffers < 2 {
+		buffers = 2
+	}
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(newHash), WithCores(workers, buffers)))
+}
+
+func newMultiCoreChecksummedWriter(delegate io.Writer, checksumInterval int, newHash func() hash.Hash, cores int, buffers int) ChecksummedWriter {
+	mustValidateChecksummed(checksumInterval, newHash)
+	if cores < 1 || buffers < 1 {
+		panic(fmt.Errorf("invalid cores %d or buffers %d", cores, buffers))
+	}
+	checksumSize := newHash().Size()
 	cwi := &multiCoreChecksummedWriter{
 		delegate:         delegate,
 		checksumInterval: checksumInterval,
+		checksumSize:     checksumSize,
 		newHash:          newHash,
 		cores:            cores,
-		freeChan:         make(chan *multiCoreChecksummedWriterBuffer, cores+1),
-		checksumChan:     make(chan *multiCoreChecksummedWriterBuffer, cores+1),
-		writeChan:        make(chan *multiCoreChecksummedWriterBuffer, cores+1),
+		buffers:          buffers,
+		freeChan:         make(chan *multiCoreChecksummedWriterBuffer, buffers+1),
+		checksumChan:     make(chan *multiCoreChecksummedWriterBuffer, buffers+1),
+		writeChan:        make(chan *multiCoreChecksummedWriterBuffer, buffers+1),
 		doneChan:         make(chan struct{}),
 	}
-	for i := 0; i < cores; i++ {
-		cwi.freeChan <- &multiCoreChecksummedWriterBuffer{0, make([]byte, 0, checksumInterval+4)}
+	for i := 0; i < buffers; i++ {
+		cwi.freeChan <- &multiCoreChecksummedWriterBuffer{buf: make([]byte, 0, checksumInterval+checksumSize)}
 	}
 	cwi.buffer = <-cwi.freeChan
 	go cwi.writer()
@@ -285,35 +1661,143 @@ func NewMultiCoreChecksummedWriter(delegate io.Writer, checksumInterval int, new
 }
 
 func (cwi *multiCoreChecksummedWriter) Write(v []byte) (int, error) {
+	defer allocAuditEnd(&allocAudit.write, &cwi.stats.Allocs, allocAuditStart())
 	var n int
 	for len(cwi.buffer.buf)+len(v) >= cwi.checksumInterval {
 		n2 := cwi.checksumInterval - len(cwi.buffer.buf)
 		cwi.buffer.buf = append(cwi.buffer.buf, v[:n2]...)
-		s := cwi.buffer.seq + 1
-		cwi.checksumChan <- cwi.buffer
+		cwi.dispatch(false)
 		n += n2
 		v = v[n2:]
-		cwi.buffer = <-cwi.freeChan
-		cwi.buffer.seq = s
 	}
 	if len(v) > 0 {
 		cwi.buffer.buf = append(cwi.buffer.buf, v...)
 		n += len(v)
 	}
+	atomic.AddUint64(&cwi.stats.BytesWritten, uint64(n))
 	cwi.lock.Lock()
 	err := cwi.err
 	cwi.lock.Unlock()
 	return n, err
 }
 
+func (cwi *multiCoreChecksummedWriter) WriteContext(ctx context.Context, v []byte) (int, error) {
+	return writeContext(ctx, cwi, v, cwi.checksumInterval)
+}
+
+func (cwi *multiCoreChecksummedWriter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		b := cwi.buffer.buf
+		n, err := io.ReadFull(r, b[len(b):cwi.checksumInterval])
+		cwi.buffer.buf = b[:len(b)+n]
+		total += int64(n)
+		atomic.AddUint64(&cwi.stats.BytesWritten, uint64(n))
+		if len(cwi.buffer.buf) == cwi.checksumInterval {
+			cwi.dispatch(false)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+	cwi.lock.Lock()
+	err := cwi.err
+	cwi.lock.Unlock()
+	return total, err
+}
+
+func (cwi *multiCoreChecksummedWriter) Flush() error {
+	if len(cwi.buffer.buf) > 0 {
+		cwi.dispatch(true)
+	}
+	if err := cwi.drain(); err != nil {
+		return err
+	}
+	cwi.seal(cwi.buffer.offset)
+	return flushDelegate(cwi.delegate)
+}
+
+// dispatch sends the current buffer, a whole interval or, if flush, one
+// ended early and padded out, to be checksummed and takes a free one to
+// continue with.
+func (cwi *multiCoreChecksummedWriter) dispatch(flush bool) {
+	if flush {
+		padding := cwi.checksumInterval - len(cwi.buffer.buf)
+		cwi.buffer.buf = appendFlushPadding(cwi.buffer.buf, padding)
+		atomic.AddUint64(&cwi.padded, uint64(padding))
+	}
+	seq := cwi.buffer.seq + 1
+	offset := cwi.buffer.offset + int64(len(cwi.buffer.buf)+cwi.checksumSize)
+	cwi.buffer.flush = flush
+	cwi.checksumChan <- cwi.buffer
+	cwi.buffer = <-cwi.freeChan
+	cwi.buffer.seq = seq
+	cwi.buffer.offset = offset
+}
+
+// drain waits for every outstanding buffer to be written and come back
+// free, returning any error from writing them.
+func (cwi *multiCoreChecksummedWriter) drain() error {
+	held := make([]*multiCoreChecksummedWriterBuffer, 0, cwi.buffers)
+	for len(held) < cwi.buffers-1 {
+		held = append(held, <-cwi.freeChan)
+	}
+	for _, b := range held {
+		cwi.freeChan <- b
+	}
+	cwi.lock.Lock()
+	defer cwi.lock.Unlock()
+	return cwi.err
+}
+
 func (cwi *multiCoreChecksummedWriter) Close() error {
+	return cwi.CloseWithError(nil)
+}
+
+func (cwi *multiCoreChecksummedWriter) CloseWithError(err error) error {
+	return cwi.close(err, true)
+}
+
+func (cwi *multiCoreChecksummedWriter) Stats() ChecksummedWriterStats {
+	return ChecksummedWriterStats{
+		BytesWritten:     atomic.LoadUint64(&cwi.stats.BytesWritten),
+		ChecksumsEmitted: atomic.LoadUint64(&cwi.stats.ChecksumsEmitted),
+		Allocs:           atomic.LoadUint64(&cwi.stats.Allocs),
+	}
+}
+
+func (cwi *multiCoreChecksummedWriter) Describe() ChecksummedOverhead {
+	return describeWriter(cwi.Stats(), cwi.checksumSize, atomic.LoadUint64(&cwi.padded))
+}
+
+func (cwi *multiCoreChecksummedWriter) CloseWithoutDelegate() error {
+	return cwi.close(nil, false)
+}
+
+func (cwi *multiCoreChecksummedWriter) Unwrap() interface{} {
+	return unwrapDelegate(cwi.delegate)
+}
+
+func (cwi *multiCoreChecksummedWriter) Sync() error {
+	if err := cwi.drain(); err != nil {
+		return err
+	}
+	return syncDelegate(cwi.delegate)
+}
+
+func (cwi *multiCoreChecksummedWriter) close(err error, delegate bool) error {
 	cwi.lock.Lock()
 	if cwi.closed {
-		err := cwi.err
+		err = cwi.err
 		cwi.lock.Unlock()
 		return err
 	}
 	cwi.closed = true
+	cwi.closeErr = err
+	cwi.noCloseDelegate = !delegate
 	cwi.lock.Unlock()
 	if len(cwi.buffer.buf) > 0 {
 		cwi.checksumChan <- cwi.buffer
@@ -325,7 +1809,7 @@ func (cwi *multiCoreChecksummedWriter) Close() error {
 	cwi.writeChan <- nil
 	<-cwi.doneChan
 	cwi.lock.Lock()
-	err := cwi.err
+	err = cwi.err
 	cwi.lock.Unlock()
 	return err
 }
@@ -338,9 +1822,13 @@ func (cwi *multiCoreChecksummedWriter) checksummer() {
 		}
 		if len(b.buf) >= cwi.checksumInterval {
 			h := cwi.newHash()
+			setChecksumIndex(h, b.offset/int64(cwi.checksumInterval+cwi.checksumSize))
 			h.Write(b.buf)
-			b.buf = b.buf[:len(b.buf)+4]
-			binary.BigEndian.PutUint32(b.buf[len(b.buf)-4:], h.Sum32())
+			if b.flush {
+				h.Write(flushMark)
+			}
+			b.buf = h.Sum(b.buf)
+			atomic.AddUint64(&cwi.stats.ChecksumsEmitted, 1)
 		}
 		cwi.writeChan <- b
 	}
@@ -349,32 +1837,39 @@ func (cwi *multiCoreChecksummedWriter) checksummer() {
 
 func (cwi *multiCoreChecksummedWriter) writer() {
 	var seq int64
-	var lastWasNil bool
+	// Buffers can arrive out of order from the checksummers, so hold any
+	// early arrivals until their turn comes.
+	pending := make(map[int64]*multiCoreChecksummedWriterBuffer, cwi.buffers)
 	for {
 		b := <-cwi.writeChan
 		if b == nil {
-			if lastWasNil {
+			// Close only sends nil once all checksummers are done, so every
+			// buffer has already arrived.
+			break
+		}
+		pending[b.seq] = b
+		for {
+			b = pending[seq]
+			if b == nil {
 				break
 			}
-			lastWasNil = true
-			cwi.writeChan <- nil
-			continue
-		}
-		lastWasNil = false
-		if b.seq != seq {
-			cwi.writeChan <- b
-			continue
+			delete(pending, seq)
+			_, err := cwi.delegate.Write(b.buf)
+			cwi.lock.Lock()
+			cwi.err = err
+			cwi.lock.Unlock()
+			b.buf = b.buf[:0]
+			b.flush = false
+			cwi.freeChan <- b
+			seq++
 		}
-		_, err := cwi.delegate.Write(b.buf)
-		cwi.lock.Lock()
-		cwi.err = err
-		cwi.lock.Unlock()
-		b.buf = b.buf[:0]
-		cwi.freeChan <- b
-		seq++
 	}
-	if c, ok := cwi.delegate.(io.Closer); ok {
-		err := c.Close()
+	cwi.lock.Lock()
+	closeErr := cwi.closeErr
+	noCloseDelegate := cwi.noCloseDelegate
+	cwi.lock.Unlock()
+	if _, ok := cwi.delegate.(io.Closer); ok && !noCloseDelegate {
+		err := closeDelegate(cwi.delegate, closeErr)
 		cwi.lock.Lock()
 		cwi.err = err
 		cwi.lock.Unlock()
@@ -382,6 +1877,63 @@ func (cwi *multiCoreChecksummedWriter) writer() {
 	cwi.doneChan <- struct{}{}
 }
 
+// describeWriter returns the ChecksummedOverhead of a ChecksummedWriter with
+// the stats and checksum size given, and padded bytes of Flush padding.
+func describeWriter(s ChecksummedWriterStats, checksumSize int, padded uint64) ChecksummedOverhead {
+	checksumBytes := s.ChecksumsEmitted * uint64(checksumSize)
+	return ChecksummedOverhead{LogicalBytes: s.BytesWritten, PhysicalBytes: s.BytesWritten + checksumBytes + padded, ChecksumBytes: checksumBytes}
+}
+
+// flushDelegate calls the delegate's Flush method, if it has one.
+func flushDelegate(delegate interface{}) error {
+	if f, ok := delegate.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// closeWithErrorer is implemented by writers, such as *io.PipeWriter, that can
+// pass an error on to their readers when closed.
+type closeWithErrorer interface {
+	CloseWithError(err error) error
+}
+
+// closeDelegate closes the delegate, using CloseWithError if err is not nil
+// and the delegate supports it; delegates that can't be closed are left be.
+func closeDelegate(delegate interface{}, err error) error {
+	if err != nil {
+		if c, ok := delegate.(closeWithErrorer); ok {
+			return c.CloseWithError(err)
+		}
+	}
+	if c, ok := delegate.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// writeContext writes v to w in pieces of at most size bytes, checking ctx
+// before each.
+func writeContext(ctx context.Context, w io.Writer, v []byte, size int) (int, error) {
+	var n int
+	for len(v) > 0 {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		piece := v
+		if len(piece) > size {
+			piece = piece[:size]
+		}
+		m, err := w.Write(piece)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		v = v[m:]
+	}
+	return n, nil
+}
+
 type errDelegateStruct struct {
 }
 
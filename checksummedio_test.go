@@ -1,11 +1,21 @@
 package brimio
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
 	"hash/crc32"
+	"hash/crc64"
 	"io"
 	"io/ioutil"
+	"os"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -332,6 +342,1075 @@ func TestMultiCoreChecksummedWriter(t *testing.T) {
 	}
 }
 
+func TestChecksummedReadWriter64(t *testing.T) {
+	newHash := func() hash.Hash64 { return crc64.New(crc64.MakeTable(crc64.ECMA)) }
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter64(buf, 16, newHash)
+	n, err := cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	if n != 40 {
+		t.Fatal(n)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash1 := newHash()
+	hash1.Write([]byte("1234567890123456"))
+	hash2 := newHash()
+	hash2.Write([]byte("7890ghijklmnopqr"))
+	if !bytes.Equal(buf.Bytes(), []byte("1234567890123456"+string(hash1.Sum(nil))+"7890ghijklmnopqr"+string(hash2.Sum(nil))+"stuvwxyz")) {
+		t.Fatalf("%#v", string(buf.Bytes()))
+	}
+	buf2 := &bytes.Buffer{}
+	cw = NewMultiCoreChecksummedWriter64(buf2, 16, newHash, 2)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	err = cw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatalf("%#v", string(buf2.Bytes()))
+	}
+	cr := NewChecksummedReader64(bytes.NewReader(buf.Bytes()), 16, newHash)
+	o, err := cr.Seek(18, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 18 {
+		t.Fatal(o)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "90ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	o, err = cr.Seek(-30, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 10 {
+		t.Fatal(o)
+	}
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal(ok)
+	}
+}
+
+func TestChecksummedReadWriterHash(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriterHash(buf, 16, sha256.New)
+	n, err := cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	if n != 40 {
+		t.Fatal(n)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash1 := sha256.Sum256([]byte("1234567890123456"))
+	hash2 := sha256.Sum256([]byte("7890ghijklmnopqr"))
+	if !bytes.Equal(buf.Bytes(), []byte("1234567890123456"+string(hash1[:])+"7890ghijklmnopqr"+string(hash2[:])+"stuvwxyz")) {
+		t.Fatalf("%#v", string(buf.Bytes()))
+	}
+	buf2 := &bytes.Buffer{}
+	cw = NewMultiCoreChecksummedWriterHash(buf2, 16, sha256.New, 2)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	err = cw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatalf("%#v", string(buf2.Bytes()))
+	}
+	cr := NewChecksummedReaderHash(bytes.NewReader(buf.Bytes()), 16, sha256.New)
+	o, err := cr.Seek(18, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 18 {
+		t.Fatal(o)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "90ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	_, err = cr.Seek(5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal(ok)
+	}
+}
+
+func TestAppendingChecksummedWriter(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890"))
+	cw, err = NewAppendingChecksummedWriter(f, 16, crc32.NewIEEE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := cw.Write([]byte("ghijklmnopqrstuvwxyz"))
+	if n != 20 {
+		t.Fatal(n)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash1 := crc32.NewIEEE()
+	hash1.Write([]byte("1234567890123456"))
+	hash2 := crc32.NewIEEE()
+	hash2.Write([]byte("7890ghijklmnopqr"))
+	if !bytes.Equal(v, []byte("1234567890123456"+string(hash1.Sum(nil))+"7890ghijklmnopqr"+string(hash2.Sum(nil))+"stuvwxyz")) {
+		t.Fatalf("%#v", string(v))
+	}
+	f.Write([]byte("12"))
+	_, err = NewAppendingChecksummedWriter(f, 16, crc32.NewIEEE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("3456789"))
+	_, err = NewAppendingChecksummedWriter(f, 16, crc32.NewIEEE)
+	if err == nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksummedReaderVerifyAll(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 4, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := append([]byte{}, buf.Bytes()...)
+	b[9] = 'X'
+	b[33] = 'X'
+	cr := NewChecksummedReader(bytes.NewReader(b), 4, crc32.NewIEEE)
+	cr.Seek(13, 0)
+	var calls int64
+	corrupted, err := cr.VerifyAll(func(verified int64, total int64) {
+		calls++
+		if verified != calls {
+			t.Fatal(verified)
+		}
+		if total != 10 {
+			t.Fatal(total)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 10 {
+		t.Fatal(calls)
+	}
+	if len(corrupted) != 2 || corrupted[0] != 1 || corrupted[1] != 4 {
+		t.Fatal(corrupted)
+	}
+	o, err := cr.Seek(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 13 {
+		t.Fatal(o)
+	}
+	cr = NewChecksummedReader(bytes.NewReader(buf.Bytes()[:20]), 4, crc32.NewIEEE)
+	cr.Seek(0, 0)
+	corrupted, err = cr.VerifyAll(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupted != nil {
+		t.Fatal(corrupted)
+	}
+}
+
+func TestChecksummedReaderVerifyAllocs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE)
+	cr.Seek(20, 0)
+	allocs := testing.AllocsPerRun(100, func() {
+		if ok, err := cr.Verify(); !ok || err != nil {
+			t.Fatal(ok, err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatal(allocs)
+	}
+}
+
+func TestChecksummedContext(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 4, crc32.NewIEEE)
+	n, err := cw.WriteContext(context.Background(), []byte("12345678901234567890"))
+	if err != nil || n != 20 {
+		t.Fatal(n, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err = cw.WriteContext(ctx, []byte("abc")); err != context.Canceled || n != 0 {
+		t.Fatal(n, err)
+	}
+	cw.Close()
+	b := buf.Bytes()
+	b[1] = 'X'
+	cr := NewChecksummedReader(bytes.NewReader(b), 4, crc32.NewIEEE)
+	cr.Seek(6, 0)
+	ctx, cancel = context.WithCancel(context.Background())
+	corrupt, err := cr.VerifyAllContext(ctx, func(verified int64, total int64) {
+		if verified == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled || len(corrupt) != 1 || corrupt[0] != 0 {
+		t.Fatal(corrupt, err)
+	}
+	if o, err := cr.Seek(0, 1); err != nil || o != 6 {
+		t.Fatal(o, err)
+	}
+	if _, err = cr.VerifyRangeContext(ctx, 0, 4); err != context.Canceled {
+		t.Fatal(err)
+	}
+	v := make([]byte, 10)
+	if n, err = cr.ReadContext(ctx, v); err != context.Canceled || n != 0 {
+		t.Fatal(n, err)
+	}
+	if n, err = cr.ReadContext(context.Background(), v); err != nil || string(v[:n]) != "7890123456" {
+		t.Fatal(n, err, string(v[:n]))
+	}
+}
+
+func TestChecksummedReaderVerifyRange(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 4, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[9] = 'X'
+	b[33] = 'X'
+	cr := NewChecksummedReader(bytes.NewReader(b), 4, crc32.NewIEEE)
+	cr.Seek(13, 0)
+	corrupted, err := cr.VerifyRange(8, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupted != nil {
+		t.Fatal(corrupted)
+	}
+	corrupted, err = cr.VerifyRange(7, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupted) != 2 || corrupted[0] != 1 || corrupted[1] != 4 {
+		t.Fatal(corrupted)
+	}
+	corrupted, err = cr.VerifyRange(17, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupted) != 1 || corrupted[0] != 4 {
+		t.Fatal(corrupted)
+	}
+	o, err := cr.Seek(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 13 {
+		t.Fatal(o)
+	}
+}
+
+func TestChecksummedReaderAutoVerify(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	opts := &ChecksummedReaderOptions{AutoVerify: true}
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(buf.Bytes()), 16, newHash, opts)
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	o, err := cr.Seek(-22, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 18 {
+		t.Fatal(o)
+	}
+	v = make([]byte, 20)
+	n, err := io.ReadFull(cr, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v[:n]) != "90ghijklmnopqrstuvwx" {
+		t.Fatalf("%#v", string(v[:n]))
+	}
+	b := append([]byte{}, buf.Bytes()...)
+	b[22] = 'X'
+	cr = NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+	v, err = ioutil.ReadAll(cr)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if string(v) != "1234567890123456" {
+		t.Fatalf("%#v", string(v))
+	}
+	o, err = cr.Seek(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 16 {
+		t.Fatal(o)
+	}
+	cr.Seek(32, 0)
+	v, err = ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "stuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+}
+
+func TestChecksummedReaderOnCorruption(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 4, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[9] = 'X'
+	b[33] = 'X'
+	var events [][2]int64
+	opts := &ChecksummedReaderOptions{OnCorruption: func(blockIndex int64, offset int64) {
+		events = append(events, [2]int64{blockIndex, offset})
+	}}
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 4, func() hash.Hash { return crc32.NewIEEE() }, opts)
+	cr.Seek(5, 0)
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal(ok)
+	}
+	if _, err = cr.VerifyAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[0] != [2]int64{1, 8} || events[1] != [2]int64{1, 8} || events[2] != [2]int64{4, 32} {
+		t.Fatal(events)
+	}
+	events = nil
+	opts.AutoVerify = true
+	cr = NewChecksummedReaderWithOptions(bytes.NewReader(b), 4, func() hash.Hash { return crc32.NewIEEE() }, opts)
+	cr.Seek(17, 0)
+	if _, err = cr.Read(make([]byte, 1)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0] != [2]int64{4, 32} {
+		t.Fatal(events)
+	}
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) || mismatch.BlockIndex != 4 || mismatch.Offset != 16 || mismatch.Length != 4 || len(mismatch.Expected) != 4 || bytes.Equal(mismatch.Expected, mismatch.Computed) {
+		t.Fatalf("%#v", err)
+	}
+	cr.Seek(16, 0)
+	if _, err = io.Copy(ioutil.Discard, cr); !errors.As(err, &mismatch) || mismatch.BlockIndex != 4 {
+		t.Fatalf("%#v", err)
+	}
+}
+
+func TestStreamingChecksummedReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	cr := NewStreamingChecksummedReader(bytes.NewBuffer(buf.Bytes()), 16, crc32.NewIEEE)
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	b := append([]byte{}, buf.Bytes()...)
+	b[22] = 'X'
+	cr = NewStreamingChecksummedReader(bytes.NewBuffer(b), 16, crc32.NewIEEE)
+	v, err = ioutil.ReadAll(cr)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if string(v) != "1234567890123456" {
+		t.Fatalf("%#v", string(v))
+	}
+	cr = NewStreamingChecksummedReader(bytes.NewBuffer(buf.Bytes()[:38]), 16, crc32.NewIEEE)
+	v, err = ioutil.ReadAll(cr)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if err = cr.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksummedReaderWriteTo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	for _, autoVerify := range []bool{false, true} {
+		cr := NewChecksummedReaderWithOptions(bytes.NewReader(buf.Bytes()), 16, newHash, &ChecksummedReaderOptions{AutoVerify: autoVerify})
+		out := &bytes.Buffer{}
+		n, err := io.Copy(out, cr)
+		if err != nil {
+			t.Fatal(autoVerify, err)
+		}
+		if n != 40 || out.String() != "12345678901234567890ghijklmnopqrstuvwxyz" {
+			t.Fatalf("%v %d %#v", autoVerify, n, out.String())
+		}
+		cr.Seek(18, 0)
+		out.Reset()
+		n, err = io.Copy(out, cr)
+		if err != nil {
+			t.Fatal(autoVerify, err)
+		}
+		if n != 22 || out.String() != "90ghijklmnopqrstuvwxyz" {
+			t.Fatalf("%v %d %#v", autoVerify, n, out.String())
+		}
+		o, err := cr.Seek(0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o != 40 {
+			t.Fatal(autoVerify, o)
+		}
+	}
+	b := append([]byte{}, buf.Bytes()...)
+	b[30] = 'X'
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, &ChecksummedReaderOptions{AutoVerify: true})
+	cr.Seek(3, 0)
+	out := &bytes.Buffer{}
+	n, err := io.Copy(out, cr)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if n != 13 || out.String() != "4567890123456" {
+		t.Fatalf("%d %#v", n, out.String())
+	}
+}
+
+func TestChecksummedReaderSeek(t *testing.T) {
+	content := "12345678901234567890ghijklmnopqrstuvwxyz"
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte(content))
+	cw.Close()
+	for _, b := range [][]byte{buf.Bytes(), buf.Bytes()[:36], buf.Bytes()[:34]} {
+		size := ContentSize(int64(len(b)), 16, 4)
+		for _, autoVerify := range []bool{false, true} {
+			cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{AutoVerify: autoVerify})
+			for _, tc := range []struct {
+				offset int64
+				whence int
+				want   int64
+			}{
+				{15, io.SeekStart, 15},
+				{16, io.SeekStart, 16},
+				{1, io.SeekCurrent, 17},
+				{-2, io.SeekCurrent, 15},
+				{0, io.SeekCurrent, 15},
+				{0, io.SeekEnd, size},
+				{-1, io.SeekEnd, size - 1},
+				{-size, io.SeekEnd, 0},
+				{-16, io.SeekEnd, size - 16},
+			} {
+				o, err := cr.Seek(tc.offset, tc.whence)
+				if err != nil || o != tc.want {
+					t.Fatal(len(b), tc, o, err)
+				}
+				v := make([]byte, 1)
+				if o < size {
+					if _, err = io.ReadFull(cr, v); err != nil || v[0] != content[o] {
+						t.Fatal(len(b), tc, string(v), err)
+					}
+					if o, err = cr.Seek(-1, io.SeekCurrent); err != nil || o != tc.want {
+						t.Fatal(len(b), tc, o, err)
+					}
+				} else if _, err = cr.Read(v); err != io.EOF {
+					t.Fatal(len(b), tc, err)
+				}
+			}
+			cr.Seek(5, io.SeekStart)
+			if o, err := cr.Seek(-size-1, io.SeekEnd); err == nil || o != 5 {
+				t.Fatal(len(b), o, err)
+			}
+			if o, err := cr.Seek(-6, io.SeekCurrent); err == nil || o != 5 {
+				t.Fatal(len(b), o, err)
+			}
+			if o, err := cr.Seek(0, 3); err == nil || o != 5 {
+				t.Fatal(len(b), o, err)
+			}
+			if o, err := cr.Seek(0, io.SeekCurrent); err != nil || o != 5 {
+				t.Fatal(len(b), o, err)
+			}
+		}
+	}
+}
+
+func TestChecksummedReaderReadByte(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	var v []byte
+	scratch := make([]byte, binary.MaxVarintLen64)
+	for i := uint64(0); i < 20; i++ {
+		v = append(v, scratch[:binary.PutUvarint(scratch, i*1000)]...)
+	}
+	cw.Write(v)
+	cw.Close()
+	for _, autoVerify := range []bool{false, true} {
+		cr := NewChecksummedReaderWithOptions(bytes.NewReader(buf.Bytes()), 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{AutoVerify: autoVerify})
+		for i := uint64(0); i < 20; i++ {
+			x, err := binary.ReadUvarint(cr)
+			if err != nil || x != i*1000 {
+				t.Fatal(autoVerify, i, x, err)
+			}
+		}
+		if o, err := cr.Seek(0, io.SeekCurrent); err != nil || o != int64(len(v)) {
+			t.Fatal(autoVerify, o, err)
+		}
+		if _, err := cr.ReadByte(); err != io.EOF {
+			t.Fatal(autoVerify, err)
+		}
+		cr.Seek(15, io.SeekStart)
+		if c, err := cr.ReadByte(); err != nil || c != v[15] {
+			t.Fatal(autoVerify, c, err)
+		}
+		if ok, err := cr.Verify(); err != nil || !ok {
+			t.Fatal(autoVerify, ok, err)
+		}
+	}
+}
+
+func TestChecksummedReaderSkipCorrupted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[22] = 'X'
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	for _, zero := range []bool{false, true} {
+		expected := "1234567890123456stuvwxyz"
+		if zero {
+			expected = "1234567890123456" + string(make([]byte, 16)) + "stuvwxyz"
+		}
+		opts := &ChecksummedReaderOptions{SkipCorrupted: true, ZeroCorrupted: zero}
+		cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+		v, err := ioutil.ReadAll(cr)
+		if err != nil {
+			t.Fatal(zero, err)
+		}
+		if string(v) != expected {
+			t.Fatalf("%v %#v", zero, string(v))
+		}
+		if s := cr.Skipped(); len(s) != 1 || s[0] != (Range{Offset: 16, Length: 16}) {
+			t.Fatal(zero, s)
+		}
+		cr = NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+		out := &bytes.Buffer{}
+		if _, err = io.Copy(out, cr); err != nil {
+			t.Fatal(zero, err)
+		}
+		if out.String() != expected {
+			t.Fatalf("%v %#v", zero, out.String())
+		}
+		if s := cr.Skipped(); len(s) != 1 || s[0] != (Range{Offset: 16, Length: 16}) {
+			t.Fatal(zero, s)
+		}
+	}
+}
+
+func TestChecksummedWriterReadFrom(t *testing.T) {
+	v := []byte("12345678901234567890ghijklmnopqrstuvwxyz12345678")
+	want := &bytes.Buffer{}
+	cw := NewChecksummedWriter(want, 16, crc32.NewIEEE)
+	cw.Write(v[:3])
+	cw.Write(v[3:])
+	cw.Close()
+	for _, multiCore := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriter(buf, 16, crc32.NewIEEE, 2)
+		} else {
+			cw = NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+		}
+		cw.Write(v[:3])
+		// Wrapped so io.Copy can't use bytes.Reader's WriteTo.
+		n, err := io.Copy(cw, struct{ io.Reader }{bytes.NewReader(v[3:])})
+		if err != nil {
+			t.Fatal(multiCore, err)
+		}
+		if n != int64(len(v)-3) {
+			t.Fatal(multiCore, n)
+		}
+		if err = cw.Close(); err != nil {
+			t.Fatal(multiCore, err)
+		}
+		if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+			t.Fatalf("%v %#v", multiCore, buf.String())
+		}
+	}
+}
+
+func TestChecksummedWriterFlush(t *testing.T) {
+	for _, multiCore := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		bw := bufio.NewWriter(buf)
+		var cw ChecksummedWriter
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriter(bw, 16, crc32.NewIEEE, 2)
+		} else {
+			cw = NewChecksummedWriter(bw, 16, crc32.NewIEEE)
+		}
+		cw.Write([]byte("12345"))
+		if err := cw.Flush(); err != nil {
+			t.Fatal(multiCore, err)
+		}
+		// The interval is padded out and its checksum marked as ended early.
+		padded := "12345\x80" + strings.Repeat("\x00", 10)
+		hash := crc32.NewIEEE()
+		hash.Write([]byte(padded))
+		hash.Write([]byte{0x80})
+		if buf.String() != padded+string(hash.Sum(nil)) {
+			t.Fatalf("%v %#v", multiCore, buf.String())
+		}
+		// An empty interval gets no checksum.
+		if err := cw.Flush(); err != nil {
+			t.Fatal(multiCore, err)
+		}
+		if buf.Len() != 20 {
+			t.Fatal(multiCore, buf.Len())
+		}
+		cw.Write([]byte("67890123456789012345"))
+		cw.Flush()
+		cw.Write([]byte("abcdefghijklmnopqrstuvwxyz"))
+		cw.Flush()
+		cw.Write([]byte("ABC"))
+		if err := cw.Close(); err != nil {
+			t.Fatal(multiCore, err)
+		}
+		bw.Flush()
+		v, err := ioutil.ReadAll(NewStreamingChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE))
+		if err != nil {
+			t.Fatal(multiCore, err)
+		}
+		if string(v) != "1234567890123456789012345abcdefghijklmnopqrstuvwxyzABC" {
+			t.Fatalf("%v %#v", multiCore, string(v))
+		}
+		b := append([]byte{}, buf.Bytes()...)
+		b[2] = 'X'
+		_, err = ioutil.ReadAll(NewStreamingChecksummedReader(bytes.NewReader(b), 16, crc32.NewIEEE))
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatal(multiCore, err)
+		}
+	}
+}
+
+func TestChecksummedContentHoldingOwnChecksum(t *testing.T) {
+	// Content that happens to hold the checksum of what precedes it must
+	// come back as written, not be taken for an interval ended early.
+	sum := crc32.NewIEEE()
+	sum.Write([]byte("record"))
+	content := []byte("record" + string(sum.Sum(nil)) + "tail")
+	for _, whole := range []bool{false, true} {
+		v := content
+		if whole {
+			v = append(append([]byte{}, content...), bytes.Repeat([]byte{'x'}, 64-len(content))...)
+		}
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriter(buf, 64, crc32.NewIEEE)
+		cw.Write(v)
+		cw.Close()
+		r, err := ioutil.ReadAll(NewStreamingChecksummedReader(bytes.NewReader(buf.Bytes()), 64, crc32.NewIEEE))
+		if err != nil || !bytes.Equal(r, v) {
+			t.Fatalf("%v %q %v", whole, r, err)
+		}
+		cr, err := NewChecksummedReaderWith(bytes.NewReader(buf.Bytes()), WithInterval(64), WithFlushed())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, err = ioutil.ReadAll(cr); err != nil || !bytes.Equal(r, v) {
+			t.Fatalf("%v %q %v", whole, r, err)
+		}
+		if !whole {
+			continue
+		}
+		// Nor may a corrupted interval verify by the checksum within it.
+		b := buf.Bytes()
+		b[len(content)] ^= 1
+		if _, err = ioutil.ReadAll(NewStreamingChecksummedReader(bytes.NewReader(b), 64, crc32.NewIEEE)); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatal(err)
+		}
+		cr, _ = NewChecksummedReaderWith(bytes.NewReader(b), WithInterval(64), WithFlushed())
+		if _, err = ioutil.ReadAll(cr); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChecksummedReaderFlushed(t *testing.T) {
+	for _, multiCore := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		var cw ChecksummedWriter
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriter(buf, 16, crc32.NewIEEE, 2)
+		} else {
+			cw = NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+		}
+		var want []byte
+		for _, v := range []string{"12345", "67890123456789012345", "", "abcdefghijklmnop", "qrstuvwxyz"} {
+			cw.Write([]byte(v))
+			cw.Flush()
+			want = append(want, v...)
+		}
+		cw.Write([]byte("ABC"))
+		if err := cw.Close(); err != nil {
+			t.Fatal(multiCore, err)
+		}
+		if d := cw.Describe(); d.PhysicalBytes != uint64(buf.Len()) {
+			t.Fatal(multiCore, d, buf.Len())
+		}
+		want = append(want, "ABC"...)
+		cr, err := NewChecksummedReaderWith(bytes.NewReader(buf.Bytes()), WithInterval(16), WithFlushed())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := cr.Size(); err != nil || n != int64(len(want)) {
+			t.Fatal(multiCore, n, err)
+		}
+		v, err := ioutil.ReadAll(cr)
+		if err != nil || string(v) != string(want) {
+			t.Fatalf("%v %q %v", multiCore, v, err)
+		}
+		for _, o := range []int64{0, 3, 5, 16, 25, 40, 41, int64(len(want)) - 1} {
+			if n, err := cr.Seek(o, 0); err != nil || n != o {
+				t.Fatal(multiCore, o, n, err)
+			}
+			v, err = ioutil.ReadAll(cr)
+			if err != nil || string(v) != string(want[o:]) {
+				t.Fatalf("%v %d %q %v", multiCore, o, v, err)
+			}
+		}
+		if n, err := cr.Seek(-4, 2); err != nil || n != int64(len(want))-4 {
+			t.Fatal(multiCore, n, err)
+		}
+		w := &bytes.Buffer{}
+		if _, err = io.Copy(w, cr); err != nil || w.String() != string(want[len(want)-4:]) {
+			t.Fatalf("%v %q %v", multiCore, w.String(), err)
+		}
+		if corrupt, err := cr.VerifyAll(nil); err != nil || corrupt != nil {
+			t.Fatal(multiCore, corrupt, err)
+		}
+		// Without Flushed the padding is not taken for content.
+		cr = NewChecksummedReaderWithOptions(bytes.NewReader(buf.Bytes()), 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{AutoVerify: true})
+		if _, err = ioutil.ReadAll(cr); err == nil {
+			t.Fatal(multiCore)
+		}
+	}
+}
+
+func TestChecksummedGeneration(t *testing.T) {
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	for _, multiCore := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriterHash(buf, 16, newHash)
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriterHash(buf, 16, newHash, 2)
+		}
+		if g := cw.Generation(); g != (ChecksummedGeneration{}) {
+			t.Fatal(multiCore, g)
+		}
+		cw.Write([]byte("12345678901234567890123456789012"))
+		if err := cw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		g := cw.Generation()
+		if g != (ChecksummedGeneration{Generation: 1, Sealed: 40}) {
+			t.Fatal(multiCore, g)
+		}
+		// Content being written after the Flush has no checksum yet.
+		buf.Write([]byte("abcdefghijklmnopqrst"))
+		cr := NewChecksummedReaderHash(bytes.NewReader(buf.Bytes()), 16, newHash)
+		if corrupt, err := cr.VerifyAll(nil); err != nil || len(corrupt) != 1 || corrupt[0] != 2 {
+			t.Fatal(multiCore, corrupt, err)
+		}
+		if corrupt, err := cr.VerifyAt(g); err != nil || corrupt != nil {
+			t.Fatal(multiCore, corrupt, err)
+		}
+		b := buf.Bytes()
+		b[3] = 'X'
+		if corrupt, err := cr.VerifyAt(g); err != nil || len(corrupt) != 1 || corrupt[0] != 0 {
+			t.Fatal(multiCore, corrupt, err)
+		}
+		cw.CloseWithoutDelegate()
+	}
+}
+
+func TestChecksummedGenerationFlushedPartial(t *testing.T) {
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	for _, multiCore := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriterHash(buf, 16, newHash)
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriterHash(buf, 16, newHash, 2)
+		}
+		// Each Flush ends an interval early, shifting those after it.
+		cw.Write([]byte("12345678901234567890"))
+		cw.Flush()
+		cw.Write([]byte("abcdefghijklmnopqrst"))
+		cw.Flush()
+		g := cw.Generation()
+		if g.Sealed != int64(buf.Len()) || g.Sealed != 80 {
+			t.Fatal(multiCore, g, buf.Len())
+		}
+		cr := NewChecksummedReaderHash(bytes.NewReader(buf.Bytes()), 16, newHash)
+		cr.Seek(5, 0)
+		if corrupt, err := cr.VerifyAt(g); err != nil || corrupt != nil {
+			t.Fatal(multiCore, corrupt, err)
+		}
+		if o, err := cr.Seek(0, 1); err != nil || o != 5 {
+			t.Fatal(multiCore, o, err)
+		}
+		b := buf.Bytes()
+		// The padding of the early interval holding the "qrst" ending the
+		// second write.
+		b[len(b)-5] ^= 1
+		if corrupt, err := cr.VerifyAt(g); err != nil || len(corrupt) != 1 || corrupt[0] != 3 {
+			t.Fatal(multiCore, corrupt, err)
+		}
+		cw.CloseWithoutDelegate()
+	}
+}
+
+func TestChecksummedCloseWithoutDelegate(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	for _, multiCore := range []bool{false, true} {
+		var cw ChecksummedWriter
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriter(f, 16, crc32.NewIEEE, 2)
+		} else {
+			cw = NewChecksummedWriter(f, 16, crc32.NewIEEE)
+		}
+		cw.Write([]byte("1234567890123456"))
+		if err = cw.CloseWithoutDelegate(); err != nil {
+			t.Fatal(multiCore, err)
+		}
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	cr := NewChecksummedReader(f, 16, crc32.NewIEEE)
+	if ok, err := cr.Verify(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if err = cr.CloseWithoutDelegate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksummedStats(t *testing.T) {
+	for _, multiCore := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		var cw ChecksummedWriter
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriter(buf, 16, crc32.NewIEEE, 2)
+		} else {
+			cw = NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+		}
+		cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+		cw.Close()
+		s := cw.Stats()
+		// Allocs depends on the brimioallocaudit build tag; see TestAllocStats.
+		s.Allocs = 0
+		if s != (ChecksummedWriterStats{BytesWritten: 40, ChecksumsEmitted: 2}) {
+			t.Fatalf("%v %#v", multiCore, s)
+		}
+	}
+}
+
+func TestChecksummedReaderStats(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := append([]byte{}, buf.Bytes()...)
+	b[30] = 'X'
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{AutoVerify: true})
+	v := make([]byte, 10)
+	if n, err := cr.Read(v); n != 10 || err != nil {
+		t.Fatal(n, err)
+	}
+	if _, err := cr.Read(v); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cr.Read(v); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if _, err := cr.VerifyAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	s := cr.Stats()
+	s.Allocs = 0
+	if s != (ChecksummedReaderStats{BytesRead: 16, BlocksVerified: 4, VerifyFailures: 2, PhysicalBytesRead: 80, ChecksumBytesRead: 16}) {
+		t.Fatalf("%#v", s)
+	}
+	cr.Close()
+	if o := cr.Describe(); o != (ChecksummedOverhead{LogicalBytes: 16, PhysicalBytes: 80, ChecksumBytes: 16}) || o.Amplification() != 5 {
+		t.Fatalf("%#v", o)
+	}
+}
+
+func TestChecksummedOverhead(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	if o := cw.Describe(); o != (ChecksummedOverhead{LogicalBytes: 40, PhysicalBytes: 48, ChecksumBytes: 8}) || o.Amplification() != 1.2 {
+		t.Fatalf("%#v", o)
+	}
+	// Without AutoVerify, only what is read is counted.
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE)
+	if _, err := ioutil.ReadAll(cr); err != nil {
+		t.Fatal(err)
+	}
+	if o := cr.Describe(); o != (ChecksummedOverhead{LogicalBytes: 40, PhysicalBytes: 48, ChecksumBytes: 8}) {
+		t.Fatalf("%#v", o)
+	}
+	if a := (ChecksummedOverhead{}).Amplification(); a != 0 {
+		t.Fatal(a)
+	}
+}
+
+func TestContentSize(t *testing.T) {
+	for _, c := range [][2]int64{{0, 0}, {16, 20}, {15, 15}, {17, 21}, {32, 40}, {16, 18}, {16, 19}} {
+		if s := ContentSize(c[1], 16, 4); s != c[0] {
+			t.Fatal(c, s)
+		}
+		if c[1] != 18 && c[1] != 19 {
+			if p := PhysicalSize(c[0], 16, 4); p != c[1] {
+				t.Fatal(c, p)
+			}
+		}
+	}
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE)
+	cr.Seek(5, 0)
+	s, err := cr.Size()
+	if err != nil || s != 40 {
+		t.Fatal(s, err)
+	}
+	if o, _ := cr.Seek(0, 1); o != 5 {
+		t.Fatal(o)
+	}
+}
+
+func TestChecksummedWriterCloseWithError(t *testing.T) {
+	for _, multiCore := range []bool{false, true} {
+		pr, pw := io.Pipe()
+		var cw ChecksummedWriter
+		if multiCore {
+			cw = NewMultiCoreChecksummedWriter(pw, 16, crc32.NewIEEE, 2)
+		} else {
+			cw = NewChecksummedWriter(pw, 16, crc32.NewIEEE)
+		}
+		errPoison := fmt.Errorf("poison")
+		go func() {
+			cw.Write([]byte("12345678901234567890"))
+			cw.CloseWithError(errPoison)
+		}()
+		v, err := ioutil.ReadAll(pr)
+		if err != errPoison {
+			t.Fatal(multiCore, err)
+		}
+		hash := crc32.NewIEEE()
+		hash.Write([]byte("1234567890123456"))
+		if string(v) != "1234567890123456"+string(hash.Sum(nil))+"7890" {
+			t.Fatalf("%v %#v", multiCore, string(v))
+		}
+	}
+}
+
+func TestPooledChecksummedWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	buf2 := &bytes.Buffer{}
+	cw2 := NewPooledChecksummedWriter(buf2, 16, func() hash.Hash { return crc32.NewIEEE() }, 2, 5)
+	for i := 0; i < 100; i++ {
+		v := []byte(fmt.Sprintf("%d-12345678901234567890ghijklmnopqrstuvwxyz", i))
+		cw.Write(v)
+		n, err := cw2.Write(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(v) {
+			t.Fatal(n)
+		}
+	}
+	cw.Close()
+	if err := cw2.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatalf("%#v", string(buf2.Bytes()))
+	}
+}
+
 func Benchmark16x7ChecksummedWriter________________(b *testing.B) {
 	cw := NewChecksummedWriter(&NullIO{}, 16, crc32.NewIEEE)
 	v := []byte{1, 2, 3, 4, 5, 6, 7}
@@ -457,3 +1536,145 @@ func Benchmark65532x60001MultiCoreChecksummedWriter(b *testing.B) {
 		}
 	}
 }
+
+func TestChecksummedReaderReplaceCorrupted(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	good := buf.Bytes()
+	b := append([]byte{}, good...)
+	b[30] = 'X'
+	var corrupted []int64
+	opts := &ChecksummedReaderOptions{
+		OnCorruption:     func(blockIndex int64, offset int64) { corrupted = append(corrupted, blockIndex) },
+		ReplaceCorrupted: ReplaceFromReplica(bytes.NewReader(good)),
+	}
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	if len(corrupted) != 1 || corrupted[0] != 1 || cr.Stats().VerifyFailures != 1 {
+		t.Fatalf("%#v %#v", corrupted, cr.Stats())
+	}
+	out := &bytes.Buffer{}
+	cr = NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+	if _, err = io.Copy(out, cr); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", out.String())
+	}
+	// A replica with the same corruption is no help.
+	opts.ReplaceCorrupted = ReplaceFromReplica(bytes.NewReader(b))
+	cr = NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+	if _, err = ioutil.ReadAll(cr); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	// Falling back to the other options, such as zeroing.
+	opts.SkipCorrupted = true
+	opts.ZeroCorrupted = true
+	cr = NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, opts)
+	if v, err = ioutil.ReadAll(cr); err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "1234567890123456"+string(make([]byte, 16))+"stuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+}
+
+// movingSeeks counts the Seeks of its io.ReadSeeker that may move the
+// position, hiding any io.ReaderAt; movingSeeksAt adds one back.
+type movingSeeks struct {
+	io.ReadSeeker
+	seeks int
+}
+
+func (ms *movingSeeks) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		ms.seeks++
+	}
+	return ms.ReadSeeker.Seek(offset, whence)
+}
+
+type movingSeeksAt struct {
+	*movingSeeks
+	io.ReaderAt
+}
+
+func TestChecksummedReaderVerifyReaderAt(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[25] = 'X'
+	br := bytes.NewReader(b)
+	ms := &movingSeeks{ReadSeeker: br}
+	cr := NewChecksummedReader(&movingSeeksAt{movingSeeks: ms, ReaderAt: br}, 16, crc32.NewIEEE)
+	v := make([]byte, 3)
+	if _, err := io.ReadFull(cr, v); err != nil {
+		t.Fatal(err)
+	}
+	ms.seeks = 0
+	if ok, err := cr.Verify(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if _, err := cr.Seek(18, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	ms.seeks = 0
+	if ok, err := cr.Verify(); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	if ms.seeks != 0 {
+		t.Fatal(ms.seeks)
+	}
+	if _, err := io.ReadFull(cr, v); err != nil || string(v) != "90g" {
+		t.Fatal(string(v), err)
+	}
+	// Without an io.ReaderAt, Verify seeks and restores the position.
+	ms = &movingSeeks{ReadSeeker: bytes.NewReader(b)}
+	cr = NewChecksummedReader(ms, 16, crc32.NewIEEE)
+	if _, err := cr.Seek(18, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	ms.seeks = 0
+	if ok, err := cr.Verify(); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	if ms.seeks == 0 {
+		t.Fatal(ms.seeks)
+	}
+	if _, err := io.ReadFull(cr, v); err != nil || string(v) != "90g" {
+		t.Fatal(string(v), err)
+	}
+}
+
+func TestChecksummedReaderVerifyDetail(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[25] = 'X'
+	cr := NewChecksummedReader(bytes.NewReader(b), 16, crc32.NewIEEE)
+	cr.Seek(3, 0)
+	if ok, r, err := cr.VerifyDetail(); err != nil || !ok || r != (Range{Offset: 0, Length: 16}) {
+		t.Fatal(ok, r, err)
+	}
+	cr.Seek(20, 0)
+	if ok, r, err := cr.VerifyDetail(); err != nil || ok || r != (Range{Offset: 16, Length: 16}) {
+		t.Fatal(ok, r, err)
+	}
+	// The trailing partial interval has no checksum to verify.
+	cr.Seek(35, 0)
+	if _, r, err := cr.VerifyDetail(); err == nil || r != (Range{Offset: 32, Length: 16}) {
+		t.Fatal(r, err)
+	}
+}
@@ -0,0 +1,23 @@
+package brimio
+
+import (
+	"hash"
+	"io"
+)
+
+// ChecksummedPipe creates a synchronous in-memory pipe, as io.Pipe does,
+// whose writer embeds checksums at the interval given as ChecksummedWriter
+// does and whose reader verifies them as NewStreamingChecksummedReader does,
+// such as for validating a transport layer placed between the two or for
+// testing code that uses the format without touching disk.
+//
+// The reader returns content an interval at a time, so it waits for an
+// interval to be completed, ended early by Flush, or ended by closing the
+// writer before returning any of it. Closing the writer with CloseWithError
+// has the reader return that error, and closing the reader has further
+// writes return io.ErrClosedPipe.
+func ChecksummedPipe(interval int, newHash func() hash.Hash) (io.ReadCloser, ChecksummedWriter) {
+	mustValidateChecksummed(interval, newHash)
+	pr, pw := io.Pipe()
+	return newStreamingChecksummedReader(pr, interval, newHash), newChecksummedWriterImpl(pw, interval, newHash)
+}
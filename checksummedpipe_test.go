@@ -0,0 +1,43 @@
+package brimio
+
+import (
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestChecksummedPipe(t *testing.T) {
+	r, w := ChecksummedPipe(16, func() hash.Hash { return crc32.NewIEEE() })
+	go func() {
+		w.Write([]byte("12345678901234567890"))
+		w.Flush()
+		w.Write([]byte("ghijklmnopqrstuvwxyz"))
+		w.Close()
+	}()
+	v, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	r, w = ChecksummedPipe(16, func() hash.Hash { return crc32.NewIEEE() })
+	go func() {
+		w.Write([]byte("1234567890123456"))
+		w.CloseWithError(fmt.Errorf("upstream failed"))
+	}()
+	if _, err = io.ReadFull(r, make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Read(make([]byte, 1)); err == nil || err.Error() != "upstream failed" {
+		t.Fatal(err)
+	}
+	r, w = ChecksummedPipe(16, func() hash.Hash { return crc32.NewIEEE() })
+	r.Close()
+	if _, err = w.Write([]byte("x")); err != io.ErrClosedPipe {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,291 @@
+package brimio
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sync"
+)
+
+// ReadWriterAt is the interface that groups the io.ReaderAt and io.WriterAt
+// interfaces.
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// ChecksummedWriterAt overwrites content in place within content written by
+// ChecksummedWriter, recomputing the checksum of each affected interval.
+//
+// Implements the io.WriterAt interface, with offsets being those within the
+// checksummed content rather than the underlying content. Its methods may be
+// called concurrently; as each rewrites whole intervals, they are run one at
+// a time.
+type ChecksummedWriterAt interface {
+	// WriteAt implements the io.WriterAt interface.
+	//
+	// Each interval touched is read from the underlying content, updated,
+	// and written back along with its new checksum. If the existing checksum
+	// of an interval only partially overwritten does not match, nothing is
+	// written for that interval and an error is returned; otherwise the
+	// corruption would be hidden behind a fresh checksum.
+	//
+	// Writes may extend the content but may not start beyond its end.
+	WriteAt(v []byte, offset int64) (n int, err error)
+	// RepairFrom verifies each complete interval of the content and, for
+	// each found not checksum valid, reads the same interval from replica,
+	// which must have the same layout, and writes it back in place with its
+	// checksum if the replica's copy is checksum valid.
+	//
+	// It returns the indexes of the intervals repaired and of those the
+	// replica had no checksum valid copy of. Errors reading the replica just
+	// leave that interval unrepaired; an error is only returned if the
+	// underlying content itself fails.
+	RepairFrom(replica io.ReaderAt) (repaired []int64, unrepairable []int64, err error)
+	// Truncate shortens the content to logicalSize bytes, such as to roll
+	// back a log after a failed append, truncating the underlying content,
+	// which must have a Truncate method as *os.File does, to the matching
+	// length.
+	//
+	// As with content written by ChecksummedWriter, a new trailing partial
+	// interval has no checksum; so its checksum isn't dropped while hiding
+	// corruption, the interval being cut is verified first and nothing is
+	// truncated if it does not match.
+	Truncate(logicalSize int64) error
+	// Unwrap implements the Unwrapper interface, returning the underlying
+	// ReadWriterAt.
+	Unwrap() interface{}
+}
+
+// NewChecksummedWriterAt returns a ChecksummedWriterAt that delegates
+// requests to an underlying ReadWriterAt containing checksums of the content
+// at given intervals using the hashing function given.
+func NewChecksummedWriterAt(delegate ReadWriterAt, interval int, newHash func() hash.Hash32) ChecksummedWriterAt {
+	return newChecksummedWriterAtImpl(delegate, interval, hash32(newHash))
+}
+
+// NewChecksummedWriterAtHash is the same as NewChecksummedWriterAt but for
+// checksums of newHash().Size() bytes using the hashing function given.
+func NewChecksummedWriterAtHash(delegate ReadWriterAt, interval int, newHash func() hash.Hash) ChecksummedWriterAt {
+	return newChecksummedWriterAtImpl(delegate, interval, newHash)
+}
+
+// TruncateChecksummed truncates the checksummed content of f to logicalLen
+// bytes of content at the matching physical length and syncs it, such as to
+// roll back a write-ahead log after a failed transaction; see
+// ChecksummedWriterAt.Truncate. A logicalLen past the end of the content is
+// an error rather than growing f.
+func TruncateChecksummed(f *os.File, logicalLen int64, cfg ChecksummedConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if err := newChecksummedWriterAtImpl(f, cfg.Interval, cfg.NewHash).Truncate(logicalLen); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+type checksummedWriterAtImpl struct {
+	delegate         ReadWriterAt
+	checksumInterval int
+	checksumSize     int
+	newHash          func() hash.Hash
+	// lock guards block and checksum, and keeps concurrent calls from
+	// losing each other's updates to an interval they both touch.
+	lock     sync.Mutex
+	block    []byte
+	checksum []byte
+}
+
+func newChecksummedWriterAtImpl(delegate ReadWriterAt, interval int, newHash func() hash.Hash) *checksummedWriterAtImpl {
+	mustValidateChecksummed(interval, newHash)
+	checksumSize := newHash().Size()
+	return &checksummedWriterAtImpl{
+		delegate:         delegate,
+		checksumInterval: interval,
+		checksumSize:     checksumSize,
+		newHash:          newHash,
+		block:            make([]byte, interval+checksumSize),
+		checksum:         make([]byte, checksumSize),
+	}
+}
+
+func (cwa *checksummedWriterAtImpl) WriteAt(v []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	cwa.lock.Lock()
+	defer cwa.lock.Unlock()
+	size, err := cwa.contentSize()
+	if err != nil {
+		return 0, err
+	}
+	if offset > size {
+		return 0, fmt.Errorf("offset %d beyond end of content", offset)
+	}
+	var n int
+	for len(v) > 0 {
+		blockIndex := offset / int64(cwa.checksumInterval)
+		blockOffset := int(offset % int64(cwa.checksumInterval))
+		start := blockIndex * int64(cwa.checksumInterval+cwa.checksumSize)
+		r, err := cwa.delegate.ReadAt(cwa.block, start)
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+		length := r
+		if r == len(cwa.block) {
+			length = cwa.checksumInterval
+		} else if r > cwa.checksumInterval {
+			return n, fmt.Errorf("partial checksum for interval at %d", start)
+		}
+		c := cwa.checksumInterval - blockOffset
+		if c > len(v) {
+			c = len(v)
+		}
+		if r == len(cwa.block) && c < cwa.checksumInterval && !cwa.valid(blockIndex) {
+			return n, fmt.Errorf("checksum mismatch for interval at %d", start)
+		}
+		copy(cwa.block[blockOffset:], v[:c])
+		if blockOffset+c > length {
+			length = blockOffset + c
+		}
+		block := cwa.block[:length]
+		if length == cwa.checksumInterval {
+			hash := cwa.newHash()
+			setChecksumIndex(hash, blockIndex)
+			hash.Write(block)
+			block = hash.Sum(block)
+		}
+		if _, err = cwa.delegate.WriteAt(block, start); err != nil {
+			return n, err
+		}
+		n += c
+		v = v[c:]
+		offset += int64(c)
+	}
+	return n, nil
+}
+
+func (cwa *checksummedWriterAtImpl) RepairFrom(replica io.ReaderAt) ([]int64, []int64, error) {
+	cwa.lock.Lock()
+	defer cwa.lock.Unlock()
+	var repaired, unrepairable []int64
+	blockSize := int64(len(cwa.block))
+	for index := int64(0); ; index++ {
+		r, err := cwa.delegate.ReadAt(cwa.block, index*blockSize)
+		if r < len(cwa.block) {
+			if err == io.EOF {
+				err = nil
+			}
+			return repaired, unrepairable, err
+		}
+		if cwa.valid(index) {
+			continue
+		}
+		if r, _ = replica.ReadAt(cwa.block, index*blockSize); r < len(cwa.block) || !cwa.valid(index) {
+			unrepairable = append(unrepairable, index)
+			continue
+		}
+		if _, err = cwa.delegate.WriteAt(cwa.block, index*blockSize); err != nil {
+			return repaired, unrepairable, err
+		}
+		repaired = append(repaired, index)
+	}
+}
+
+func (cwa *checksummedWriterAtImpl) Truncate(logicalSize int64) error {
+	t, ok := cwa.delegate.(interface{ Truncate(int64) error })
+	if !ok {
+		return fmt.Errorf("underlying content cannot be truncated")
+	}
+	if logicalSize < 0 {
+		return fmt.Errorf("negative size %d", logicalSize)
+	}
+	cwa.lock.Lock()
+	defer cwa.lock.Unlock()
+	size, err := cwa.contentSize()
+	if err != nil {
+		return err
+	}
+	if logicalSize > size {
+		return fmt.Errorf("size %d beyond end of content", logicalSize)
+	}
+	blockIndex := logicalSize / int64(cwa.checksumInterval)
+	start := blockIndex * int64(len(cwa.block))
+	r, err := cwa.delegate.ReadAt(cwa.block, start)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if r < len(cwa.block) && r > cwa.checksumInterval {
+		return fmt.Errorf("partial checksum for interval at %d", start)
+	}
+	blockOffset := int(logicalSize % int64(cwa.checksumInterval))
+	if r == len(cwa.block) && blockOffset > 0 && !cwa.valid(blockIndex) {
+		return fmt.Errorf("checksum mismatch for interval at %d", start)
+	}
+	return t.Truncate(start + int64(blockOffset))
+}
+
+// contentSize returns the length of the content, from that of the
+// underlying content: found with Stat if it has that method, as *os.File
+// does, or else by probing for its last byte with ReadAt.
+func (cwa *checksummedWriterAtImpl) contentSize() (int64, error) {
+	var size int64
+	if s, ok := cwa.delegate.(interface{ Stat() (os.FileInfo, error) }); ok {
+		fi, err := s.Stat()
+		if err != nil {
+			return 0, err
+		}
+		size = fi.Size()
+	} else {
+		var b [1]byte
+		has := func(o int64) (bool, error) {
+			n, err := cwa.delegate.ReadAt(b[:], o)
+			if n == 1 || err == io.EOF {
+				return n == 1, nil
+			}
+			return false, err
+		}
+		// The size is at least lo and less than hi; double hi until it's
+		// past the end and then halve the difference.
+		lo, hi := int64(0), int64(1)
+		for {
+			ok, err := has(hi - 1)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				break
+			}
+			lo, hi = hi, hi*2
+		}
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			ok, err := has(mid - 1)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		size = lo
+	}
+	return ContentSize(size, cwa.checksumInterval, cwa.checksumSize), nil
+}
+
+func (cwa *checksummedWriterAtImpl) Unwrap() interface{} {
+	return unwrapDelegate(cwa.delegate)
+}
+
+// valid returns whether the complete interval in block, the one at index,
+// matches its checksum.
+func (cwa *checksummedWriterAtImpl) valid(index int64) bool {
+	hash := cwa.newHash()
+	setChecksumIndex(hash, index)
+	hash.Write(cwa.block[:cwa.checksumInterval])
+	return checksumMatches(hash, cwa.block[cwa.checksumInterval:], cwa.checksum[:0])
+}
@@ -0,0 +1,325 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestChecksummedWriterAt(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cwa := NewChecksummedWriterAt(f, 16, crc32.NewIEEE)
+	n, err := cwa.WriteAt([]byte("ABCDEFGHIJ"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 {
+		t.Fatal(n)
+	}
+	n, err = cwa.WriteAt([]byte("STUVWXYZ1234"), 36)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12 {
+		t.Fatal(n)
+	}
+	v, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash1 := crc32.NewIEEE()
+	hash1.Write([]byte("1234567890ABCDEF"))
+	hash2 := crc32.NewIEEE()
+	hash2.Write([]byte("GHIJghijklmnopqr"))
+	hash3 := crc32.NewIEEE()
+	hash3.Write([]byte("stuvSTUVWXYZ1234"))
+	if !bytes.Equal(v, []byte("1234567890ABCDEF"+string(hash1.Sum(nil))+"GHIJghijklmnopqr"+string(hash2.Sum(nil))+"stuvSTUVWXYZ1234"+string(hash3.Sum(nil)))) {
+		t.Fatalf("%#v", string(v))
+	}
+	_, err = cwa.WriteAt([]byte("x"), 49)
+	if err == nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte("!"), 0)
+	_, err = cwa.WriteAt([]byte("x"), 1)
+	if err == nil {
+		t.Fatal(err)
+	}
+	_, err = cwa.WriteAt([]byte("abcdefghijklmnop"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := NewChecksummedReader(f, 16, crc32.NewIEEE)
+	if _, err = cr.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal(ok)
+	}
+}
+
+// noStatFile hides the Stat method of an *os.File, so a ChecksummedWriterAt
+// must find the end of the content with ReadAt alone.
+type noStatFile struct {
+	f *os.File
+}
+
+func (nsf noStatFile) ReadAt(v []byte, offset int64) (int, error) {
+	return nsf.f.ReadAt(v, offset)
+}
+
+func (nsf noStatFile) WriteAt(v []byte, offset int64) (int, error) {
+	return nsf.f.WriteAt(v, offset)
+}
+
+func (nsf noStatFile) Truncate(size int64) error {
+	return nsf.f.Truncate(size)
+}
+
+func TestChecksummedWriterAtPastEnd(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 10, crc32.NewIEEE)
+	cw.Write([]byte("1234567890"))
+	for _, cwa := range []ChecksummedWriterAt{NewChecksummedWriterAt(f, 10, crc32.NewIEEE), NewChecksummedWriterAt(noStatFile{f}, 10, crc32.NewIEEE)} {
+		// An offset on an interval boundary past the end would leave a hole.
+		if n, err := cwa.WriteAt([]byte("abc"), 20); err == nil || n != 0 {
+			t.Fatal(n, err)
+		}
+		if n, err := cwa.WriteAt([]byte("abc"), 11); err == nil || n != 0 {
+			t.Fatal(n, err)
+		}
+		if fi, _ := f.Stat(); fi.Size() != 14 {
+			t.Fatal(fi.Size())
+		}
+	}
+	cwa := NewChecksummedWriterAt(noStatFile{f}, 10, crc32.NewIEEE)
+	if n, err := cwa.WriteAt([]byte("abc"), 10); err != nil || n != 3 {
+		t.Fatal(n, err)
+	}
+	if n, err := cwa.WriteAt([]byte("d"), 13); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+	f.Seek(0, 0)
+	cr, err := NewChecksummedReaderWith(f, WithInterval(10), WithAutoVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil || string(v) != "1234567890abcd" {
+		t.Fatalf("%q %v", v, err)
+	}
+}
+
+func TestChecksummedWriterAtConcurrent(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	content := bytes.Repeat([]byte("-"), 16*8)
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write(content)
+	cwa := NewChecksummedWriterAt(f, 16, crc32.NewIEEE)
+	// Each byte written concurrently, several within each interval.
+	var wg sync.WaitGroup
+	for i := range content {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := cwa.WriteAt([]byte{byte('a' + i%26)}, int64(i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	f.Seek(0, 0)
+	cr := NewChecksummedReaderWithOptions(f, 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{AutoVerify: true})
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != len(content) {
+		t.Fatal(len(v))
+	}
+	for i := range v {
+		if v[i] != byte('a'+i%26) {
+			t.Fatal(i, string(v))
+		}
+	}
+}
+
+func TestChecksummedWriterAtRepairFrom(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz1234567890"))
+	cw.Close()
+	good := buf.Bytes()
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	b := append([]byte{}, good...)
+	b[1] = 'X'
+	b[25] = 'X'
+	if _, err = f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	replica := append([]byte{}, good...)
+	replica[21] = 'Y'
+	cwa := NewChecksummedWriterAt(f, 16, crc32.NewIEEE)
+	repaired, unrepairable, err := cwa.RepairFrom(bytes.NewReader(replica))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repaired) != 1 || repaired[0] != 0 {
+		t.Fatal(repaired)
+	}
+	if len(unrepairable) != 1 || unrepairable[0] != 1 {
+		t.Fatal(unrepairable)
+	}
+	v, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v[:20], good[:20]) || !bytes.Equal(v[20:], b[20:]) {
+		t.Fatalf("%#v", string(v))
+	}
+}
+
+func TestChecksummedWriterAtTruncate(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cwa := NewChecksummedWriterAt(f, 16, crc32.NewIEEE)
+	if err = cwa.Truncate(41); err == nil {
+		t.Fatal(err)
+	}
+	if err = cwa.Truncate(20); err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash1 := crc32.NewIEEE()
+	hash1.Write([]byte("1234567890123456"))
+	if !bytes.Equal(v, []byte("1234567890123456"+string(hash1.Sum(nil))+"7890")) {
+		t.Fatalf("%#v", string(v))
+	}
+	// Appending again continues from the truncated content.
+	cw, err = NewAppendingChecksummedWriter(f, 16, crc32.NewIEEE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("abcdefghijklmnop"))
+	f.Seek(0, 0)
+	valid, err := NewChecksummedReader(f, 16, crc32.NewIEEE).Verify()
+	if err != nil || !valid {
+		t.Fatalf("%#v %#v", valid, err)
+	}
+	if err = cwa.Truncate(16); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := f.Stat(); fi.Size() != 20 {
+		t.Fatal(fi.Size())
+	}
+	// Cutting into a corrupt interval is refused.
+	f.WriteAt([]byte("!"), 0)
+	if err = cwa.Truncate(8); err == nil {
+		t.Fatal(err)
+	}
+	if fi, _ := f.Stat(); fi.Size() != 20 {
+		t.Fatal(fi.Size())
+	}
+}
+
+func TestChecksummedWriterAtTruncatePastEnd(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 10, crc32.NewIEEE)
+	cw.Write([]byte("1234567890"))
+	for _, cwa := range []ChecksummedWriterAt{NewChecksummedWriterAt(f, 10, crc32.NewIEEE), NewChecksummedWriterAt(noStatFile{f}, 10, crc32.NewIEEE)} {
+		// A size on an interval boundary past the end would grow the file.
+		for _, size := range []int64{40, 20, 11} {
+			if err = cwa.Truncate(size); err == nil {
+				t.Fatal(size)
+			}
+		}
+		if fi, _ := f.Stat(); fi.Size() != 14 {
+			t.Fatal(fi.Size())
+		}
+	}
+	if err = NewChecksummedWriterAt(noStatFile{f}, 10, crc32.NewIEEE).Truncate(10); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := f.Stat(); fi.Size() != 14 {
+		t.Fatal(fi.Size())
+	}
+}
+
+func TestTruncateChecksummed(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	if err = TruncateChecksummed(f, 32, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if fi, _ := f.Stat(); fi.Size() != 40 {
+		t.Fatal(fi.Size())
+	}
+	f.Seek(0, 0)
+	corrupted, err := NewChecksummedReader(f, 16, crc32.NewIEEE).VerifyAll(nil)
+	if err != nil || corrupted != nil {
+		t.Fatalf("%#v %#v", corrupted, err)
+	}
+	if err = TruncateChecksummed(f, 8, ChecksummedConfig{}); err == nil {
+		t.Fatal(err)
+	}
+	// Lengths past the end are refused rather than growing the file.
+	for _, length := range []int64{48, 33} {
+		if err = TruncateChecksummed(f, length, cfg); err == nil {
+			t.Fatal(length)
+		}
+	}
+	if fi, _ := f.Stat(); fi.Size() != 40 {
+		t.Fatal(fi.Size())
+	}
+}
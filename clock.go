@@ -0,0 +1,80 @@
+package brimio
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for the package's components that pace,
+// probe, or decay over time, such as PacedWriter, FailoverReader, Placer, and
+// RepairPlan. Each has a Clock field defaulting to SystemClock when nil, so
+// tests can substitute a ManualClock to run deterministically and without
+// waiting.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses for at least d.
+	Sleep(d time.Duration)
+}
+
+// Rand is the source of randomness for components such as Scrambled;
+// *math/rand.Rand and any math/rand.Source satisfy it.
+type Rand interface {
+	// Int63 returns a non-negative pseudo-random 63 bit integer.
+	Int63() int64
+}
+
+// SystemClock is the Clock of the time package itself.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// clockOrSystem returns c, or SystemClock if c is nil.
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+	return c
+}
+
+// ManualClock is a Clock whose time only moves when told to: by Advance, or
+// by Sleep, which returns at once having advanced the time by its duration.
+// It is safe for concurrent use.
+type ManualClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+// NewManualClock returns a ManualClock starting at the time given.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now implements the Clock interface.
+func (mc *ManualClock) Now() time.Time {
+	mc.lock.Lock()
+	defer mc.lock.Unlock()
+	return mc.now
+}
+
+// Sleep implements the Clock interface by advancing the time by d.
+func (mc *ManualClock) Sleep(d time.Duration) {
+	mc.Advance(d)
+}
+
+// Advance moves the time forward by d.
+func (mc *ManualClock) Advance(d time.Duration) {
+	mc.lock.Lock()
+	if d > 0 {
+		mc.now = mc.now.Add(d)
+	}
+	mc.lock.Unlock()
+}
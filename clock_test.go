@@ -0,0 +1,34 @@
+package brimio
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	mc := NewManualClock(start)
+	if !mc.Now().Equal(start) {
+		t.Fatal(mc.Now())
+	}
+	mc.Sleep(time.Second)
+	mc.Advance(time.Minute)
+	mc.Advance(-time.Hour)
+	if d := mc.Now().Sub(start); d != time.Minute+time.Second {
+		t.Fatal(d)
+	}
+	if clockOrSystem(nil) != SystemClock || clockOrSystem(mc) != mc {
+		t.Fatal()
+	}
+}
+
+func TestScrambledRand(t *testing.T) {
+	a := make([]byte, 20)
+	b := make([]byte, 20)
+	NewScrambledRand(rand.New(rand.NewSource(1))).Read(a)
+	NewSeededScrambled(1).Read(b)
+	if string(a) != string(b) {
+		t.Fatalf("%#v %#v", a, b)
+	}
+}
@@ -0,0 +1,332 @@
+package brimio
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// CompressedChecksummedMagic ends content written by
+// CompressedChecksummedWriter.
+const CompressedChecksummedMagic = "BRIMIOCZ"
+
+// compressedFooterLen is the length of the footer, not counting its checksum:
+// the index offset, interval, content length, and magic.
+const compressedFooterLen = 8 + 4 + 8 + len(CompressedChecksummedMagic)
+
+// CompressedChecksummedWriter compresses content an interval at a time, each
+// independently with DEFLATE and followed by a checksum of its compressed
+// form, so CompressedChecksummedReader can still seek to any offset of the
+// content and decompress just the interval there.
+//
+// Each interval is written as a big endian 4 byte length, that many bytes of
+// compressed content, and the checksum of those bytes. Close writes any
+// trailing partial interval the same way and then the index: a big endian 8
+// byte offset of each interval, followed by the footer of an 8 byte offset of
+// the index, the 4 byte interval, the 8 byte length of the content, the
+// CompressedChecksummedMagic, and a checksum of the index and footer.
+type CompressedChecksummedWriter struct {
+	// Level is the compression level, as with flate.NewWriter; it must be
+	// set, if at all, before the first Write.
+	Level    int
+	delegate io.Writer
+	interval int
+	newHash  func() hash.Hash
+	buf      []byte
+	out      bytes.Buffer
+	fw       *flate.Writer
+	offsets  []int64
+	offset   int64
+	length   int64
+	err      error
+}
+
+// NewCompressedChecksummedWriter returns a CompressedChecksummedWriter that
+// delegates to an underlying io.Writer, compressing each interval of content
+// and checksumming it with the hashing function given.
+//
+// Like the other constructors, it panics if the interval or hashing function
+// are not valid; see ChecksummedConfig.Validate.
+func NewCompressedChecksummedWriter(delegate io.Writer, interval int, newHash func() hash.Hash) *CompressedChecksummedWriter {
+	mustValidateChecksummed(interval, newHash)
+	return &CompressedChecksummedWriter{
+		Level:    flate.DefaultCompression,
+		delegate: delegate,
+		interval: interval,
+		newHash:  newHash,
+		buf:      make([]byte, 0, interval),
+	}
+}
+
+// Write implements the io.Writer interface.
+func (ccw *CompressedChecksummedWriter) Write(v []byte) (int, error) {
+	var n int
+	for len(v) > 0 {
+		if ccw.err != nil {
+			return n, ccw.err
+		}
+		c := ccw.interval - len(ccw.buf)
+		if c > len(v) {
+			c = len(v)
+		}
+		ccw.buf = append(ccw.buf, v[:c]...)
+		n += c
+		v = v[c:]
+		if len(ccw.buf) == ccw.interval {
+			ccw.writeInterval()
+		}
+	}
+	return n, ccw.err
+}
+
+// writeInterval compresses and writes out the buffered content.
+func (ccw *CompressedChecksummedWriter) writeInterval() {
+	ccw.out.Reset()
+	ccw.out.Write([]byte{0, 0, 0, 0})
+	if ccw.fw == nil {
+		if ccw.fw, ccw.err = flate.NewWriter(&ccw.out, ccw.Level); ccw.err != nil {
+			return
+		}
+	} else {
+		ccw.fw.Reset(&ccw.out)
+	}
+	ccw.fw.Write(ccw.buf)
+	if ccw.err = ccw.fw.Close(); ccw.err != nil {
+		return
+	}
+	b := ccw.out.Bytes()
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	hash := ccw.newHash()
+	hash.Write(b[4:])
+	b = hash.Sum(b)
+	ccw.offsets = append(ccw.offsets, ccw.offset)
+	ccw.length += int64(len(ccw.buf))
+	ccw.buf = ccw.buf[:0]
+	ccw.offset += int64(len(b))
+	_, ccw.err = ccw.delegate.Write(b)
+}
+
+// Close implements the io.Closer interface, writing any trailing partial
+// interval and the index, and then closing the underlying io.Writer if it
+// can be closed.
+func (ccw *CompressedChecksummedWriter) Close() error {
+	if ccw.err == nil && len(ccw.buf) > 0 {
+		ccw.writeInterval()
+	}
+	if ccw.err == nil {
+		b := make([]byte, 0, len(ccw.offsets)*8+compressedFooterLen)
+		var v [8]byte
+		for _, o := range ccw.offsets {
+			binary.BigEndian.PutUint64(v[:], uint64(o))
+			b = append(b, v[:]...)
+		}
+		binary.BigEndian.PutUint64(v[:], uint64(ccw.offset))
+		b = append(b, v[:]...)
+		binary.BigEndian.PutUint32(v[:], uint32(ccw.interval))
+		b = append(b, v[:4]...)
+		binary.BigEndian.PutUint64(v[:], uint64(ccw.length))
+		b = append(b, v[:]...)
+		b = append(b, CompressedChecksummedMagic...)
+		hash := ccw.newHash()
+		hash.Write(b)
+		_, ccw.err = ccw.delegate.Write(hash.Sum(b))
+	}
+	err := closeDelegate(ccw.delegate, nil)
+	if ccw.err == nil {
+		ccw.err = fmt.Errorf("closed")
+		return err
+	}
+	return ccw.err
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.Writer.
+func (ccw *CompressedChecksummedWriter) Unwrap() interface{} {
+	return unwrapDelegate(ccw.delegate)
+}
+
+// CompressedChecksummedReader reads content written by
+// CompressedChecksummedWriter, verifying and decompressing an interval at a
+// time; seeking only reads the interval sought to.
+//
+// Implements the io.ReadSeeker and io.Closer interfaces. Content that fails
+// its checksum results in ErrChecksumMismatch.
+type CompressedChecksummedReader struct {
+	delegate   io.ReadSeeker
+	newHash    func() hash.Hash
+	interval   int
+	length     int64
+	offsets    []int64
+	indexStart int64
+	pos        int64
+	block      []byte
+	blockIndex int64
+	raw        []byte
+	fr         io.ReadCloser
+}
+
+// NewCompressedChecksummedReader returns a CompressedChecksummedReader over
+// the content of delegate, reading and verifying its index with the hashing
+// function given.
+func NewCompressedChecksummedReader(delegate io.ReadSeeker, newHash func() hash.Hash) (*CompressedChecksummedReader, error) {
+	checksumSize := newHash().Size()
+	end, err := delegate.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	footerStart := end - int64(compressedFooterLen+checksumSize)
+	if footerStart < 0 {
+		return nil, fmt.Errorf("no compressed checksummed footer")
+	}
+	footer := make([]byte, compressedFooterLen+checksumSize)
+	if _, err = delegate.Seek(footerStart, 0); err != nil {
+		return nil, err
+	}
+	if _, err = io.ReadFull(delegate, footer); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(footer[20:20+len(CompressedChecksummedMagic)], []byte(CompressedChecksummedMagic)) {
+		return nil, fmt.Errorf("no compressed checksummed footer")
+	}
+	indexStart := int64(binary.BigEndian.Uint64(footer))
+	interval := int(binary.BigEndian.Uint32(footer[8:]))
+	length := int64(binary.BigEndian.Uint64(footer[12:]))
+	if interval < MinChecksumInterval || interval > MaxChecksumInterval || length < 0 || indexStart < 0 || indexStart > footerStart || (footerStart-indexStart)%8 != 0 {
+		return nil, fmt.Errorf("invalid compressed checksummed footer")
+	}
+	count := (footerStart - indexStart) / 8
+	if count != (length+int64(interval)-1)/int64(interval) {
+		return nil, fmt.Errorf("invalid compressed checksummed footer")
+	}
+	index := make([]byte, footerStart-indexStart, end-indexStart)
+	if _, err = delegate.Seek(indexStart, 0); err != nil {
+		return nil, err
+	}
+	if _, err = io.ReadFull(delegate, index); err != nil {
+		return nil, err
+	}
+	index = append(index, footer...)
+	hash := newHash()
+	hash.Write(index[:len(index)-checksumSize])
+	if !checksumMatches(hash, index[len(index)-checksumSize:], nil) {
+		return nil, ErrChecksumMismatch
+	}
+	ccr := &CompressedChecksummedReader{
+		delegate:   delegate,
+		newHash:    newHash,
+		interval:   interval,
+		length:     length,
+		offsets:    make([]int64, count),
+		indexStart: indexStart,
+		blockIndex: -1,
+	}
+	for i := range ccr.offsets {
+		ccr.offsets[i] = int64(binary.BigEndian.Uint64(index[i*8:]))
+	}
+	return ccr, nil
+}
+
+// Size returns the length of the content.
+func (ccr *CompressedChecksummedReader) Size() int64 {
+	return ccr.length
+}
+
+// Read implements the io.Reader interface.
+func (ccr *CompressedChecksummedReader) Read(v []byte) (int, error) {
+	if ccr.pos >= ccr.length {
+		return 0, io.EOF
+	}
+	index := ccr.pos / int64(ccr.interval)
+	if index != ccr.blockIndex {
+		if err := ccr.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(v, ccr.block[ccr.pos-index*int64(ccr.interval):])
+	ccr.pos += int64(n)
+	return n, nil
+}
+
+// load reads, verifies, and decompresses the interval at index.
+func (ccr *CompressedChecksummedReader) load(index int64) error {
+	ccr.blockIndex = -1
+	end := ccr.indexStart
+	if index+1 < int64(len(ccr.offsets)) {
+		end = ccr.offsets[index+1]
+	}
+	l := end - ccr.offsets[index]
+	if l < 4 || l > int64(2*ccr.interval+1024) {
+		return ErrChecksumMismatch
+	}
+	if int64(cap(ccr.raw)) < l {
+		ccr.raw = make([]byte, l)
+	}
+	raw := ccr.raw[:l]
+	if _, err := ccr.delegate.Seek(ccr.offsets[index], 0); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(ccr.delegate, raw); err != nil {
+		return err
+	}
+	checksumSize := len(raw) - 4 - int(binary.BigEndian.Uint32(raw))
+	hash := ccr.newHash()
+	if checksumSize != hash.Size() {
+		return ErrChecksumMismatch
+	}
+	compressed := raw[4 : len(raw)-checksumSize]
+	hash.Write(compressed)
+	if !checksumMatches(hash, raw[len(raw)-checksumSize:], nil) {
+		return ErrChecksumMismatch
+	}
+	length := ccr.length - index*int64(ccr.interval)
+	if length > int64(ccr.interval) {
+		length = int64(ccr.interval)
+	}
+	if ccr.block == nil {
+		ccr.block = make([]byte, ccr.interval)
+	}
+	if ccr.fr == nil {
+		ccr.fr = flate.NewReader(bytes.NewReader(compressed))
+	} else {
+		ccr.fr.(flate.Resetter).Reset(bytes.NewReader(compressed), nil)
+	}
+	if _, err := io.ReadFull(ccr.fr, ccr.block[:length]); err != nil {
+		return fmt.Errorf("decompressing interval %d: %s", index, err)
+	}
+	ccr.block = ccr.block[:length]
+	ccr.blockIndex = index
+	return nil
+}
+
+// Seek implements the io.Seeker interface, with offsets within the content.
+func (ccr *CompressedChecksummedReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+	case 1:
+		offset += ccr.pos
+	case 2:
+		offset += ccr.length
+	default:
+		return ccr.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return ccr.pos, fmt.Errorf("negative position %d", offset)
+	}
+	ccr.pos = offset
+	return offset, nil
+}
+
+// Close implements the io.Closer interface, closing the underlying
+// io.ReadSeeker if it can be closed.
+func (ccr *CompressedChecksummedReader) Close() error {
+	return closeDelegate(ccr.delegate, nil)
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.ReadSeeker.
+func (ccr *CompressedChecksummedReader) Unwrap() interface{} {
+	return unwrapDelegate(ccr.delegate)
+}
@@ -0,0 +1,87 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompressedChecksummed(t *testing.T) {
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	content := bytes.Repeat([]byte("0123456789abcdef"), 100)
+	content = append(content, "tail"...)
+	buf := &bytes.Buffer{}
+	ccw := NewCompressedChecksummedWriter(buf, 256, newHash)
+	for i := 0; i < len(content); i += 100 {
+		end := i + 100
+		if end > len(content) {
+			end = len(content)
+		}
+		if _, err := ccw.Write(content[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ccw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(content) {
+		t.Fatal(buf.Len())
+	}
+	ccr, err := NewCompressedChecksummedReader(bytes.NewReader(buf.Bytes()), newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ccr.Size() != int64(len(content)) {
+		t.Fatal(ccr.Size())
+	}
+	v, err := ioutil.ReadAll(ccr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, content) {
+		t.Fatalf("%#v", string(v))
+	}
+	if _, err = ccr.Seek(1000, 0); err != nil {
+		t.Fatal(err)
+	}
+	v = make([]byte, 30)
+	if _, err = io.ReadFull(ccr, v); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, content[1000:1030]) {
+		t.Fatalf("%#v", string(v))
+	}
+	if _, err = ccr.Seek(-2, 2); err != nil {
+		t.Fatal(err)
+	}
+	if v, err = ioutil.ReadAll(ccr); err != nil || string(v) != "il" {
+		t.Fatalf("%#v %#v", string(v), err)
+	}
+	// Corrupting the first interval is detected only when reading it.
+	b := append([]byte{}, buf.Bytes()...)
+	b[6] ^= 1
+	ccr, err = NewCompressedChecksummedReader(bytes.NewReader(b), newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ccr.Seek(300, 0)
+	if _, err = ccr.Read(v); err != nil {
+		t.Fatal(err)
+	}
+	ccr.Seek(0, 0)
+	if _, err = ccr.Read(v); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+	// Corrupting the index is detected on opening.
+	b = append([]byte{}, buf.Bytes()...)
+	b[len(b)-40] ^= 1
+	if _, err = NewCompressedChecksummedReader(bytes.NewReader(b), newHash); err == nil {
+		t.Fatal(err)
+	}
+	if _, err = NewCompressedChecksummedReader(bytes.NewReader(content), newHash); err == nil {
+		t.Fatal(err)
+	}
+}
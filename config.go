@@ -0,0 +1,72 @@
+package brimio
+
+import (
+	"fmt"
+	"hash"
+)
+
+// MinChecksumInterval and MaxChecksumInterval bound the interval of content
+// between checksums.
+const (
+	MinChecksumInterval = 1
+	MaxChecksumInterval = 1 << 30
+)
+
+// MaxChecksumSize is the largest checksum, in bytes, a hashing function may
+// produce; enough for NewMultiHash combining SHA-512 with several others.
+const MaxChecksumSize = 256
+
+// ChecksummedConfig describes the layout of checksummed content: the
+// interval of content between checksums and the hashing function used to
+// compute them.
+type ChecksummedConfig struct {
+	Interval int
+	NewHash  func() hash.Hash
+}
+
+// Validate returns an error if the ChecksummedConfig can't be used: the
+// Interval must be within MinChecksumInterval and MaxChecksumInterval and
+// NewHash must give checksums of 1 to MaxChecksumSize bytes.
+func (cfg ChecksummedConfig) Validate() error {
+	return validateChecksummed(cfg.Interval, cfg.NewHash)
+}
+
+func validateChecksummed(interval int, newHash func() hash.Hash) error {
+	if interval < MinChecksumInterval || interval > MaxChecksumInterval {
+		return fmt.Errorf("invalid checksum interval %d", interval)
+	}
+	if newHash == nil {
+		return fmt.Errorf("no hashing function")
+	}
+	if size := newHash().Size(); size < 1 || size > MaxChecksumSize {
+		return fmt.Errorf("invalid checksum size %d", size)
+	}
+	return nil
+}
+
+// mustValidateChecksummed panics if the interval and hashing function given
+// to a constructor can't be used, as they would otherwise corrupt content or
+// fail in obscure ways later.
+func mustValidateChecksummed(interval int, newHash func() hash.Hash) {
+	if err := validateChecksummed(interval, newHash); err != nil {
+		panic(err)
+	}
+}
+
+// hash32 adapts a 32 bit hashing function given to a constructor, keeping nil
+// as nil so validation still reports it rather than the wrapper panicking
+// once called.
+func hash32(newHash func() hash.Hash32) func() hash.Hash {
+	if newHash == nil {
+		return nil
+	}
+	return func() hash.Hash { return newHash() }
+}
+
+// hash64 is hash32 for 64 bit hashing functions.
+func hash64(newHash func() hash.Hash64) func() hash.Hash {
+	if newHash == nil {
+		return nil
+	}
+	return func() hash.Hash { return newHash() }
+}
@@ -0,0 +1,72 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func TestChecksummedConfigValidate(t *testing.T) {
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	if err := (ChecksummedConfig{Interval: 16, NewHash: newHash}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (ChecksummedConfig{Interval: 16, NewHash: NewMultiHash(sha512.New, newHash)}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []ChecksummedConfig{
+		{Interval: 0, NewHash: newHash},
+		{Interval: -1, NewHash: newHash},
+		{Interval: MaxChecksumInterval + 1, NewHash: newHash},
+		{Interval: 16},
+		{Interval: 16, NewHash: NewMultiHash()},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%#v", cfg)
+		}
+	}
+	if _, err := NewChecksummedWriterWithHeader(&bytes.Buffer{}, 0, "crc32-ieee"); err == nil {
+		t.Fatal(err)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("no panic")
+			}
+		}()
+		NewChecksummedWriter(&bytes.Buffer{}, 0, crc32.NewIEEE)
+	}()
+}
+
+func TestChecksummedConstructorsNilHash(t *testing.T) {
+	// Each panics as validation does, not later from calling the nil
+	// function through a wrapper.
+	for name, construct := range map[string]func(){
+		"NewChecksummedReader":   func() { NewChecksummedReader(bytes.NewReader(nil), 16, nil) },
+		"NewChecksummedReader64": func() { NewChecksummedReader64(bytes.NewReader(nil), 16, nil) },
+		"NewChecksummedWriter":   func() { NewChecksummedWriter(&bytes.Buffer{}, 16, nil) },
+		"NewChecksummedWriter64": func() { NewChecksummedWriter64(&bytes.Buffer{}, 16, nil) },
+		"NewMultiCoreChecksummedWriter": func() {
+			NewMultiCoreChecksummedWriter(&bytes.Buffer{}, 16, nil, 2)
+		},
+		"NewMultiCoreChecksummedWriter64": func() {
+			NewMultiCoreChecksummedWriter64(&bytes.Buffer{}, 16, nil, 2)
+		},
+		"NewChecksummedWriterAt":        func() { NewChecksummedWriterAt(nil, 16, nil) },
+		"NewStreamingChecksummedReader": func() { NewStreamingChecksummedReader(bytes.NewReader(nil), 16, nil) },
+		"NewAppendingChecksummedWriter": func() {
+			NewAppendingChecksummedWriter(nil, 16, nil)
+		},
+	} {
+		func() {
+			defer func() {
+				if err, ok := recover().(error); !ok || err.Error() != "no hashing function" {
+					t.Fatal(name, err)
+				}
+			}()
+			construct()
+		}()
+	}
+}
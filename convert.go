@@ -0,0 +1,34 @@
+package brimio
+
+import (
+	"hash"
+	"io"
+)
+
+// AddChecksums converts the plain content of src into checksummed content
+// written to dst, as NewChecksummedWriterHash would write it, in a single
+// streaming pass. It returns the amount of content converted; dst is left
+// open.
+func AddChecksums(dst io.Writer, src io.Reader, interval int, newHash func() hash.Hash) (int64, error) {
+	if err := validateChecksummed(interval, newHash); err != nil {
+		return 0, err
+	}
+	cw := NewChecksummedWriterHash(dst, interval, newHash)
+	n, err := cw.ReadFrom(src)
+	if err != nil {
+		return n, err
+	}
+	return n, cw.CloseWithoutDelegate()
+}
+
+// StripChecksums copies the checksummed content of src to dst as plain
+// content, verifying and removing the checksums as it goes. If an interval is
+// not checksum valid, copying stops with a *ChecksumMismatchError giving the
+// interval's index. It returns the amount of content copied.
+func StripChecksums(dst io.Writer, src io.ReadSeeker, interval int, newHash func() hash.Hash) (int64, error) {
+	if err := validateChecksummed(interval, newHash); err != nil {
+		return 0, err
+	}
+	cr := NewChecksummedReaderWithOptions(src, interval, newHash, &ChecksummedReaderOptions{AutoVerify: true})
+	return io.Copy(dst, cr)
+}
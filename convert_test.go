@@ -0,0 +1,54 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestAddChecksums(t *testing.T) {
+	content := "12345678901234567890ghijklmnopqrstuvwxyz"
+	want := &bytes.Buffer{}
+	cw := NewChecksummedWriter(want, 16, crc32.NewIEEE)
+	cw.Write([]byte(content))
+	cw.Close()
+	buf := &bytes.Buffer{}
+	n, err := AddChecksums(buf, strings.NewReader(content), 16, func() hash.Hash { return crc32.NewIEEE() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(content)) || !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Fatalf("%d %#v", n, buf.String())
+	}
+}
+
+func TestStripChecksums(t *testing.T) {
+	content := "12345678901234567890ghijklmnopqrstuvwxyz"
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte(content))
+	cw.Close()
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	out := &bytes.Buffer{}
+	n, err := StripChecksums(out, bytes.NewReader(buf.Bytes()), 16, newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(content)) || out.String() != content {
+		t.Fatalf("%d %#v", n, out.String())
+	}
+	b := append([]byte{}, buf.Bytes()...)
+	b[25] = 'X'
+	out.Reset()
+	n, err = StripChecksums(out, bytes.NewReader(b), 16, newHash)
+	var mismatch *ChecksumMismatchError
+	if !errors.As(err, &mismatch) || mismatch.BlockIndex != 1 {
+		t.Fatal(err)
+	}
+	if n != 16 || out.String() != content[:16] {
+		t.Fatalf("%d %#v", n, out.String())
+	}
+}
@@ -0,0 +1,58 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+)
+
+// CopyChecksummed copies checksummed content as is from src to dst with
+// io.Copy, so copies between files can take the kernel's accelerated paths,
+// such as copy_file_range or sendfile, that never pass the content through
+// this process to be checked. Afterward, if every is greater than 0, every
+// every'th complete interval of the copied content is read back from dst and
+// verified, with 1 verifying them all; this requires dst to also be an
+// io.ReaderAt and io.Seeker.
+//
+// The copy should start at an interval boundary of src. It returns the count
+// of bytes copied, checksums included, and the indexes, counting from the
+// start of the copy, of any sampled intervals that are not checksum valid.
+func CopyChecksummed(dst io.Writer, src io.Reader, cfg ChecksummedConfig, every int) (int64, []int64, error) {
+	var ra io.ReaderAt
+	var start int64
+	if every > 0 {
+		if err := cfg.Validate(); err != nil {
+			return 0, nil, err
+		}
+		var ok bool
+		if ra, ok = dst.(io.ReaderAt); !ok {
+			return 0, nil, fmt.Errorf("destination %T cannot be read back to verify", dst)
+		}
+		s, ok := dst.(io.Seeker)
+		if !ok {
+			return 0, nil, fmt.Errorf("destination %T cannot be read back to verify", dst)
+		}
+		var err error
+		if start, err = s.Seek(0, 1); err != nil {
+			return 0, nil, err
+		}
+	}
+	n, err := io.Copy(dst, src)
+	if err != nil || every <= 0 {
+		return n, nil, err
+	}
+	block := make([]byte, cfg.Interval+cfg.NewHash().Size())
+	var corrupt []int64
+	for i := int64(0); i < n/int64(len(block)); i += int64(every) {
+		// io.ReaderAt allows io.EOF with a full read at the end.
+		if r, err := ra.ReadAt(block, start+i*int64(len(block))); err != nil && !(err == io.EOF && r == len(block)) {
+			return n, corrupt, err
+		}
+		hash := cfg.NewHash()
+		setChecksumIndex(hash, i)
+		hash.Write(block[:cfg.Interval])
+		if !checksumMatches(hash, block[cfg.Interval:], nil) {
+			corrupt = append(corrupt, i)
+		}
+	}
+	return n, corrupt, nil
+}
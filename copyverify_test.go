@@ -0,0 +1,77 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestCopyChecksummed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz1234567890"))
+	cw.Close()
+	b := buf.Bytes()
+	b[45] = 'X'
+	src, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+	dst, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+	src.Write(b)
+	dst.Write([]byte("prefix"))
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	for _, every := range []int{1, 2} {
+		src.Seek(0, 0)
+		dst.Seek(6, 0)
+		n, corrupt, err := CopyChecksummed(dst, src, cfg, every)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(len(b)) {
+			t.Fatal(n)
+		}
+		if len(corrupt) != 1 || corrupt[0] != 2 {
+			t.Fatal(every, corrupt)
+		}
+	}
+	src.Seek(0, 0)
+	dst.Seek(6, 0)
+	if _, corrupt, err := CopyChecksummed(dst, src, cfg, 3); err != nil || corrupt != nil {
+		t.Fatal(corrupt, err)
+	}
+	if _, _, err := CopyChecksummed(&bytes.Buffer{}, bytes.NewReader(b), cfg, 1); err == nil {
+		t.Fatal(err)
+	}
+	// Reading back the last interval may give io.EOF along with it.
+	b = b[:40]
+	b[5] = 'X'
+	dst2 := &eofReadWriteSeeker{}
+	if _, corrupt, err := CopyChecksummed(dst2, bytes.NewReader(b), cfg, 1); err != nil || len(corrupt) != 1 || corrupt[0] != 0 {
+		t.Fatal(corrupt, err)
+	}
+}
+
+// eofReadWriteSeeker is written to in memory and read back as an
+// eofReaderAt.
+type eofReadWriteSeeker struct {
+	bytes.Buffer
+}
+
+func (w *eofReadWriteSeeker) Seek(offset int64, whence int) (int64, error) {
+	return int64(w.Len()), nil
+}
+
+func (w *eofReadWriteSeeker) ReadAt(v []byte, offset int64) (int, error) {
+	return eofReaderAt{bytes.NewReader(w.Bytes())}.ReadAt(v, offset)
+}
@@ -0,0 +1,36 @@
+package brimio
+
+import (
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// castagnoliTable is built once; hash/crc32 uses SSE4.2 or ARMv8 CRC
+// instructions for it where available.
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// NewCRC32C returns a new Castagnoli CRC32 hash.Hash32, hardware accelerated
+// where available, suitable as the newHash of any of the constructors.
+func NewCRC32C() hash.Hash32 {
+	return crc32.New(castagnoliTable)
+}
+
+// NewCRC32CChecksummedWriter returns a ChecksummedWriter like
+// NewChecksummedWriter does but using Castagnoli CRC32 checksums.
+func NewCRC32CChecksummedWriter(delegate io.Writer, checksumInterval int) ChecksummedWriter {
+	return NewChecksummedWriter(delegate, checksumInterval, NewCRC32C)
+}
+
+// NewCRC32CChecksummedReader returns a ChecksummedReader like
+// NewChecksummedReader does but expecting Castagnoli CRC32 checksums.
+func NewCRC32CChecksummedReader(delegate io.ReadSeeker, interval int) ChecksummedReader {
+	return NewChecksummedReader(delegate, interval, NewCRC32C)
+}
+
+// NewCRC32CStreamingChecksummedReader returns an io.ReadCloser like
+// NewStreamingChecksummedReader does but expecting Castagnoli CRC32
+// checksums.
+func NewCRC32CStreamingChecksummedReader(delegate io.Reader, interval int) io.ReadCloser {
+	return NewStreamingChecksummedReader(delegate, interval, NewCRC32C)
+}
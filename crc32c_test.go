@@ -0,0 +1,33 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCRC32CChecksummed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewCRC32CChecksummedWriter(buf, 16)
+	cw.Write([]byte("12345678901234567890"))
+	cw.Close()
+	if c := crc32.Checksum([]byte("1234567890123456"), crc32.MakeTable(crc32.Castagnoli)); !bytes.Equal(buf.Bytes()[16:20], []byte{byte(c >> 24), byte(c >> 16), byte(c >> 8), byte(c)}) {
+		t.Fatalf("%#v", buf.Bytes())
+	}
+	cr := NewCRC32CChecksummedReader(bytes.NewReader(buf.Bytes()), 16)
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal(ok)
+	}
+	v, err := ioutil.ReadAll(NewCRC32CStreamingChecksummedReader(bytes.NewReader(buf.Bytes()), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890" {
+		t.Fatalf("%#v", string(v))
+	}
+}
@@ -0,0 +1,85 @@
+package brimio
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// BlockRange identifies the blocks First through Last inclusive, counting from
+// 0, where each block is an interval of content and its checksum.
+type BlockRange struct {
+	First int64
+	Last  int64
+}
+
+// dumpPreviewSize is the most content DumpBlocks will show for each block.
+const dumpPreviewSize = 64
+
+// DumpBlocks writes a human readable description of the blocks of
+// checksummed content from r to w, for debugging. For each block it lists the
+// offset within r, the content length, the stored and computed checksums, and
+// a hex preview of the start of the content.
+//
+// Only blocks within the ranges given are described, or all blocks if ranges
+// is empty. The trailing block is noted as unchecksummed if it falls short of
+// a full interval. An error is returned, before anything is written, if cfg
+// is not valid.
+func DumpBlocks(w io.Writer, r io.ReadSeeker, cfg ChecksummedConfig, ranges []BlockRange) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	checksumSize := cfg.NewHash().Size()
+	blockSize := int64(cfg.Interval + checksumSize)
+	block := make([]byte, blockSize)
+	computed := make([]byte, checksumSize)
+	end, err := r.Seek(0, 2)
+	if err != nil || end == 0 {
+		return err
+	}
+	last := (end - 1) / blockSize
+	if len(ranges) == 0 {
+		ranges = []BlockRange{{0, last}}
+	}
+	for _, rng := range ranges {
+		for i := rng.First; i <= rng.Last && i <= last; i++ {
+			if i < 0 {
+				continue
+			}
+			if _, err = r.Seek(i*blockSize, 0); err != nil {
+				return err
+			}
+			n, err := io.ReadFull(r, block)
+			if err != nil && err != io.ErrUnexpectedEOF {
+				return err
+			}
+			if n < len(block) {
+				if n > cfg.Interval {
+					n = cfg.Interval
+				}
+				_, err = fmt.Fprintf(w, "block %d offset %d length %d unchecksummed\n", i, i*blockSize, n)
+			} else {
+				n = cfg.Interval
+				hash := cfg.NewHash()
+				setChecksumIndex(hash, i)
+				hash.Write(block[:n])
+				computed = hash.Sum(computed[:0])
+				status := "ok"
+				if !checksumMatches(hash, block[n:], nil) {
+					status = "MISMATCH"
+				}
+				_, err = fmt.Fprintf(w, "block %d offset %d length %d stored %x computed %x %s\n", i, i*blockSize, n, block[n:], computed, status)
+			}
+			if err != nil {
+				return err
+			}
+			if n > dumpPreviewSize {
+				n = dumpPreviewSize
+			}
+			if _, err = io.WriteString(w, hex.Dump(block[:n])); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestDumpBlocks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[20] = 'X'
+	out := &bytes.Buffer{}
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	if err := DumpBlocks(out, bytes.NewReader(b), cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[0], "block 0 offset 0 length 16 stored ") || !strings.HasSuffix(lines[0], " ok") {
+		t.Fatalf("%#v", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "block 1 offset 20 length 16 stored ") || !strings.HasSuffix(lines[2], " MISMATCH") {
+		t.Fatalf("%#v", lines[2])
+	}
+	if lines[4] != "block 2 offset 40 length 8 unchecksummed" {
+		t.Fatalf("%#v", lines[4])
+	}
+	out.Reset()
+	if err := DumpBlocks(out, bytes.NewReader(b), cfg, []BlockRange{{2, 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "block 2 ") {
+		t.Fatalf("%#v", out.String())
+	}
+	out.Reset()
+	if err := DumpBlocks(out, bytes.NewReader(nil), cfg, nil); err != nil || out.Len() != 0 {
+		t.Fatal(err, out.String())
+	}
+}
+
+func TestDumpBlocksInvalidConfig(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	out := &bytes.Buffer{}
+	for _, cfg := range []ChecksummedConfig{{}, {Interval: 16}, {NewHash: func() hash.Hash { return crc32.NewIEEE() }}} {
+		if err := DumpBlocks(out, bytes.NewReader(buf.Bytes()), cfg, nil); err == nil || out.Len() != 0 {
+			t.Fatalf("%#v %v %q", cfg, err, out.String())
+		}
+	}
+}
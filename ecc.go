@@ -0,0 +1,468 @@
+package brimio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// ECCConfig describes the layout of content written by ECCWriter.
+//
+// Each interval of content is split into DataShards equal shards and followed
+// by ParityShards Reed-Solomon parity shards of the same size and then a 4
+// byte big endian CRC-32 (IEEE) of each of the data and parity shards in
+// turn. Any ParityShards of the shards of an interval can then be lost to
+// corruption and rebuilt from the rest, the checksums identifying which are
+// corrupt.
+//
+// A trailing partial interval of n bytes is treated as if zero padded to
+// DataShards shards of n/DataShards bytes, rounded up, though the padding
+// itself is not written.
+type ECCConfig struct {
+	Interval     int
+	DataShards   int
+	ParityShards int
+}
+
+// Validate returns an error if the ECCConfig can't be used: the shards must
+// total no more than 256 and the Interval must divide evenly into the
+// DataShards.
+func (cfg ECCConfig) Validate() error {
+	if cfg.DataShards < 1 || cfg.ParityShards < 1 || cfg.DataShards+cfg.ParityShards > 256 {
+		return fmt.Errorf("invalid shard counts %d+%d", cfg.DataShards, cfg.ParityShards)
+	}
+	if cfg.Interval < 1 || cfg.Interval%cfg.DataShards != 0 {
+		return fmt.Errorf("interval %d not a multiple of %d data shards", cfg.Interval, cfg.DataShards)
+	}
+	return nil
+}
+
+// shardSize returns the shard size for an interval of n bytes of content.
+func (cfg ECCConfig) shardSize(n int) int {
+	return (n + cfg.DataShards - 1) / cfg.DataShards
+}
+
+// blockLen returns the length of an interval of n bytes of content along
+// with its parity and checksums.
+func (cfg ECCConfig) blockLen(n int) int {
+	return n + cfg.ParityShards*cfg.shardSize(n) + 4*(cfg.DataShards+cfg.ParityShards)
+}
+
+// contentLen returns the length of content in a trailing block of n bytes,
+// or -1 if no content length fits.
+func (cfg ECCConfig) contentLen(n int) int {
+	for s := 1; s <= cfg.Interval/cfg.DataShards; s++ {
+		l := n - cfg.ParityShards*s - 4*(cfg.DataShards+cfg.ParityShards)
+		if l > 0 && cfg.shardSize(l) == s {
+			return l
+		}
+	}
+	return -1
+}
+
+// ECCWriter writes content with Reed-Solomon parity, as described by
+// ECCConfig, so corrupt intervals can be repaired rather than just detected.
+type ECCWriter struct {
+	delegate io.Writer
+	cfg      ECCConfig
+	codec    *eccCodec
+	buf      []byte
+	block    []byte
+}
+
+// NewECCWriter returns an ECCWriter that delegates to an underlying io.Writer
+// with the layout given.
+func NewECCWriter(delegate io.Writer, cfg ECCConfig) (*ECCWriter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &ECCWriter{
+		delegate: delegate,
+		cfg:      cfg,
+		codec:    newECCCodec(cfg.DataShards, cfg.ParityShards),
+		buf:      make([]byte, 0, cfg.Interval),
+		block:    make([]byte, cfg.blockLen(cfg.Interval)),
+	}, nil
+}
+
+// Write implements the io.Writer interface.
+func (ew *ECCWriter) Write(v []byte) (int, error) {
+	var n int
+	for len(v) > 0 {
+		c := copy(ew.buf[len(ew.buf):cap(ew.buf)], v)
+		ew.buf = ew.buf[:len(ew.buf)+c]
+		v = v[c:]
+		if len(ew.buf) == cap(ew.buf) {
+			if err := ew.flush(); err != nil {
+				return n, err
+			}
+		}
+		n += c
+	}
+	return n, nil
+}
+
+func (ew *ECCWriter) flush() error {
+	block := ew.codec.encodeBlock(ew.cfg, ew.buf, ew.block)
+	ew.buf = ew.buf[:0]
+	if _, err := ew.delegate.Write(block); err != nil {
+		ew.delegate = errDelegate
+		return err
+	}
+	return nil
+}
+
+// Close implements the io.Closer interface, writing any trailing partial
+// interval and then closing the underlying io.Writer if it can be closed.
+func (ew *ECCWriter) Close() error {
+	if len(ew.buf) > 0 {
+		if err := ew.flush(); err != nil {
+			return err
+		}
+	}
+	err := closeDelegate(ew.delegate, nil)
+	ew.delegate = errDelegate
+	return err
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.Writer.
+func (ew *ECCWriter) Unwrap() interface{} {
+	return unwrapDelegate(ew.delegate)
+}
+
+// ECCReader reads content written by ECCWriter, repairing any intervals with
+// corrupt shards as it goes. Intervals with more corrupt shards than can be
+// repaired result in ErrChecksumMismatch from Read.
+type ECCReader struct {
+	delegate io.Reader
+	cfg      ECCConfig
+	codec    *eccCodec
+	block    []byte
+	content  []byte
+	repaired int64
+	err      error
+}
+
+// NewECCReader returns an ECCReader that delegates to an underlying io.Reader
+// with the layout given.
+func NewECCReader(delegate io.Reader, cfg ECCConfig) (*ECCReader, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &ECCReader{
+		delegate: delegate,
+		cfg:      cfg,
+		codec:    newECCCodec(cfg.DataShards, cfg.ParityShards),
+		block:    make([]byte, cfg.blockLen(cfg.Interval)),
+	}, nil
+}
+
+// Read implements the io.Reader interface.
+func (er *ECCReader) Read(v []byte) (int, error) {
+	if len(er.content) == 0 {
+		if er.err != nil {
+			return 0, er.err
+		}
+		n, err := io.ReadFull(er.delegate, er.block)
+		l := er.cfg.Interval
+		switch err {
+		case nil:
+		case io.ErrUnexpectedEOF:
+			if l = er.cfg.contentLen(n); l < 0 {
+				er.err = ErrChecksumMismatch
+				return 0, er.err
+			}
+			er.err = io.EOF
+		default:
+			er.err = err
+			return 0, err
+		}
+		repaired, err := er.codec.decodeBlock(er.cfg, er.block[:n], l)
+		if err != nil {
+			er.err = err
+			return 0, err
+		}
+		if repaired {
+			er.repaired++
+		}
+		er.content = er.block[:l]
+	}
+	n := copy(v, er.content)
+	er.content = er.content[n:]
+	return n, nil
+}
+
+// Repaired returns the number of intervals repaired so far.
+func (er *ECCReader) Repaired() int64 {
+	return er.repaired
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.Reader.
+func (er *ECCReader) Unwrap() interface{} {
+	return unwrapDelegate(er.delegate)
+}
+
+// RepairECC repairs, in place, any intervals of the size bytes of content
+// written by ECCWriter in rw that have corrupt shards. It returns the indexes
+// of the intervals repaired and of those with too many corrupt shards to be
+// repaired.
+func RepairECC(rw ReadWriterAt, size int64, cfg ECCConfig) ([]int64, []int64, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	codec := newECCCodec(cfg.DataShards, cfg.ParityShards)
+	blockLen := int64(cfg.blockLen(cfg.Interval))
+	block := make([]byte, blockLen)
+	var repaired []int64
+	var unrepairable []int64
+	for i := int64(0); i*blockLen < size; i++ {
+		b := block
+		l := cfg.Interval
+		if size-i*blockLen < blockLen {
+			b = block[:size-i*blockLen]
+			if l = cfg.contentLen(len(b)); l < 0 {
+				unrepairable = append(unrepairable, i)
+				break
+			}
+		}
+		if _, err := rw.ReadAt(b, i*blockLen); err != nil && err != io.EOF {
+			return repaired, unrepairable, err
+		}
+		ok, err := codec.decodeBlock(cfg, b, l)
+		if err == ErrChecksumMismatch {
+			unrepairable = append(unrepairable, i)
+			continue
+		}
+		if err != nil {
+			return repaired, unrepairable, err
+		}
+		if ok {
+			if _, err = rw.WriteAt(b, i*blockLen); err != nil {
+				return repaired, unrepairable, err
+			}
+			repaired = append(repaired, i)
+		}
+	}
+	return repaired, unrepairable, nil
+}
+
+// eccCodec does Reed-Solomon erasure coding over GF(2^8) with a systematic
+// Cauchy matrix, so any k of the k+m shards can rebuild the others.
+type eccCodec struct {
+	k int
+	m int
+	// matrix is the k+m rows by k columns encoding matrix; the identity for
+	// the data shards then the parity rows.
+	matrix [][]byte
+}
+
+func newECCCodec(k int, m int) *eccCodec {
+	c := &eccCodec{k: k, m: m, matrix: make([][]byte, k+m)}
+	for i := range c.matrix {
+		c.matrix[i] = make([]byte, k)
+		if i < k {
+			c.matrix[i][i] = 1
+			continue
+		}
+		for j := 0; j < k; j++ {
+			c.matrix[i][j] = gfInv(byte(i) ^ byte(j))
+		}
+	}
+	return c
+}
+
+// shards returns the data and parity shards of the block, which holds l
+// bytes of content; any zero padding of the final data shard is in pad.
+func (c *eccCodec) shards(cfg ECCConfig, block []byte, l int, pad []byte) [][]byte {
+	s := cfg.shardSize(l)
+	shards := make([][]byte, c.k+c.m)
+	for i := 0; i < c.k; i++ {
+		if (i+1)*s <= l {
+			shards[i] = block[i*s : (i+1)*s]
+			continue
+		}
+		shards[i] = pad[:s]
+		for j := range shards[i] {
+			shards[i][j] = 0
+		}
+		if i*s < l {
+			copy(shards[i], block[i*s:l])
+		}
+		pad = pad[s:]
+	}
+	for i := 0; i < c.m; i++ {
+		shards[c.k+i] = block[l+i*s : l+(i+1)*s]
+	}
+	return shards
+}
+
+// encodeBlock writes content along with its parity shards and checksums into
+// block, returning the portion of block used.
+func (c *eccCodec) encodeBlock(cfg ECCConfig, content []byte, block []byte) []byte {
+	l := len(content)
+	block = block[:cfg.blockLen(l)]
+	copy(block, content)
+	shards := c.shards(cfg, block, l, make([]byte, c.k*cfg.shardSize(l)))
+	for i := 0; i < c.m; i++ {
+		c.combine(c.matrix[c.k+i], shards[:c.k], shards[c.k+i])
+	}
+	sums := block[l+c.m*cfg.shardSize(l):]
+	for i, shard := range shards {
+		binary.BigEndian.PutUint32(sums[i*4:], crc32.ChecksumIEEE(shard))
+	}
+	return block
+}
+
+// decodeBlock verifies the shards of a block holding l bytes of content,
+// rebuilding any corrupt ones in place and returning true if it did so. If
+// too many shards are corrupt ErrChecksumMismatch is returned.
+func (c *eccCodec) decodeBlock(cfg ECCConfig, block []byte, l int) (bool, error) {
+	s := cfg.shardSize(l)
+	shards := c.shards(cfg, block, l, make([]byte, c.k*s))
+	sums := block[l+c.m*s:]
+	var good []int
+	var bad []int
+	for i, shard := range shards {
+		if crc32.ChecksumIEEE(shard) == binary.BigEndian.Uint32(sums[i*4:]) {
+			good = append(good, i)
+		} else {
+			bad = append(bad, i)
+		}
+	}
+	if len(bad) == 0 {
+		return false, nil
+	}
+	if len(good) < c.k {
+		return false, ErrChecksumMismatch
+	}
+	good = good[:c.k]
+	sub := make([][]byte, c.k)
+	in := make([][]byte, c.k)
+	for i, g := range good {
+		sub[i] = c.matrix[g]
+		in[i] = shards[g]
+	}
+	inv, err := gfInvertMatrix(sub)
+	if err != nil {
+		return false, err
+	}
+	for _, b := range bad {
+		if b < c.k {
+			c.combine(inv[b], in, shards[b])
+		}
+	}
+	for _, b := range bad {
+		if b >= c.k {
+			c.combine(c.matrix[b], shards[:c.k], shards[b])
+		}
+	}
+	// The final data shard may have been rebuilt into padding, so copy its
+	// content back into the block.
+	for i := 0; i < c.k; i++ {
+		if i*s < l && (i+1)*s > l {
+			copy(block[i*s:l], shards[i])
+		}
+	}
+	for _, b := range bad {
+		binary.BigEndian.PutUint32(sums[b*4:], crc32.ChecksumIEEE(shards[b]))
+	}
+	// Check nothing slipped past the checksums.
+	parity := make([]byte, s)
+	for i := 0; i < c.m; i++ {
+		c.combine(c.matrix[c.k+i], shards[:c.k], parity)
+		if !bytes.Equal(parity, shards[c.k+i]) {
+			return false, ErrChecksumMismatch
+		}
+	}
+	return true, nil
+}
+
+// combine sets out to the sum of the shards each multiplied by its
+// coefficient.
+func (c *eccCodec) combine(coefficients []byte, shards [][]byte, out []byte) {
+	for i := range out {
+		out[i] = 0
+	}
+	for j, shard := range shards {
+		cf := coefficients[j]
+		if cf == 0 {
+			continue
+		}
+		for i, v := range shard {
+			out[i] ^= gfMul(cf, v)
+		}
+	}
+}
+
+var gfExp [510]byte
+var gfLog [256]int
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfExp[i+255] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func gfMul(a byte, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-gfLog[a]]
+}
+
+// gfInvertMatrix returns the inverse of the square matrix m by Gauss-Jordan
+// elimination.
+func gfInvertMatrix(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	a := make([][]byte, n)
+	for i := range a {
+		a[i] = make([]byte, 2*n)
+		copy(a[i], m[i])
+		a[i][n+i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for r := col; r < n; r++ {
+			if a[r][col] != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, fmt.Errorf("singular matrix")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		inv := gfInv(a[col][col])
+		for j := range a[col] {
+			a[col][j] = gfMul(a[col][j], inv)
+		}
+		for r := 0; r < n; r++ {
+			if r == col || a[r][col] == 0 {
+				continue
+			}
+			f := a[r][col]
+			for j := range a[r] {
+				a[r][j] ^= gfMul(f, a[col][j])
+			}
+		}
+	}
+	for i := range a {
+		a[i] = a[i][n:]
+	}
+	return a, nil
+}
@@ -0,0 +1,79 @@
+package brimio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestECC(t *testing.T) {
+	cfg := ECCConfig{Interval: 16, DataShards: 4, ParityShards: 2}
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	buf := &bytes.Buffer{}
+	ew, err := NewECCWriter(buf, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ew.Write(content[:5])
+	ew.Write(content[5:])
+	if err = ew.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Two full blocks of 16+8+24 and a trailing 8+4+24.
+	if buf.Len() != 48+48+36 {
+		t.Fatal(buf.Len())
+	}
+	good := buf.Bytes()
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	b := append([]byte{}, good...)
+	// Two shards of the first block, a parity shard of the second, and the
+	// last data shard of the trailing partial block.
+	b[0] ^= 0xff
+	b[13] ^= 0xff
+	b[48+17] ^= 0xff
+	b[96+7] ^= 0xff
+	r, err := NewECCReader(bytes.NewReader(b), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, content) || r.Repaired() != 3 {
+		t.Fatal(string(v), r.Repaired())
+	}
+	f.Write(b)
+	repaired, unrepairable, err := RepairECC(f, int64(len(b)), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repaired) != 3 || len(unrepairable) != 0 {
+		t.Fatal(repaired, unrepairable)
+	}
+	v, err = ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, good) {
+		t.Fatal(v)
+	}
+	// Three corrupt shards are too many.
+	b = append([]byte{}, good...)
+	b[0] ^= 0xff
+	b[5] ^= 0xff
+	b[9] ^= 0xff
+	r, _ = NewECCReader(bytes.NewReader(b), cfg)
+	if _, err = ioutil.ReadAll(r); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+	if err = (ECCConfig{Interval: 10, DataShards: 4, ParityShards: 2}).Validate(); err == nil {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,323 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// EncryptedChecksummedMagic starts content written by
+// EncryptedChecksummedWriter.
+const EncryptedChecksummedMagic = "BRIMIOEC"
+
+// encryptedSaltLen is the length of the random salt each file's key is
+// derived with.
+const encryptedSaltLen = 32
+
+// encryptedHeaderLen is the length of the header: the magic, the interval,
+// and the salt.
+const encryptedHeaderLen = len(EncryptedChecksummedMagic) + 4 + encryptedSaltLen
+
+// NewAESGCM returns an AES-GCM cipher.AEAD for the 16, 24, or 32 byte key
+// given, for use with EncryptedChecksummedWriter and
+// EncryptedChecksummedReader. Other AEADs, such as ChaCha20-Poly1305 from
+// golang.org/x/crypto, can be used just as well.
+func NewAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptedAEAD returns the AEAD for a file, keyed by the HMAC-SHA256 of its
+// salt under key, so the same key can be used for any number of files without
+// their nonces, which count intervals from 0, repeating under the same key.
+func encryptedAEAD(key []byte, salt []byte, newAEAD func(key []byte) (cipher.AEAD, error)) (cipher.AEAD, error) {
+	if len(key) == 0 || len(key) > sha256.Size {
+		return nil, fmt.Errorf("invalid key length %d", len(key))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(salt)
+	aead, err := newAEAD(mac.Sum(nil)[:len(key)])
+	if err != nil {
+		return nil, err
+	}
+	if aead.NonceSize() < 8 {
+		return nil, fmt.Errorf("nonce size %d too small", aead.NonceSize())
+	}
+	return aead, nil
+}
+
+// encryptedNonceAndData fills nonce with the interval index and returns the
+// additional data that binds the interval to its place: the index and
+// whether it is the final interval, so reordered or truncated content fails
+// to open.
+func encryptedNonceAndData(nonce []byte, index int64, final bool, data []byte) []byte {
+	for i := range nonce {
+		nonce[i] = 0
+	}
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], uint64(index))
+	data = append(data[:0], nonce[len(nonce)-8:]...)
+	if final {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
+
+// EncryptedChecksummedWriter seals content an interval at a time with an AEAD
+// such as AES-GCM, so each interval is both confidential and, through its
+// authentication tag, checked for tampering as well as accidental corruption
+// when read back with EncryptedChecksummedReader.
+//
+// The content starts with a header of the EncryptedChecksummedMagic, a big
+// endian 4 byte interval, and a random salt the file's key is derived from.
+// Each interval of content follows, sealed with its index as nonce, taking
+// the interval plus the AEAD's Overhead bytes. The final interval, which may
+// be partial or even empty, is marked as such so truncation is detected; as
+// it is only known to be final at Close, an interval's worth of content is
+// held back until more is written or the writer is closed.
+type EncryptedChecksummedWriter struct {
+	delegate io.Writer
+	interval int
+	aead     cipher.AEAD
+	buf      []byte
+	sealed   []byte
+	nonce    []byte
+	data     []byte
+	index    int64
+	err      error
+}
+
+// NewEncryptedChecksummedWriter returns an EncryptedChecksummedWriter that
+// delegates to an underlying io.Writer, writing the header at once and then
+// sealing each interval of content with the AEAD newAEAD returns for a key
+// derived from the key given, such as NewAESGCM.
+func NewEncryptedChecksummedWriter(delegate io.Writer, interval int, key []byte, newAEAD func(key []byte) (cipher.AEAD, error)) (*EncryptedChecksummedWriter, error) {
+	if interval < MinChecksumInterval || interval > MaxChecksumInterval {
+		return nil, fmt.Errorf("invalid interval %d", interval)
+	}
+	header := make([]byte, encryptedHeaderLen)
+	copy(header, EncryptedChecksummedMagic)
+	binary.BigEndian.PutUint32(header[len(EncryptedChecksummedMagic):], uint32(interval))
+	salt := header[len(EncryptedChecksummedMagic)+4:]
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	aead, err := encryptedAEAD(key, salt, newAEAD)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = delegate.Write(header); err != nil {
+		return nil, err
+	}
+	return &EncryptedChecksummedWriter{
+		delegate: delegate,
+		interval: interval,
+		aead:     aead,
+		buf:      make([]byte, 0, interval),
+		sealed:   make([]byte, 0, interval+aead.Overhead()),
+		nonce:    make([]byte, aead.NonceSize()),
+	}, nil
+}
+
+// Write implements the io.Writer interface.
+func (ecw *EncryptedChecksummedWriter) Write(v []byte) (int, error) {
+	var n int
+	for len(v) > 0 {
+		if ecw.err != nil {
+			return n, ecw.err
+		}
+		// The buffered interval is only sealed once there's more content,
+		// proving it isn't the final one.
+		if len(ecw.buf) == ecw.interval {
+			ecw.seal(false)
+			continue
+		}
+		c := ecw.interval - len(ecw.buf)
+		if c > len(v) {
+			c = len(v)
+		}
+		ecw.buf = append(ecw.buf, v[:c]...)
+		n += c
+		v = v[c:]
+	}
+	return n, ecw.err
+}
+
+func (ecw *EncryptedChecksummedWriter) seal(final bool) {
+	ecw.data = encryptedNonceAndData(ecw.nonce, ecw.index, final, ecw.data)
+	ecw.sealed = ecw.aead.Seal(ecw.sealed[:0], ecw.nonce, ecw.buf, ecw.data)
+	ecw.buf = ecw.buf[:0]
+	ecw.index++
+	_, ecw.err = ecw.delegate.Write(ecw.sealed)
+}
+
+// Close implements the io.Closer interface, sealing the final interval and
+// then closing the underlying io.Writer if it can be closed.
+func (ecw *EncryptedChecksummedWriter) Close() error {
+	if ecw.err == nil {
+		ecw.seal(true)
+	}
+	err := closeDelegate(ecw.delegate, nil)
+	if ecw.err == nil {
+		ecw.err = fmt.Errorf("closed")
+		return err
+	}
+	return ecw.err
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.Writer.
+func (ecw *EncryptedChecksummedWriter) Unwrap() interface{} {
+	return unwrapDelegate(ecw.delegate)
+}
+
+// EncryptedChecksummedReader reads content written by
+// EncryptedChecksummedWriter, opening an interval at a time; seeking only
+// reads the interval sought to.
+//
+// Implements the io.ReadSeeker and io.Closer interfaces. Content that fails
+// to open, whether corrupted, tampered with, reordered, truncated, or read
+// with the wrong key, results in ErrChecksumMismatch.
+type EncryptedChecksummedReader struct {
+	delegate   io.ReadSeeker
+	interval   int
+	aead       cipher.AEAD
+	count      int64
+	length     int64
+	pos        int64
+	block      []byte
+	blockIndex int64
+	sealed     []byte
+	nonce      []byte
+	data       []byte
+}
+
+// NewEncryptedChecksummedReader returns an EncryptedChecksummedReader over
+// the content of delegate, which must be decrypted with the same key and
+// AEAD it was written with.
+func NewEncryptedChecksummedReader(delegate io.ReadSeeker, key []byte, newAEAD func(key []byte) (cipher.AEAD, error)) (*EncryptedChecksummedReader, error) {
+	end, err := delegate.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, encryptedHeaderLen)
+	if _, err = delegate.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	if _, err = io.ReadFull(delegate, header); err != nil || !bytes.Equal(header[:len(EncryptedChecksummedMagic)], []byte(EncryptedChecksummedMagic)) {
+		return nil, fmt.Errorf("not encrypted checksummed content")
+	}
+	interval := int(binary.BigEndian.Uint32(header[len(EncryptedChecksummedMagic):]))
+	if interval < MinChecksumInterval || interval > MaxChecksumInterval {
+		return nil, fmt.Errorf("invalid interval %d", interval)
+	}
+	aead, err := encryptedAEAD(key, header[len(EncryptedChecksummedMagic)+4:], newAEAD)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := int64(interval + aead.Overhead())
+	size := end - int64(encryptedHeaderLen)
+	count := (size + blockSize - 1) / blockSize
+	last := size - (count-1)*blockSize
+	if count == 0 || last < int64(aead.Overhead()) {
+		// The final interval is missing.
+		return nil, ErrChecksumMismatch
+	}
+	return &EncryptedChecksummedReader{
+		delegate:   delegate,
+		interval:   interval,
+		aead:       aead,
+		count:      count,
+		length:     (count-1)*int64(interval) + last - int64(aead.Overhead()),
+		blockIndex: -1,
+		nonce:      make([]byte, aead.NonceSize()),
+	}, nil
+}
+
+// Size returns the length of the content.
+func (ecr *EncryptedChecksummedReader) Size() int64 {
+	return ecr.length
+}
+
+// Read implements the io.Reader interface.
+func (ecr *EncryptedChecksummedReader) Read(v []byte) (int, error) {
+	if ecr.pos >= ecr.length {
+		return 0, io.EOF
+	}
+	index := ecr.pos / int64(ecr.interval)
+	if index != ecr.blockIndex {
+		if err := ecr.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(v, ecr.block[ecr.pos-index*int64(ecr.interval):])
+	ecr.pos += int64(n)
+	return n, nil
+}
+
+// load reads and opens the interval at index.
+func (ecr *EncryptedChecksummedReader) load(index int64) error {
+	ecr.blockIndex = -1
+	blockSize := int64(ecr.interval + ecr.aead.Overhead())
+	length := blockSize
+	final := index == ecr.count-1
+	if final {
+		length = ecr.length - index*int64(ecr.interval) + int64(ecr.aead.Overhead())
+	}
+	if ecr.sealed == nil {
+		ecr.sealed = make([]byte, blockSize)
+	}
+	sealed := ecr.sealed[:length]
+	if _, err := ecr.delegate.Seek(int64(encryptedHeaderLen)+index*blockSize, 0); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(ecr.delegate, sealed); err != nil {
+		return err
+	}
+	ecr.data = encryptedNonceAndData(ecr.nonce, index, final, ecr.data)
+	block, err := ecr.aead.Open(ecr.block[:0], ecr.nonce, sealed, ecr.data)
+	if err != nil {
+		return ErrChecksumMismatch
+	}
+	ecr.block = block
+	ecr.blockIndex = index
+	return nil
+}
+
+// Seek implements the io.Seeker interface, with offsets within the content.
+func (ecr *EncryptedChecksummedReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+	case 1:
+		offset += ecr.pos
+	case 2:
+		offset += ecr.length
+	default:
+		return ecr.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return ecr.pos, fmt.Errorf("negative position %d", offset)
+	}
+	ecr.pos = offset
+	return offset, nil
+}
+
+// Close implements the io.Closer interface, closing the underlying
+// io.ReadSeeker if it can be closed.
+func (ecr *EncryptedChecksummedReader) Close() error {
+	return closeDelegate(ecr.delegate, nil)
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.ReadSeeker.
+func (ecr *EncryptedChecksummedReader) Unwrap() interface{} {
+	return unwrapDelegate(ecr.delegate)
+}
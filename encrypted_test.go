@@ -0,0 +1,81 @@
+package brimio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEncryptedChecksummed(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	for _, l := range []int{0, 16, 32, 40} {
+		buf := &bytes.Buffer{}
+		ecw, err := NewEncryptedChecksummedWriter(buf, 16, key, NewAESGCM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ecw.Write(content[:l]); err != nil {
+			t.Fatal(err)
+		}
+		if err = ecw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(buf.Bytes(), []byte("1234")) {
+			t.Fatal("content not encrypted")
+		}
+		ecr, err := NewEncryptedChecksummedReader(bytes.NewReader(buf.Bytes()), key, NewAESGCM)
+		if err != nil {
+			t.Fatal(l, err)
+		}
+		if ecr.Size() != int64(l) {
+			t.Fatal(l, ecr.Size())
+		}
+		v, err := ioutil.ReadAll(ecr)
+		if err != nil {
+			t.Fatal(l, err)
+		}
+		if !bytes.Equal(v, content[:l]) {
+			t.Fatalf("%d %#v", l, string(v))
+		}
+	}
+	buf := &bytes.Buffer{}
+	ecw, err := NewEncryptedChecksummedWriter(buf, 16, key, NewAESGCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecw.Write(content)
+	ecw.Close()
+	ecr, err := NewEncryptedChecksummedReader(bytes.NewReader(buf.Bytes()), key, NewAESGCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecr.Seek(18, 0)
+	v := make([]byte, 4)
+	if _, err = io.ReadFull(ecr, v); err != nil || string(v) != "90gh" {
+		t.Fatalf("%#v %#v", string(v), err)
+	}
+	// The wrong key, tampering, and truncation at an interval boundary are
+	// all detected.
+	ecr, err = NewEncryptedChecksummedReader(bytes.NewReader(buf.Bytes()), []byte("0123456789abcdef0123456789abcdeF"), NewAESGCM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ecr.Read(v); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+	b := append([]byte{}, buf.Bytes()...)
+	b[encryptedHeaderLen+40] ^= 1
+	ecr, _ = NewEncryptedChecksummedReader(bytes.NewReader(b), key, NewAESGCM)
+	ecr.Seek(20, 0)
+	if _, err = ecr.Read(v); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+	b = buf.Bytes()[:encryptedHeaderLen+2*(16+16)]
+	ecr, _ = NewEncryptedChecksummedReader(bytes.NewReader(b), key, NewAESGCM)
+	ecr.Seek(20, 0)
+	if _, err = ecr.Read(v); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+}
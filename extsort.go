@@ -0,0 +1,216 @@
+package brimio
+
+import (
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// DefaultExternalSorterMemoryBudget is the default amount of Record content
+// an ExternalSorter will hold in memory before spilling a sorted run to disk.
+const DefaultExternalSorterMemoryBudget = 64 * 1024 * 1024
+
+// recordOverhead approximates the memory used by a Record beyond its Key and
+// Value content.
+const recordOverhead = 64
+
+// ExternalSorter sorts more Records than fit in memory by spilling sorted
+// runs to checksummed temporary segments and then merging them.
+//
+// Add each Record and then call Sort for a RecordIterator over them all in
+// order; Records with equal keys keep the order they were added in. Close
+// once done to remove the temporary segments.
+type ExternalSorter struct {
+	// MemoryBudget is about how much Record content to hold in memory before
+	// spilling a run; each of the Workers can be writing a run of this size
+	// at the same time, in addition to the run being added to.
+	MemoryBudget int64
+	// Workers is how many runs can be sorted and spilled in parallel.
+	Workers int
+	// FileFactory creates the temporary segments; DefaultFileFactory if nil.
+	FileFactory FileFactory
+	// Config is the checksum layout of the temporary segments.
+	Config  ChecksummedConfig
+	compare func(a []byte, b []byte) int
+	run     []Record
+	runSize int64
+	files   []*os.File
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	lock    sync.Mutex
+	err     error
+}
+
+// NewExternalSorter returns an ExternalSorter ordering keys by compare, such
+// as bytes.Compare.
+func NewExternalSorter(compare func(a []byte, b []byte) int) *ExternalSorter {
+	return &ExternalSorter{
+		MemoryBudget: DefaultExternalSorterMemoryBudget,
+		Workers:      1,
+		Config:       ChecksummedConfig{Interval: 65536, NewHash: func() hash.Hash { return crc32.NewIEEE() }},
+		compare:      compare,
+	}
+}
+
+// Add adds a copy of the Record given to those to be sorted. An error from
+// spilling an earlier run may be returned.
+func (es *ExternalSorter) Add(r Record) error {
+	if err := es.error(); err != nil {
+		return err
+	}
+	b := make([]byte, len(r.Key)+len(r.Value))
+	copy(b, r.Key)
+	copy(b[len(r.Key):], r.Value)
+	r.Key = b[:len(r.Key):len(r.Key)]
+	if !r.Tombstone {
+		r.Value = b[len(r.Key):]
+	}
+	es.run = append(es.run, r)
+	es.runSize += int64(len(b)) + recordOverhead
+	if es.runSize >= es.MemoryBudget {
+		es.spill()
+	}
+	return nil
+}
+
+func (es *ExternalSorter) error() error {
+	es.lock.Lock()
+	defer es.lock.Unlock()
+	return es.err
+}
+
+// spill hands the current run to a worker to sort and write to a new
+// temporary segment.
+func (es *ExternalSorter) spill() {
+	if es.sem == nil {
+		workers := es.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		es.sem = make(chan struct{}, workers)
+	}
+	run := es.run
+	es.run = nil
+	es.runSize = 0
+	f, err := es.fileFactory().CreateTemp("brimio-sort")
+	es.lock.Lock()
+	if err != nil {
+		if es.err == nil {
+			es.err = err
+		}
+		es.lock.Unlock()
+		return
+	}
+	// Files are kept in run order, so merging keeps equal keys in the order
+	// they were added.
+	es.files = append(es.files, f)
+	es.lock.Unlock()
+	es.sem <- struct{}{}
+	es.wg.Add(1)
+	go func() {
+		defer func() {
+			<-es.sem
+			es.wg.Done()
+		}()
+		es.sortRun(run)
+		// Padding the final interval means it is checksummed like the rest
+		// rather than read back unverified.
+		cw, err := NewChecksummedWriterWith(f, es.spillOptions()...)
+		if err == nil {
+			rw := NewRecordWriter(cw)
+			for _, r := range run {
+				if err = rw.WriteRecord(r); err != nil {
+					break
+				}
+			}
+			if err2 := rw.CloseWithError(err); err == nil {
+				err = err2
+			}
+		}
+		if err != nil {
+			es.lock.Lock()
+			if es.err == nil {
+				es.err = err
+			}
+			es.lock.Unlock()
+		}
+	}()
+}
+
+func (es *ExternalSorter) sortRun(run []Record) {
+	sort.SliceStable(run, func(i int, j int) bool {
+		return es.compare(run[i].Key, run[j].Key) < 0
+	})
+}
+
+// Sort returns a RecordIterator over all the Records added, in order,
+// verifying the temporary segments as they are read back. No more Records
+// should be added afterward.
+func (es *ExternalSorter) Sort() (RecordIterator, error) {
+	var iterators []RecordIterator
+	if len(es.files) > 0 && len(es.run) > 0 {
+		es.spill()
+	}
+	es.wg.Wait()
+	if err := es.error(); err != nil {
+		return nil, err
+	}
+	if len(es.files) == 0 {
+		es.sortRun(es.run)
+		rs := recordSliceIterator(es.run)
+		return &rs, nil
+	}
+	for _, f := range es.files {
+		if _, err := f.Seek(0, 0); err != nil {
+			return nil, err
+		}
+		cr, err := NewChecksummedReaderWith(f, append(es.spillOptions(), WithAutoVerify())...)
+		if err != nil {
+			return nil, err
+		}
+		iterators = append(iterators, NewRecordReader(cr))
+	}
+	return NewMergeIterator(iterators, es.compare), nil
+}
+
+// spillOptions returns the options temporary segments are written and read
+// back with.
+func (es *ExternalSorter) spillOptions() []ChecksummedOption {
+	return []ChecksummedOption{WithInterval(es.Config.Interval), WithHash(es.Config.NewHash), WithFooter(), WithPadFinal(), WithKeepDelegateOpen()}
+}
+
+func (es *ExternalSorter) fileFactory() FileFactory {
+	if es.FileFactory == nil {
+		return DefaultFileFactory
+	}
+	return es.FileFactory
+}
+
+// Close removes any temporary segments.
+func (es *ExternalSorter) Close() error {
+	es.wg.Wait()
+	var err error
+	for _, f := range es.files {
+		if err2 := es.fileFactory().RemoveTemp(f); err2 != nil && err == nil {
+			err = err2
+		}
+	}
+	es.files = nil
+	es.run = nil
+	return err
+}
+
+// recordSliceIterator is a RecordIterator over Records in memory.
+type recordSliceIterator []Record
+
+func (rsi *recordSliceIterator) Next() (Record, error) {
+	if len(*rsi) == 0 {
+		return Record{}, io.EOF
+	}
+	r := (*rsi)[0]
+	*rsi = (*rsi)[1:]
+	return r, nil
+}
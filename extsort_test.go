@@ -0,0 +1,117 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExternalSorter(t *testing.T) {
+	for _, spill := range []bool{false, true} {
+		dir, err := ioutil.TempDir("", "brimio")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		es := NewExternalSorter(bytes.Compare)
+		es.FileFactory = &DirFileFactory{Dirs: []string{dir}}
+		if spill {
+			es.MemoryBudget = 1000
+			es.Workers = 3
+		}
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < 500; i++ {
+			k := fmt.Sprintf("%05d", rnd.Intn(300))
+			if err = es.Add(Record{Key: []byte(k), Value: []byte(fmt.Sprint(i))}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		it, err := es.Sort()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last Record
+		var count int
+		for {
+			r, err := it.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count > 0 {
+				c := bytes.Compare(last.Key, r.Key)
+				var a, b int
+				fmt.Sscan(string(last.Value), &a)
+				fmt.Sscan(string(r.Value), &b)
+				// Equal keys keep the order they were added in.
+				if c > 0 || c == 0 && a > b {
+					t.Fatal(spill, string(last.Key), string(last.Value), string(r.Key), string(r.Value))
+				}
+			}
+			last = Record{Key: append([]byte{}, r.Key...), Value: append([]byte{}, r.Value...)}
+			count++
+		}
+		if count != 500 {
+			t.Fatal(spill, count)
+		}
+		files, _ := filepath.Glob(filepath.Join(dir, "*"))
+		if spill != (len(files) > 1) {
+			t.Fatal(spill, len(files))
+		}
+		if err = es.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if files, _ = filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+			t.Fatal(files)
+		}
+	}
+}
+
+func TestExternalSorterCorruptSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	es := NewExternalSorter(bytes.Compare)
+	es.FileFactory = &DirFileFactory{Dirs: []string{dir}}
+	es.MemoryBudget = 1000
+	for i := 0; i < 50; i++ {
+		if err = es.Add(Record{Key: []byte(fmt.Sprintf("%05d", i)), Value: []byte("value")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer es.Close()
+	es.wg.Wait()
+	if len(es.files) == 0 {
+		t.Fatal("expected a spilled run")
+	}
+	// Each run is far shorter than the default interval, so all of it would
+	// otherwise be a trailing partial interval.
+	b := []byte{0}
+	es.files[0].ReadAt(b, 10)
+	b[0] ^= 1
+	if _, err = es.files[0].WriteAt(b, 10); err != nil {
+		t.Fatal(err)
+	}
+	it, err := es.Sort()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, err = it.Next(); err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+}
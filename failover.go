@@ -0,0 +1,154 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverMaxErrorRate is the error rate above which a FailoverReader
+// will demote a source.
+const DefaultFailoverMaxErrorRate = 0.5
+
+// DefaultFailoverProbeInterval is how long a FailoverReader will wait before
+// probing a demoted source for recovery.
+const DefaultFailoverProbeInterval = 10 * time.Second
+
+// failoverWeight is how much each new outcome counts toward a source's error
+// rate and latency averages.
+const failoverWeight = 0.2
+
+// ErrNoFailoverSources is returned by a FailoverReader that has no sources to
+// read from.
+var ErrNoFailoverSources = fmt.Errorf("no failover sources")
+
+// FailoverReader implements io.ReaderAt over an ordered list of replicas,
+// such as copies of a segment on different disks or hosts, reading from the
+// first healthy source and failing over to the next on error.
+//
+// Each source's error rate and latency are tracked as moving averages. A
+// source whose error rate exceeds MaxErrorRate is demoted, only being tried
+// after all healthy sources have failed, except that once every
+// ProbeInterval a single read is sent to it in its normal turn to probe for
+// recovery; it is promoted again once enough reads succeed to bring its error
+// rate back down.
+type FailoverReader struct {
+	// MaxErrorRate, from 0 to 1, is the error rate above which a source is
+	// demoted.
+	MaxErrorRate float64
+	// ProbeInterval is how long to wait before probing a demoted source.
+	ProbeInterval time.Duration
+	// Clock is the source of time for latencies and probes; SystemClock if
+	// nil.
+	Clock   Clock
+	lock    sync.Mutex
+	sources []*failoverSource
+}
+
+type failoverSource struct {
+	delegate  io.ReaderAt
+	errorRate float64
+	latency   time.Duration
+	demoted   bool
+	nextProbe time.Time
+}
+
+// FailoverSourceHealth describes a FailoverReader source's health as tracked
+// so far.
+type FailoverSourceHealth struct {
+	ErrorRate float64
+	Latency   time.Duration
+	Demoted   bool
+}
+
+// NewFailoverReader returns a FailoverReader over the sources given, in order
+// of preference.
+func NewFailoverReader(sources []io.ReaderAt) *FailoverReader {
+	fr := &FailoverReader{
+		MaxErrorRate:  DefaultFailoverMaxErrorRate,
+		ProbeInterval: DefaultFailoverProbeInterval,
+		sources:       make([]*failoverSource, len(sources)),
+	}
+	for i, s := range sources {
+		fr.sources[i] = &failoverSource{delegate: s}
+	}
+	return fr
+}
+
+// ReadAt implements the io.ReaderAt interface. A short read with io.EOF is
+// taken as the end of the content rather than a failure of the source. If
+// every source fails, the last source's error is returned.
+func (fr *FailoverReader) ReadAt(v []byte, off int64) (int, error) {
+	if len(fr.sources) == 0 {
+		return 0, ErrNoFailoverSources
+	}
+	var n int
+	var err error
+	for _, s := range fr.order() {
+		start := clockOrSystem(fr.Clock).Now()
+		n, err = s.delegate.ReadAt(v, off)
+		fr.record(s, err == nil || err == io.EOF, clockOrSystem(fr.Clock).Now().Sub(start))
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// order returns the sources to try: healthy ones, and demoted ones due a
+// probe, in their given order followed by the other demoted ones as a last
+// resort.
+func (fr *FailoverReader) order() []*failoverSource {
+	now := clockOrSystem(fr.Clock).Now()
+	order := make([]*failoverSource, 0, len(fr.sources))
+	var demoted []*failoverSource
+	fr.lock.Lock()
+	for _, s := range fr.sources {
+		if !s.demoted {
+			order = append(order, s)
+		} else if !now.Before(s.nextProbe) {
+			s.nextProbe = now.Add(fr.ProbeInterval)
+			order = append(order, s)
+		} else {
+			demoted = append(demoted, s)
+		}
+	}
+	fr.lock.Unlock()
+	return append(order, demoted...)
+}
+
+func (fr *FailoverReader) record(s *failoverSource, ok bool, latency time.Duration) {
+	fr.lock.Lock()
+	var e float64
+	if !ok {
+		e = 1
+	}
+	s.errorRate += (e - s.errorRate) * failoverWeight
+	if ok {
+		if s.latency == 0 {
+			s.latency = latency
+		} else {
+			s.latency += time.Duration(float64(latency-s.latency) * failoverWeight)
+		}
+	}
+	if !s.demoted && s.errorRate > fr.MaxErrorRate {
+		s.demoted = true
+		s.nextProbe = clockOrSystem(fr.Clock).Now().Add(fr.ProbeInterval)
+	} else if s.demoted && s.errorRate <= fr.MaxErrorRate {
+		s.demoted = false
+	}
+	fr.lock.Unlock()
+}
+
+// Health returns the FailoverSourceHealth of each source, in their given
+// order.
+func (fr *FailoverReader) Health() []FailoverSourceHealth {
+	fr.lock.Lock()
+	defer fr.lock.Unlock()
+	h := make([]FailoverSourceHealth, len(fr.sources))
+	for i, s := range fr.sources {
+		h[i] = FailoverSourceHealth{ErrorRate: s.errorRate, Latency: s.latency, Demoted: s.demoted}
+	}
+	return h
+}
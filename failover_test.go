@@ -0,0 +1,78 @@
+package brimio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+)
+
+type failingReaderAt struct {
+	delegate io.ReaderAt
+	fail     bool
+	reads    int
+}
+
+func (f *failingReaderAt) ReadAt(v []byte, off int64) (int, error) {
+	f.reads++
+	if f.fail {
+		return 0, fmt.Errorf("failing")
+	}
+	return f.delegate.ReadAt(v, off)
+}
+
+func TestFailoverReader(t *testing.T) {
+	content := []byte("12345678901234567890")
+	a := &failingReaderAt{delegate: bytes.NewReader(content), fail: true}
+	b := &failingReaderAt{delegate: bytes.NewReader(content)}
+	fr := NewFailoverReader([]io.ReaderAt{a, b})
+	fr.ProbeInterval = time.Hour
+	clock := NewManualClock(time.Unix(1000, 0))
+	fr.Clock = clock
+	v := make([]byte, 5)
+	for i := 0; i < 10; i++ {
+		n, err := fr.ReadAt(v, 3)
+		if err != nil || n != 5 || string(v) != "45678" {
+			t.Fatal(i, n, err, string(v))
+		}
+	}
+	// The first source should have been demoted after a few failures and
+	// not tried since, with no probes due for an hour.
+	if a.reads >= 10 || b.reads != 10 {
+		t.Fatal(a.reads, b.reads)
+	}
+	h := fr.Health()
+	if !h[0].Demoted || h[1].Demoted || h[1].ErrorRate != 0 {
+		t.Fatalf("%#v", h)
+	}
+	// Reading past the end is not a failure.
+	n, err := fr.ReadAt(v, 18)
+	if err != io.EOF || n != 2 {
+		t.Fatal(n, err)
+	}
+	if fr.Health()[1].Demoted {
+		t.Fatal()
+	}
+	// Probes go to the recovered first source until it is promoted again.
+	a.fail = false
+	fr.ProbeInterval = 0
+	clock.Advance(time.Hour)
+	for i := 0; i < 10; i++ {
+		if _, err = fr.ReadAt(v, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fr.Health()[0].Demoted {
+		t.Fatalf("%#v", fr.Health())
+	}
+	// With every source failing, the error comes through.
+	a.fail = true
+	b.fail = true
+	if _, err = fr.ReadAt(v, 0); err == nil || err.Error() != "failing" {
+		t.Fatal(err)
+	}
+	if _, err = NewFailoverReader(nil).ReadAt(v, 0); err != ErrNoFailoverSources {
+		t.Fatal(err)
+	}
+}
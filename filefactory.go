@@ -0,0 +1,144 @@
+package brimio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileFactory controls where and how the package's components, such as
+// ExternalSorter, create their files, so deployments can apply their own
+// placement rules.
+type FileFactory interface {
+	// CreateTemp creates a new temporary file open for reading and writing,
+	// with pattern as with ioutil.TempFile. It should be removed with
+	// RemoveTemp once done.
+	CreateTemp(pattern string) (*os.File, error)
+	// RemoveTemp closes and removes a file created by CreateTemp.
+	RemoveTemp(f *os.File) error
+	// Create creates a new file named name, open for reading and writing,
+	// failing if it already exists. Relative names are placed as the
+	// FileFactory chooses; f.Name() gives the resulting path.
+	Create(name string) (*os.File, error)
+}
+
+// DefaultFileFactory is the FileFactory used by components that aren't given
+// one. It can be replaced to change the default for the whole process.
+var DefaultFileFactory FileFactory = &DirFileFactory{}
+
+// DirFileFactory is a FileFactory that places files in Dirs, taking each in
+// turn, such as to spread temporary files across disks.
+type DirFileFactory struct {
+	// Dirs are the directories to create files in; if empty, temporary files
+	// go in the system default and other files in the current directory.
+	Dirs []string
+	// Perm is the permission bits of created files, exactly, regardless of
+	// the umask; 0600 if 0.
+	Perm os.FileMode
+	// Owner, if not nil, is given ownership of created files, such as when
+	// running as root on behalf of a service account.
+	Owner *FileOwner
+	// PreferTmpfile has temporary files created unnamed with O_TMPFILE where
+	// supported (Linux), so they disappear if the process dies; otherwise it
+	// falls back to named files.
+	PreferTmpfile bool
+	lock          sync.Mutex
+	next          int
+	unnamed       map[*os.File]bool
+}
+
+func (dff *DirFileFactory) dir() string {
+	dff.lock.Lock()
+	defer dff.lock.Unlock()
+	if len(dff.Dirs) == 0 {
+		return ""
+	}
+	d := dff.Dirs[dff.next%len(dff.Dirs)]
+	dff.next++
+	return d
+}
+
+func (dff *DirFileFactory) perm() os.FileMode {
+	if dff.Perm == 0 {
+		return 0600
+	}
+	return dff.Perm
+}
+
+// CreateTemp implements the FileFactory interface.
+func (dff *DirFileFactory) CreateTemp(pattern string) (*os.File, error) {
+	dir := dff.dir()
+	if dff.PreferTmpfile {
+		d := dir
+		if d == "" {
+			d = os.TempDir()
+		}
+		if f, err := openTmpfile(d, dff.perm()); err == nil {
+			if err = dff.setup(f); err != nil {
+				f.Close()
+				return nil, err
+			}
+			dff.lock.Lock()
+			if dff.unnamed == nil {
+				dff.unnamed = make(map[*os.File]bool)
+			}
+			dff.unnamed[f] = true
+			dff.lock.Unlock()
+			return f, nil
+		}
+	}
+	f, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if err = dff.setup(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// setup gives a newly created file the configured permissions and owner.
+func (dff *DirFileFactory) setup(f *os.File) error {
+	if err := EnsureMode(f, dff.perm()); err != nil {
+		return err
+	}
+	if dff.Owner != nil {
+		return SetOwnership(f, dff.Owner.UID, dff.Owner.GID)
+	}
+	return nil
+}
+
+// RemoveTemp implements the FileFactory interface.
+func (dff *DirFileFactory) RemoveTemp(f *os.File) error {
+	dff.lock.Lock()
+	unnamed := dff.unnamed[f]
+	delete(dff.unnamed, f)
+	dff.lock.Unlock()
+	err := f.Close()
+	if !unnamed {
+		if err2 := os.Remove(f.Name()); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+// Create implements the FileFactory interface.
+func (dff *DirFileFactory) Create(name string) (*os.File, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dff.dir(), name)
+	}
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, dff.perm())
+	if err != nil {
+		return nil, err
+	}
+	if err = dff.setup(f); err != nil {
+		f.Close()
+		os.Remove(name)
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,20 @@
+package brimio
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// oTmpfile is O_TMPFILE, which the syscall package doesn't define for most
+// architectures.
+const oTmpfile = 0x400000 | syscall.O_DIRECTORY
+
+// openTmpfile opens an unnamed file within dir that disappears once closed.
+func openTmpfile(dir string, perm os.FileMode) (*os.File, error) {
+	fd, err := syscall.Open(dir, oTmpfile|syscall.O_RDWR|syscall.O_CLOEXEC, uint32(perm))
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), filepath.Join(dir, "(unnamed)")), nil
+}
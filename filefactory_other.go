@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package brimio
+
+import (
+	"fmt"
+	"os"
+)
+
+func openTmpfile(dir string, perm os.FileMode) (*os.File, error) {
+	return nil, fmt.Errorf("O_TMPFILE not supported")
+}
@@ -0,0 +1,65 @@
+package brimio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirFileFactory(t *testing.T) {
+	var dirs []string
+	for i := 0; i < 2; i++ {
+		dir, err := ioutil.TempDir("", "brimio")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		dirs = append(dirs, dir)
+	}
+	for _, tmpfile := range []bool{false, true} {
+		// Group write is usually masked off by the umask unless the mode is
+		// set explicitly afterward.
+		dff := &DirFileFactory{Dirs: dirs, Perm: 0660, PreferTmpfile: tmpfile}
+		f1, err := dff.CreateTemp("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f2, err := dff.CreateTemp("test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f1.Write([]byte("abc")); err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Dir(f1.Name()) != dirs[0] || filepath.Dir(f2.Name()) != dirs[1] {
+			t.Fatal(f1.Name(), f2.Name())
+		}
+		if fi, err := f1.Stat(); err != nil || fi.Mode().Perm() != 0660 {
+			t.Fatal(tmpfile, fi.Mode(), err)
+		}
+		if err = dff.RemoveTemp(f1); err != nil {
+			t.Fatal(err)
+		}
+		if err = dff.RemoveTemp(f2); err != nil {
+			t.Fatal(err)
+		}
+		for _, dir := range dirs {
+			if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+				t.Fatal(tmpfile, files)
+			}
+		}
+	}
+	dff := &DirFileFactory{Dirs: dirs}
+	f, err := dff.Create("segment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if f.Name() != filepath.Join(dirs[0], "segment") {
+		t.Fatal(f.Name())
+	}
+	if _, err = os.Stat(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+}
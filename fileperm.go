@@ -0,0 +1,31 @@
+package brimio
+
+import "os"
+
+// FileOwner is the user and group owning a file, by numeric ID as with
+// os.Chown; -1 leaves either unchanged.
+type FileOwner struct {
+	UID int
+	GID int
+}
+
+// EnsureMode sets the permission bits of f to perm if they differ, exactly
+// and regardless of the umask. On Windows only the owner write bit is
+// meaningful, setting or clearing the read-only attribute.
+func EnsureMode(f *os.File, perm os.FileMode) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if modeMatches(fi.Mode().Perm(), perm.Perm()) {
+		return nil
+	}
+	return f.Chmod(perm.Perm())
+}
+
+// SetOwnership sets the owner of f to uid and gid if they differ, -1
+// leaving either unchanged. On Windows, where ownership is a matter of ACLs
+// inherited from the directory, it does nothing.
+func SetOwnership(f *os.File, uid int, gid int) error {
+	return setOwnership(f, uid, gid)
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package brimio
+
+import "os"
+
+func modeMatches(current os.FileMode, perm os.FileMode) bool {
+	return current == perm
+}
+
+func setOwnership(f *os.File, uid int, gid int) error {
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+	return f.Chown(uid, gid)
+}
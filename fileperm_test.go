@@ -0,0 +1,49 @@
+package brimio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestEnsureMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported")
+	}
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	for _, perm := range []os.FileMode{0666, 0640, 0640} {
+		if err = EnsureMode(f, perm); err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := f.Stat(); err != nil || fi.Mode().Perm() != perm {
+			t.Fatal(fi, err)
+		}
+	}
+	if err = SetOwnership(f, -1, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err = SetOwnership(f, os.Getuid(), os.Getgid()); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dff := &DirFileFactory{Dirs: []string{dir}, Perm: 0666, Owner: &FileOwner{UID: os.Getuid(), GID: os.Getgid()}}
+	f2, err := dff.Create("segment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2.Close()
+	if fi, err := os.Stat(filepath.Join(dir, "segment")); err != nil || fi.Mode().Perm() != 0666 {
+		t.Fatal(fi, err)
+	}
+}
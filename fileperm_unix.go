@@ -0,0 +1,27 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"os"
+	"syscall"
+)
+
+func modeMatches(current os.FileMode, perm os.FileMode) bool {
+	return current == perm
+}
+
+func setOwnership(f *os.File, uid int, gid int) error {
+	if uid < 0 && gid < 0 {
+		return nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && (uid < 0 || int(st.Uid) == uid) && (gid < 0 || int(st.Gid) == gid) {
+		return nil
+	}
+	return f.Chown(uid, gid)
+}
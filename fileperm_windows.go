@@ -0,0 +1,11 @@
+package brimio
+
+import "os"
+
+func modeMatches(current os.FileMode, perm os.FileMode) bool {
+	return current&0200 == perm&0200
+}
+
+func setOwnership(f *os.File, uid int, gid int) error {
+	return nil
+}
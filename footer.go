@@ -0,0 +1,340 @@
+package brimio
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// ChecksummedFooterMagic ends every checksummed footer.
+const ChecksummedFooterMagic = "BRIMIOFT"
+
+// ChecksummedFooterLen is the serialized length of a ChecksummedFooter.
+const ChecksummedFooterLen = 8 + sha256.Size + 4 + len(ChecksummedFooterMagic)
+
+// ErrNoChecksummedFooter is returned when content expected to end with a
+// ChecksummedFooter does not, such as when it was cut short.
+var ErrNoChecksummedFooter = fmt.Errorf("not a checksummed footer")
+
+// ErrChecksummedFooterDigest is returned by VerifyChecksummedFooter when the
+// content does not match the digest its footer records.
+var ErrChecksummedFooterDigest = fmt.Errorf("checksummed footer digest mismatch")
+
+// ChecksummedFooter, written at Close by a ChecksummedWriter given
+// WithFooter, records the length and digest of all the content, so a reader
+// can cheaply check that checksummed content is complete, not truncated,
+// before trusting its per interval checksums.
+//
+// Serialized it is, big endian, an 8 byte Length, the 32 byte SHA-256 Digest,
+// a 4 byte CRC32 IEEE of those, and finally the ChecksummedFooterMagic.
+type ChecksummedFooter struct {
+	// Length is the length of the content, not including checksums.
+	Length int64
+	// Digest is the SHA-256 of the content.
+	Digest [sha256.Size]byte
+}
+
+// WriteChecksummedFooter writes the serialized footer to w.
+func WriteChecksummedFooter(w io.Writer, f ChecksummedFooter) error {
+	b := make([]byte, 8, ChecksummedFooterLen)
+	binary.BigEndian.PutUint64(b, uint64(f.Length))
+	b = append(b, f.Digest[:]...)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+	b = append(b, ChecksummedFooterMagic...)
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadChecksummedFooter reads the serialized footer at the end of r,
+// returning ErrNoChecksummedFooter if there is none, including if r is too
+// short to hold one. The position of r is restored afterward.
+func ReadChecksummedFooter(r io.ReadSeeker) (ChecksummedFooter, error) {
+	var f ChecksummedFooter
+	o, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return f, err
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return f, err
+	}
+	if end < int64(ChecksummedFooterLen) {
+		r.Seek(o, io.SeekStart)
+		return f, ErrNoChecksummedFooter
+	}
+	b := make([]byte, ChecksummedFooterLen)
+	if _, err = r.Seek(end-int64(len(b)), io.SeekStart); err == nil {
+		_, err = io.ReadFull(r, b)
+	}
+	if _, err2 := r.Seek(o, io.SeekStart); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return f, err
+	}
+	p := len(b) - len(ChecksummedFooterMagic)
+	if !bytes.Equal(b[p:], []byte(ChecksummedFooterMagic)) || binary.BigEndian.Uint32(b[p-4:]) != crc32.ChecksumIEEE(b[:p-4]) {
+		return f, ErrNoChecksummedFooter
+	}
+	f.Length = int64(binary.BigEndian.Uint64(b))
+	copy(f.Digest[:], b[8:])
+	return f, nil
+}
+
+// VerifyChecksummedFooter reads all the checksummed content of r, which
+// must end with a ChecksummedFooter, through a ChecksummedReader given opts
+// along with WithFooter and WithAutoVerify, and checks that the content is
+// the length and SHA-256 digest the footer records. Opening a reader
+// WithFooter only checks the length; this is the full pass that also catches
+// content replaced or corrupted along with its checksums.
+//
+// ErrChecksummedFooterDigest is returned if the digest differs, or the error
+// of the first interval that fails to verify, ErrChecksumMismatch included.
+// The position of r is left at the end of the content read.
+func VerifyChecksummedFooter(r io.ReadSeeker, opts ...ChecksummedOption) error {
+	cr, err := NewChecksummedReaderWith(r, append(opts[:len(opts):len(opts)], WithFooter(), WithAutoVerify())...)
+	if err != nil {
+		return err
+	}
+	f, err := ReadChecksummedFooter(r)
+	if err != nil {
+		return err
+	}
+	if _, err = cr.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, cr)
+	if err != nil {
+		return err
+	}
+	var digest [sha256.Size]byte
+	h.Sum(digest[:0])
+	if n != f.Length || digest != f.Digest {
+		return ErrChecksummedFooterDigest
+	}
+	return nil
+}
+
+// footerChecksummedWriter is a ChecksummedWriter that digests the content
+// written and ends with a ChecksummedFooter once closed, for WithFooter.
+type footerChecksummedWriter struct {
+	ChecksummedWriter
+	delegate io.Writer
+	digest   *footerDigest
+	// padInterval, if set by WithPadFinal, is the interval any final
+	// partial one is zero padded to at Close.
+	padInterval int
+}
+
+// footerDigest is the running length and digest of the content written.
+type footerDigest struct {
+	hash   hash.Hash
+	length int64
+}
+
+func (fd *footerDigest) Write(v []byte) (int, error) {
+	fd.hash.Write(v)
+	fd.length += int64(len(v))
+	return len(v), nil
+}
+
+func newFooterChecksummedWriter(cw ChecksummedWriter, delegate io.Writer) *footerChecksummedWriter {
+	return &footerChecksummedWriter{ChecksummedWriter: cw, delegate: delegate, digest: &footerDigest{hash: sha256.New()}}
+}
+
+func (fcw *footerChecksummedWriter) Write(v []byte) (int, error) {
+	n, err := fcw.ChecksummedWriter.Write(v)
+	fcw.digest.Write(v[:n])
+	return n, err
+}
+
+func (fcw *footerChecksummedWriter) WriteContext(ctx context.Context, v []byte) (int, error) {
+	n, err := fcw.ChecksummedWriter.WriteContext(ctx, v)
+	fcw.digest.Write(v[:n])
+	return n, err
+}
+
+func (fcw *footerChecksummedWriter) ReadFrom(r io.Reader) (int64, error) {
+	return fcw.ChecksummedWriter.ReadFrom(io.TeeReader(r, fcw.digest))
+}
+
+func (fcw *footerChecksummedWriter) Close() error {
+	return fcw.CloseWithError(nil)
+}
+
+// CloseWithError only writes the footer when err is nil; content closed
+// with an error is left without one, marking it incomplete.
+func (fcw *footerChecksummedWriter) CloseWithError(err error) error {
+	if err != nil {
+		return fcw.ChecksummedWriter.CloseWithError(err)
+	}
+	// The delegate is closed with any error from writing the last of the
+	// content or the footer, where it can take one, and that error is kept
+	// even if closing succeeds, so a footerless file is never reported as
+	// complete.
+	err = fcw.CloseWithoutDelegate()
+	if err2 := closeDelegate(fcw.delegate, err); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (fcw *footerChecksummedWriter) CloseWithoutDelegate() error {
+	if fcw.padInterval > 0 {
+		if partial := int(fcw.digest.length % int64(fcw.padInterval)); partial > 0 {
+			// The padding is not part of the content, so not digested.
+			if _, err := fcw.ChecksummedWriter.Write(make([]byte, fcw.padInterval-partial)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := fcw.ChecksummedWriter.CloseWithoutDelegate(); err != nil {
+		return err
+	}
+	f := ChecksummedFooter{Length: fcw.digest.length}
+	fcw.digest.hash.Sum(f.Digest[:0])
+	return WriteChecksummedFooter(fcw.delegate, f)
+}
+
+// footerReadSeeker presents the content of delegate without the footer
+// ending it.
+type footerReadSeeker struct {
+	delegate io.ReadSeeker
+	size     int64
+	pos      int64
+}
+
+// newFooterReadSeeker reads the footer ending delegate and returns it along
+// with a footerReadSeeker hiding it. ErrNoChecksummedFooter is returned if
+// there is no footer or the checksummed content before it is not the length
+// the footer records, rounded up to whole intervals if padded.
+func newFooterReadSeeker(delegate io.ReadSeeker, interval int, checksumSize int, padded bool) (*footerReadSeeker, ChecksummedFooter, error) {
+	f, err := ReadChecksummedFooter(delegate)
+	if err != nil {
+		return nil, f, err
+	}
+	end, err := delegate.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, f, err
+	}
+	size := end - int64(ChecksummedFooterLen)
+	length := f.Length
+	if padded && length%int64(interval) != 0 {
+		length += int64(interval) - length%int64(interval)
+	}
+	if length < 0 || PhysicalSize(length, interval, checksumSize) != size {
+		return nil, f, ErrNoChecksummedFooter
+	}
+	pos, err := delegate.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, f, err
+	}
+	return &footerReadSeeker{delegate: delegate, size: size, pos: pos}, f, nil
+}
+
+func (frs *footerReadSeeker) Read(v []byte) (int, error) {
+	if frs.pos >= frs.size {
+		return 0, io.EOF
+	}
+	if int64(len(v)) > frs.size-frs.pos {
+		v = v[:frs.size-frs.pos]
+	}
+	n, err := frs.delegate.Read(v)
+	frs.pos += int64(n)
+	return n, err
+}
+
+func (frs *footerReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		offset += frs.size
+		whence = io.SeekStart
+	}
+	o, err := frs.delegate.Seek(offset, whence)
+	frs.pos = o
+	return o, err
+}
+
+func (frs *footerReadSeeker) Close() error {
+	if c, ok := frs.delegate.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (frs *footerReadSeeker) Unwrap() interface{} {
+	return unwrapDelegate(frs.delegate)
+}
+
+// paddedChecksummedReader is a ChecksummedReader whose content ends at
+// length, hiding the padding WithPadFinal adds after it.
+type paddedChecksummedReader struct {
+	ChecksummedReader
+	length int64
+}
+
+// limit cuts v short to end at length, given the current position, or
+// returns io.EOF if already there.
+func (pcr *paddedChecksummedReader) limit(v []byte) ([]byte, error) {
+	o, err := pcr.ChecksummedReader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if o >= pcr.length {
+		return nil, io.EOF
+	}
+	if int64(len(v)) > pcr.length-o {
+		v = v[:pcr.length-o]
+	}
+	return v, nil
+}
+
+func (pcr *paddedChecksummedReader) Read(v []byte) (int, error) {
+	v, err := pcr.limit(v)
+	if err != nil {
+		return 0, err
+	}
+	return pcr.ChecksummedReader.Read(v)
+}
+
+func (pcr *paddedChecksummedReader) ReadByte() (byte, error) {
+	if _, err := pcr.limit(nil); err != nil {
+		return 0, err
+	}
+	return pcr.ChecksummedReader.ReadByte()
+}
+
+func (pcr *paddedChecksummedReader) ReadContext(ctx context.Context, v []byte) (int, error) {
+	v, err := pcr.limit(v)
+	if err != nil {
+		return 0, err
+	}
+	return pcr.ChecksummedReader.ReadContext(ctx, v)
+}
+
+func (pcr *paddedChecksummedReader) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		offset += pcr.length
+		whence = io.SeekStart
+	}
+	return pcr.ChecksummedReader.Seek(offset, whence)
+}
+
+// VerifyDetail leaves the padding out of the Range returned.
+func (pcr *paddedChecksummedReader) VerifyDetail() (bool, Range, error) {
+	verified, r, err := pcr.ChecksummedReader.VerifyDetail()
+	if r.Offset+r.Length > pcr.length {
+		r.Length = pcr.length - r.Offset
+	}
+	return verified, r, err
+}
+
+func (pcr *paddedChecksummedReader) Size() (int64, error) {
+	return pcr.length, nil
+}
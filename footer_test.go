@@ -0,0 +1,202 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestChecksummedFooter(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	buf := &bytes.Buffer{}
+	cw, err := NewChecksummedWriterWith(buf, WithInterval(16), WithFooter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write(content[:10])
+	if _, err = cw.ReadFrom(bytes.NewReader(content[10:])); err != nil {
+		t.Fatal(err)
+	}
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if len(b) != 48+ChecksummedFooterLen {
+		t.Fatal(len(b))
+	}
+	f, err := ReadChecksummedFooter(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Length != 40 || f.Digest != sha256.Sum256(content) {
+		t.Fatalf("%#v", f)
+	}
+	cr, err := NewChecksummedReaderWith(bytes.NewReader(b), WithInterval(16), WithFooter(), WithAutoVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := cr.Size(); err != nil || size != 40 {
+		t.Fatal(size, err)
+	}
+	if o, err := cr.Seek(-2, 2); err != nil || o != 38 {
+		t.Fatal(o, err)
+	}
+	cr.Seek(0, 0)
+	v, err := ioutil.ReadAll(cr)
+	if err != nil || !bytes.Equal(v, content) {
+		t.Fatal(string(v), err)
+	}
+	if corrupt, err := cr.VerifyAll(nil); err != nil || corrupt != nil {
+		t.Fatal(corrupt, err)
+	}
+	// Truncated content, with or without its footer, is caught up front.
+	for _, short := range [][]byte{b[:48], b[:20], append(append([]byte(nil), b[:20]...), b[48:]...)} {
+		if _, err = NewChecksummedReaderWith(bytes.NewReader(short), WithInterval(16), WithFooter()); !errors.Is(err, ErrNoChecksummedFooter) {
+			t.Fatal(len(short), err)
+		}
+	}
+	// Closing with an error leaves no footer.
+	buf.Reset()
+	cw, _ = NewChecksummedWriterWith(buf, WithInterval(16), WithFooter())
+	cw.Write(content)
+	cw.CloseWithError(errors.New("test"))
+	if _, err = ReadChecksummedFooter(bytes.NewReader(buf.Bytes())); err != ErrNoChecksummedFooter {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksummedPadFinal(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	buf := &bytes.Buffer{}
+	if _, err := NewChecksummedWriterWith(buf, WithInterval(16), WithPadFinal()); err == nil {
+		t.Fatal("expected error without footer")
+	}
+	cw, err := NewChecksummedWriterWith(buf, WithInterval(16), WithFooter(), WithPadFinal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write(content)
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	// Three whole intervals and their checksums.
+	if len(b) != 60+ChecksummedFooterLen {
+		t.Fatal(len(b))
+	}
+	f, err := ReadChecksummedFooter(bytes.NewReader(b))
+	if err != nil || f.Length != 40 || f.Digest != sha256.Sum256(content) {
+		t.Fatal(f, err)
+	}
+	cr, err := NewChecksummedReaderWith(bytes.NewReader(b), WithInterval(16), WithFooter(), WithPadFinal(), WithAutoVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupted, err := cr.VerifyAll(nil); err != nil || corrupted != nil {
+		t.Fatal(corrupted, err)
+	}
+	if size, err := cr.Size(); err != nil || size != 40 {
+		t.Fatal(size, err)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil || !bytes.Equal(v, content) {
+		t.Fatal(string(v), err)
+	}
+	if o, err := cr.Seek(-1, 2); err != nil || o != 39 {
+		t.Fatal(o, err)
+	}
+	if c, err := cr.ReadByte(); err != nil || c != 'z' {
+		t.Fatal(c, err)
+	}
+	if _, err := cr.ReadByte(); err != io.EOF {
+		t.Fatal(err)
+	}
+	// Without WithPadFinal the padded length doesn't match the footer's.
+	if _, err = NewChecksummedReaderWith(bytes.NewReader(b), WithInterval(16), WithFooter()); !errors.Is(err, ErrNoChecksummedFooter) {
+		t.Fatal(err)
+	}
+}
+
+// fullDisk accepts up to room bytes, failing writes after that, and records
+// whether it was closed.
+type fullDisk struct {
+	bytes.Buffer
+	room   int
+	closed bool
+}
+
+func (fd *fullDisk) Write(v []byte) (int, error) {
+	if fd.Len()+len(v) > fd.room {
+		return 0, errors.New("disk full")
+	}
+	return fd.Buffer.Write(v)
+}
+
+func (fd *fullDisk) Close() error {
+	fd.closed = true
+	return nil
+}
+
+func TestChecksummedFooterCloseError(t *testing.T) {
+	fd := &fullDisk{room: 48}
+	cw, err := NewChecksummedWriterWith(fd, WithInterval(16), WithFooter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz")); err != nil {
+		t.Fatal(err)
+	}
+	// The content fits but the footer does not.
+	if err = cw.Close(); err == nil || err.Error() != "disk full" {
+		t.Fatal(err)
+	}
+	if !fd.closed {
+		t.Fatal("delegate left open")
+	}
+}
+
+func TestVerifyChecksummedFooter(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	write := func(v []byte, opts ...ChecksummedOption) []byte {
+		buf := &bytes.Buffer{}
+		cw, err := NewChecksummedWriterWith(buf, append([]ChecksummedOption{WithInterval(16), WithFooter()}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cw.Write(v)
+		if err = cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	b := write(content)
+	if err := VerifyChecksummedFooter(bytes.NewReader(b), WithInterval(16)); err != nil {
+		t.Fatal(err)
+	}
+	padded := write(content, WithPadFinal())
+	if err := VerifyChecksummedFooter(bytes.NewReader(padded), WithInterval(16), WithPadFinal()); err != nil {
+		t.Fatal(err)
+	}
+	// Other content of the same length, with valid checksums, under the
+	// original footer passes the length check but not the digest.
+	other := write(bytes.ToUpper(content))
+	spliced := append(append([]byte(nil), other[:len(other)-ChecksummedFooterLen]...), b[len(b)-ChecksummedFooterLen:]...)
+	if _, err := NewChecksummedReaderWith(bytes.NewReader(spliced), WithInterval(16), WithFooter()); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksummedFooter(bytes.NewReader(spliced), WithInterval(16)); err != ErrChecksummedFooterDigest {
+		t.Fatal(err)
+	}
+	// A corrupted interval fails its checksum before the digest is checked.
+	corrupted := append([]byte(nil), b...)
+	corrupted[3]++
+	if err := VerifyChecksummedFooter(bytes.NewReader(corrupted), WithInterval(16)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksummedFooter(bytes.NewReader(b[:48]), WithInterval(16)); !errors.Is(err, ErrNoChecksummedFooter) {
+		t.Fatal(err)
+	}
+}
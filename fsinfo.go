@@ -0,0 +1,9 @@
+package brimio
+
+// FsInfo gives the size and free space, in bytes, of a file system.
+type FsInfo struct {
+	Total uint64
+	Free  uint64
+	// Available is the free space available to unprivileged users.
+	Available uint64
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package brimio
+
+import "fmt"
+
+// GetFsInfo returns the FsInfo of the file system containing path; not
+// supported on this platform.
+func GetFsInfo(path string) (FsInfo, error) {
+	return FsInfo{}, fmt.Errorf("GetFsInfo not supported")
+}
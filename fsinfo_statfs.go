@@ -0,0 +1,21 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package brimio
+
+import "syscall"
+
+// GetFsInfo returns the FsInfo of the file system containing path.
+func GetFsInfo(path string) (FsInfo, error) {
+	var s syscall.Statfs_t
+	if err := syscall.Statfs(path, &s); err != nil {
+		return FsInfo{}, err
+	}
+	bsize := uint64(s.Bsize)
+	fi := FsInfo{Total: uint64(s.Blocks) * bsize, Free: uint64(s.Bfree) * bsize}
+	// Some platforms report reserved space as negative availability.
+	if s.Bavail > 0 {
+		fi.Available = uint64(s.Bavail) * bsize
+	}
+	return fi, nil
+}
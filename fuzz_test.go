@@ -0,0 +1,112 @@
+//go:build go1.18
+// +build go1.18
+
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// FuzzChecksummedRoundTrip writes data through ChecksummedWriter and
+// MultiCoreChecksummedWriter using write sizes taken from ops, asserts both
+// produce identical content, and then asserts that reading straight through
+// with both the streaming and seekable readers, and reading after each of a
+// sequence of seeks taken from ops, all return the same content as was
+// written.
+func FuzzChecksummedRoundTrip(f *testing.F) {
+	f.Add([]byte("12345678901234567890ghijklmnopqrstuvwxyz"), uint8(16), []byte{3, 17, 0, 40, 22, 5})
+	f.Add([]byte("a"), uint8(1), []byte{1, 1})
+	f.Add([]byte{}, uint8(7), []byte{})
+	f.Fuzz(func(t *testing.T, data []byte, interval uint8, ops []byte) {
+		checksumInterval := int(interval%64) + 1
+		buf := &bytes.Buffer{}
+		buf2 := &bytes.Buffer{}
+		cw := NewChecksummedWriter(buf, checksumInterval, crc32.NewIEEE)
+		cw2 := NewMultiCoreChecksummedWriter(buf2, checksumInterval, crc32.NewIEEE, 2)
+		v := data
+		for i := 0; len(v) > 0; i++ {
+			n := len(v)
+			if len(ops) > 0 {
+				n = int(ops[i%len(ops)])%len(v) + 1
+			}
+			if _, err := cw.Write(v[:n]); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cw2.Write(v[:n]); err != nil {
+				t.Fatal(err)
+			}
+			v = v[n:]
+		}
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := cw2.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+			t.Fatalf("writers differ %#v %#v", buf.Bytes(), buf2.Bytes())
+		}
+		cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), checksumInterval, crc32.NewIEEE)
+		v, err := ioutil.ReadAll(cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v, data) {
+			t.Fatalf("read %#v expected %#v", v, data)
+		}
+		if _, err = cr.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		if _, err = io.Copy(out, cr); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("copied %#v expected %#v", out.Bytes(), data)
+		}
+		v, err = ioutil.ReadAll(NewStreamingChecksummedReader(bytes.NewBuffer(buf.Bytes()), checksumInterval, crc32.NewIEEE))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v, data) {
+			t.Fatalf("streaming read %#v expected %#v", v, data)
+		}
+		for i := 0; i+1 < len(ops); i += 2 {
+			offset := int(ops[i]) % (len(data) + 1)
+			length := int(ops[i+1])
+			if offset+length > len(data) {
+				length = len(data) - offset
+			}
+			var o int64
+			if i%4 == 0 {
+				o, err = cr.Seek(int64(offset), 0)
+			} else {
+				o, err = cr.Seek(int64(offset-len(data)), 2)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o != int64(offset) {
+				t.Fatalf("seek to %d gave %d", offset, o)
+			}
+			v = make([]byte, length)
+			if _, err = io.ReadFull(cr, v); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(v, data[offset:offset+length]) {
+				t.Fatalf("read %#v at %d expected %#v", v, offset, data[offset:offset+length])
+			}
+			o, err = cr.Seek(0, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o != int64(offset+length) {
+				t.Fatalf("position %d expected %d", o, offset+length)
+			}
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
+	"io"
+	"sync"
+)
+
+// ChecksummedHeaderMagic starts every checksummed header.
+const ChecksummedHeaderMagic = "BRIMIOCK"
+
+// ChecksummedHeaderVersion is the format version of headers written by this
+// package.
+const ChecksummedHeaderVersion = 1
+
+// ErrNoChecksummedHeader is returned when content expected to start with a
+// ChecksummedHeader does not.
+var ErrNoChecksummedHeader = fmt.Errorf("not a checksummed header")
+
+// ChecksummedHeader describes checksummed content so it can be read without
+// knowing ahead of time how it was written.
+//
+// Serialized it is the ChecksummedHeaderMagic, then big endian a 2 byte
+// Version, a 2 byte ChecksumSize, a 4 byte Interval, a 1 byte length and that
+// many bytes of HashName, and finally a 4 byte CRC32 IEEE of everything
+// before it.
+type ChecksummedHeader struct {
+	Version      int
+	Interval     int
+	HashName     string
+	ChecksumSize int
+}
+
+var checksummedHeaderHashesLock sync.RWMutex
+var checksummedHeaderHashes = map[string]func() hash.Hash{
+	"crc32-ieee":       func() hash.Hash { return crc32.NewIEEE() },
+	"crc32-castagnoli": func() hash.Hash { return NewCRC32C() },
+	"crc64-iso":        func() hash.Hash { return crc64.New(crc64.MakeTable(crc64.ISO)) },
+	"crc64-ecma":       func() hash.Hash { return crc64.New(crc64.MakeTable(crc64.ECMA)) },
+	"fnv32a":           func() hash.Hash { return fnv.New32a() },
+	"fnv64a":           func() hash.Hash { return fnv.New64a() },
+	"sha1":             sha1.New,
+	"sha256":           sha256.New,
+	"sha512":           sha512.New,
+}
+
+// RegisterChecksummedHeaderHash makes a hashing function available by name to
+// checksummed headers, in addition to those built in: crc32-ieee,
+// crc32-castagnoli, crc64-iso, crc64-ecma, fnv32a, fnv64a, sha1, sha256, and
+// sha512.
+func RegisterChecksummedHeaderHash(name string, newHash func() hash.Hash) {
+	checksummedHeaderHashesLock.Lock()
+	checksummedHeaderHashes[name] = newHash
+	checksummedHeaderHashesLock.Unlock()
+}
+
+// NewHash returns the hashing function named by the header.
+func (h ChecksummedHeader) NewHash() (func() hash.Hash, error) {
+	checksummedHeaderHashesLock.RLock()
+	newHash := checksummedHeaderHashes[h.HashName]
+	checksummedHeaderHashesLock.RUnlock()
+	if newHash == nil {
+		return nil, fmt.Errorf("unknown hash %q", h.HashName)
+	}
+	if s := newHash().Size(); s != h.ChecksumSize {
+		return nil, fmt.Errorf("hash %q has size %d rather than %d", h.HashName, s, h.ChecksumSize)
+	}
+	return newHash, nil
+}
+
+// Config returns the ChecksummedConfig the header describes.
+func (h ChecksummedHeader) Config() (ChecksummedConfig, error) {
+	newHash, err := h.NewHash()
+	if err != nil {
+		return ChecksummedConfig{}, err
+	}
+	return ChecksummedConfig{Interval: h.Interval, NewHash: newHash}, nil
+}
+
+// Len returns the serialized length of the header.
+func (h ChecksummedHeader) Len() int {
+	return len(ChecksummedHeaderMagic) + 2 + 2 + 4 + 1 + len(h.HashName) + 4
+}
+
+// WriteChecksummedHeader writes the serialized header to w.
+func WriteChecksummedHeader(w io.Writer, h ChecksummedHeader) error {
+	if len(h.HashName) > 255 {
+		return fmt.Errorf("hash name %q too long", h.HashName)
+	}
+	b := make([]byte, 0, h.Len())
+	b = append(b, ChecksummedHeaderMagic...)
+	b = append(b, byte(h.Version>>8), byte(h.Version), byte(h.ChecksumSize>>8), byte(h.ChecksumSize))
+	b = append(b, byte(h.Interval>>24), byte(h.Interval>>16), byte(h.Interval>>8), byte(h.Interval))
+	b = append(b, byte(len(h.HashName)))
+	b = append(b, h.HashName...)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+	_, err := w.Write(b)
+	return err
+}
+
+// ReadChecksummedHeader reads a serialized header from r.
+func ReadChecksummedHeader(r io.Reader) (ChecksummedHeader, error) {
+	var h ChecksummedHeader
+	b := make([]byte, len(ChecksummedHeaderMagic)+2+2+4+1, len(ChecksummedHeaderMagic)+2+2+4+1+255+4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return h, err
+	}
+	if !bytes.Equal(b[:len(ChecksummedHeaderMagic)], []byte(ChecksummedHeaderMagic)) {
+		return h, ErrNoChecksummedHeader
+	}
+	p := b[len(ChecksummedHeaderMagic):]
+	h.Version = int(binary.BigEndian.Uint16(p))
+	if h.Version != ChecksummedHeaderVersion {
+		return h, fmt.Errorf("unsupported checksummed header version %d", h.Version)
+	}
+	h.ChecksumSize = int(binary.BigEndian.Uint16(p[2:]))
+	h.Interval = int(binary.BigEndian.Uint32(p[4:]))
+	n := int(p[8])
+	b = b[:len(b)+n+4]
+	if _, err := io.ReadFull(r, b[len(b)-n-4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return h, err
+	}
+	if binary.BigEndian.Uint32(b[len(b)-4:]) != crc32.ChecksumIEEE(b[:len(b)-4]) {
+		return h, fmt.Errorf("checksummed header checksum mismatch")
+	}
+	h.HashName = string(b[len(b)-n-4 : len(b)-4])
+	if h.Interval <= 0 {
+		return h, fmt.Errorf("invalid checksummed header interval %d", h.Interval)
+	}
+	return h, nil
+}
+
+// NewChecksummedWriterWithHeader returns a ChecksummedWriter like
+// NewChecksummedWriterHash does, using the hashing function registered as
+// hashName, after first writing a ChecksummedHeader describing the content
+// to delegate. Such content can be read with NewChecksummedReaderWithHeader
+// without knowing the interval or hash beforehand.
+func NewChecksummedWriterWithHeader(delegate io.Writer, checksumInterval int, hashName string) (ChecksummedWriter, error) {
+	return NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHashName(hashName), WithHeader())
+}
+
+// NewChecksummedReaderWithHeader reads the ChecksummedHeader at the start of
+// delegate and returns it along with a ChecksummedReader configured by it.
+// Offsets within the ChecksummedReader are relative to the content after the
+// header.
+func NewChecksummedReaderWithHeader(delegate io.ReadSeeker) (ChecksummedReader, ChecksummedHeader, error) {
+	if _, err := delegate.Seek(0, 0); err != nil {
+		return nil, ChecksummedHeader{}, err
+	}
+	h, err := ReadChecksummedHeader(delegate)
+	if err != nil {
+		return nil, h, err
+	}
+	newHash, err := h.NewHash()
+	if err != nil {
+		return nil, h, err
+	}
+	if err = validateChecksummed(h.Interval, newHash); err != nil {
+		return nil, h, err
+	}
+	return newChecksummedReaderImpl(&offsetReadSeeker{delegate: delegate, base: int64(h.Len())}, h.Interval, newHash), h, nil
+}
+
+// offsetReadSeeker presents the content of delegate from base onward as if it
+// started at offset 0.
+type offsetReadSeeker struct {
+	delegate io.ReadSeeker
+	base     int64
+}
+
+func (ors *offsetReadSeeker) Read(v []byte) (int, error) {
+	return ors.delegate.Read(v)
+}
+
+func (ors *offsetReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence == 0 {
+		offset += ors.base
+	}
+	o, err := ors.delegate.Seek(offset, whence)
+	return o - ors.base, err
+}
+
+func (ors *offsetReadSeeker) Close() error {
+	if c, ok := ors.delegate.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func (ors *offsetReadSeeker) Unwrap() interface{} {
+	return unwrapDelegate(ors.delegate)
+}
+
+// OpenChecksummed returns a ChecksummedReader for the content of delegate,
+// configured by the ChecksummedHeader it starts with, discovering the
+// interval, hashing function, and checksum size. It returns
+// ErrNoChecksummedHeader if delegate does not start with a header, including
+// if it is too short to hold one. Offsets within the ChecksummedReader are
+// relative to the content after the header.
+func OpenChecksummed(delegate io.ReadSeeker) (ChecksummedReader, error) {
+	cr, _, err := NewChecksummedReaderWithHeader(delegate)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = ErrNoChecksummedHeader
+	}
+	return cr, err
+}
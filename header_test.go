@@ -0,0 +1,100 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"testing"
+)
+
+func TestChecksummedHeader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw, err := NewChecksummedWriterWithHeader(buf, 16, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	h := ChecksummedHeader{Version: 1, Interval: 16, HashName: "sha256", ChecksumSize: 32}
+	buf2 := &bytes.Buffer{}
+	WriteChecksummedHeader(buf2, h)
+	if buf2.Len() != h.Len() {
+		t.Fatal(buf2.Len())
+	}
+	cw2 := NewChecksummedWriterHash(buf2, 16, sha256.New)
+	cw2.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw2.Close()
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatalf("%#v", string(buf.Bytes()))
+	}
+	cr, h2, err := NewChecksummedReaderWithHeader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h2 != h {
+		t.Fatalf("%#v", h2)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	o, err := cr.Seek(18, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o != 18 {
+		t.Fatal(o)
+	}
+	ok, err := cr.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal(ok)
+	}
+	corrupted, err := cr.VerifyAll(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if corrupted != nil {
+		t.Fatal(corrupted)
+	}
+	b := buf.Bytes()
+	b[10] ^= 1
+	if _, _, err = NewChecksummedReaderWithHeader(bytes.NewReader(b)); err == nil {
+		t.Fatal(err)
+	}
+	if _, err = NewChecksummedWriterWithHeader(buf, 16, "nope"); err == nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenChecksummed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw, err := NewChecksummedWriterWithHeader(buf, 16, "crc32-castagnoli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("12345678901234567890"))
+	cw.Close()
+	cr, err := OpenChecksummed(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890" {
+		t.Fatalf("%#v", string(v))
+	}
+	if _, err = OpenChecksummed(bytes.NewReader([]byte("12345678901234567890"))); err != ErrNoChecksummedHeader {
+		t.Fatal(err)
+	}
+	if _, err = OpenChecksummed(bytes.NewReader([]byte("123"))); err != ErrNoChecksummedHeader {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,93 @@
+package brimio
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"hash"
+)
+
+// checksumIndexer is implemented by hashes, such as those from
+// NewIndexedHash, whose checksums depend on the index of the interval they
+// are for.
+type checksumIndexer interface {
+	setIndex(index int64)
+}
+
+// setChecksumIndex tells h, if it cares, the index of the interval it is
+// hashing; it must be called before h's checksum is computed or compared.
+func setChecksumIndex(h hash.Hash, index int64) {
+	if ci, ok := h.(checksumIndexer); ok {
+		ci.setIndex(index)
+	}
+}
+
+// NewIndexedHash returns a hashing function, for use with any of the
+// constructors taking a func() hash.Hash, whose checksums also depend on the
+// index of the interval they are for. Checksums alone catch bit rot but not
+// an intact interval at the wrong position; with an indexed hash, intervals
+// that were shifted, duplicated, or transplanted from elsewhere fail
+// verification too.
+//
+// The index is mixed into the checksum of the hashing function given rather
+// than hashed with the content, so the checksums are the same size and cost
+// the same to compute.
+func NewIndexedHash(newHash func() hash.Hash) func() hash.Hash {
+	return func() hash.Hash { return &indexedHash{Hash: newHash()} }
+}
+
+type indexedHash struct {
+	hash.Hash
+	index int64
+}
+
+func (ih *indexedHash) setIndex(index int64) {
+	ih.index = index
+}
+
+func (ih *indexedHash) Sum(b []byte) []byte {
+	start := len(b)
+	b = ih.Hash.Sum(b)
+	ih.mix(b[start:])
+	return b
+}
+
+func (ih *indexedHash) matches(stored []byte) bool {
+	unmixed := append([]byte(nil), stored...)
+	ih.mix(unmixed)
+	if m, ok := ih.Hash.(checksumMatcher); ok {
+		return m.matches(unmixed)
+	}
+	return bytes.Equal(unmixed, ih.Hash.Sum(nil))
+}
+
+// mix xors sum with bytes derived from the index, which undoes itself when
+// applied twice.
+func (ih *indexedHash) mix(sum []byte) {
+	x := uint64(ih.index)
+	var z uint64
+	for i := range sum {
+		if i%8 == 0 {
+			// splitmix64, so nearby indexes differ in every byte.
+			x += splitmix64Gamma
+			z = splitmix64(x)
+		}
+		sum[i] ^= byte(z >> (8 * uint(i%8)))
+	}
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, if the
+// hash given does, so ChecksummedWriter checkpoints work with an
+// indexedHash; the index is set again before each checksum.
+func (ih *indexedHash) MarshalBinary() ([]byte, error) {
+	return marshalHash(ih.Hash)
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (ih *indexedHash) UnmarshalBinary(b []byte) error {
+	u, ok := ih.Hash.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("hash %T does not support checkpoints", ih.Hash)
+	}
+	return u.UnmarshalBinary(b)
+}
@@ -0,0 +1,116 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+)
+
+func TestIndexedHash(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz1234567890ABCDEF")
+	newCRC := func() hash.Hash { return crc32.NewIEEE() }
+	for _, newHash := range []func() hash.Hash{NewIndexedHash(newCRC), NewIndexedHash(NewMultiHashAny(newCRC, sha256.New))} {
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriterHash(buf, 16, newHash)
+		cw.Write(content[:10])
+		cw.Write(content[10:])
+		cw.Close()
+		b := buf.Bytes()
+		// The multi-core writer computes the same checksums.
+		buf2 := &bytes.Buffer{}
+		cw, err := NewChecksummedWriterWith(buf2, WithInterval(16), WithHash(newHash), WithCores(2, 4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cw.Write(content)
+		cw.Close()
+		if !bytes.Equal(b, buf2.Bytes()) {
+			t.Fatal("multi-core differs")
+		}
+		cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 16, newHash, &ChecksummedReaderOptions{AutoVerify: true})
+		v, err := ioutil.ReadAll(cr)
+		if err != nil || !bytes.Equal(v, content) {
+			t.Fatal(string(v), err)
+		}
+		if corrupt, err := cr.VerifyAll(nil); err != nil || corrupt != nil {
+			t.Fatal(corrupt, err)
+		}
+		if v, err = ioutil.ReadAll(NewStreamingChecksummedReaderHash(bytes.NewReader(b), 16, newHash)); err != nil || !bytes.Equal(v, content) {
+			t.Fatal(string(v), err)
+		}
+		// Without the index the checksums no longer match.
+		if corrupt, err := NewChecksummedReaderHash(bytes.NewReader(b), 16, newCRC).VerifyAll(nil); newHash().Size() == 4 && (err != nil || len(corrupt) != 3) {
+			t.Fatal(corrupt, err)
+		}
+		// Intact intervals swapped, or one duplicated over another, fail.
+		blockSize := 16 + newHash().Size()
+		swapped := append([]byte(nil), b...)
+		copy(swapped, b[blockSize:2*blockSize])
+		copy(swapped[blockSize:], b[:blockSize])
+		cr = NewChecksummedReaderHash(bytes.NewReader(swapped), 16, newHash)
+		if corrupt, err := cr.VerifyAll(nil); err != nil || len(corrupt) != 2 || corrupt[0] != 0 || corrupt[1] != 1 {
+			t.Fatal(corrupt, err)
+		}
+		copy(swapped, b)
+		copy(swapped[2*blockSize:], b[:blockSize])
+		cr = NewChecksummedReaderHash(bytes.NewReader(swapped), 16, newHash)
+		if corrupt, err := cr.VerifyAll(nil); err != nil || len(corrupt) != 1 || corrupt[0] != 2 {
+			t.Fatal(corrupt, err)
+		}
+		if corrupt, err := VerifyParallel(bytes.NewReader(swapped), int64(len(swapped)), ChecksummedConfig{Interval: 16, NewHash: newHash}, 2); err != nil || len(corrupt) != 1 || corrupt[0] != 2 {
+			t.Fatal(corrupt, err)
+		}
+	}
+}
+
+func TestIndexedHashFlushAndWrapped(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz1234567890ABCDEF")
+	newCRC := func() hash.Hash { return crc32.NewIEEE() }
+	// An indexed hash within a multi-hash still gets the index.
+	for _, newHash := range []func() hash.Hash{NewIndexedHash(newCRC), NewMultiHash(NewIndexedHash(newCRC), sha256.New)} {
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriterHash(buf, 16, newHash)
+		cw.Write(content[:20])
+		cw.Flush()
+		cw.Write(content[20:])
+		cw.Close()
+		b := buf.Bytes()
+		buf2 := &bytes.Buffer{}
+		cw, err := NewChecksummedWriterWith(buf2, WithInterval(16), WithHash(newHash), WithCores(2, 4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cw.Write(content[:20])
+		cw.Flush()
+		cw.Write(content[20:])
+		cw.Close()
+		if !bytes.Equal(b, buf2.Bytes()) {
+			t.Fatal("multi-core differs after Flush")
+		}
+		if v, err := ioutil.ReadAll(NewStreamingChecksummedReaderHash(bytes.NewReader(b), 16, newHash)); err != nil || !bytes.Equal(v, content) {
+			t.Fatal(string(v), err)
+		}
+		// Without Flush, so DumpBlocks can follow the fixed intervals.
+		buf.Reset()
+		cw = NewChecksummedWriterHash(buf, 16, newHash)
+		cw.Write(content)
+		cw.Close()
+		out := &bytes.Buffer{}
+		if err = DumpBlocks(out, bytes.NewReader(buf.Bytes()), ChecksummedConfig{Interval: 16, NewHash: newHash}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(out.Bytes(), []byte("MISMATCH")) || bytes.Count(out.Bytes(), []byte(" ok\n")) != 3 {
+			t.Fatal(out.String())
+		}
+		blockSize := 16 + newHash().Size()
+		swapped := append([]byte(nil), buf.Bytes()...)
+		copy(swapped, buf.Bytes()[blockSize:2*blockSize])
+		copy(swapped[blockSize:], buf.Bytes()[:blockSize])
+		if corrupt, err := NewChecksummedReaderHash(bytes.NewReader(swapped), 16, newHash).VerifyAll(nil); err != nil || len(corrupt) != 2 {
+			t.Fatal(corrupt, err)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package brimio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// ChecksumManifestMagic starts every serialized ChecksumManifest.
+const ChecksumManifestMagic = "BRIMIOCM"
+
+// ChecksumManifestVersion is the format version of ChecksumManifests written
+// by this package.
+const ChecksumManifestVersion = 1
+
+// ChecksumManifest lists the checksum of each interval of checksummed
+// content, so external inventory and audit systems can store and compare
+// them without the content itself. Its exported fields also suit
+// encoding/json, where the checksums appear as base64.
+type ChecksumManifest struct {
+	Interval     int
+	ChecksumSize int
+	// Length is the length of the content, not including checksums; any
+	// trailing partial interval has no checksum and no entry in Checksums.
+	Length int64
+	// Checksums are the stored checksums by interval index.
+	Checksums [][]byte
+}
+
+// BuildChecksumManifest returns the ChecksumManifest of the checksummed
+// content of r, reading just the checksums, not the content, and so not
+// verifying anything.
+func BuildChecksumManifest(r io.ReadSeeker, cfg ChecksummedConfig) (*ChecksumManifest, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	checksumSize := cfg.NewHash().Size()
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	m := &ChecksumManifest{Interval: cfg.Interval, ChecksumSize: checksumSize, Length: ContentSize(end, cfg.Interval, checksumSize)}
+	blockSize := int64(cfg.Interval + checksumSize)
+	blocks := end / blockSize
+	m.Checksums = make([][]byte, blocks)
+	all := make([]byte, blocks*int64(checksumSize))
+	for i := int64(0); i < blocks; i++ {
+		m.Checksums[i] = all[i*int64(checksumSize) : (i+1)*int64(checksumSize)]
+		if err = readStoredChecksum(r, cfg.Interval, i, m.Checksums[i]); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// CompareChecksums compares the checksummed content of a and b, both with
+// the layout cfg describes, by their stored checksums alone, returning the
+// index of the first interval that differs, or -1 if none do. The content
+// itself is not read, making this a cheap consistency check of replicas,
+// though a trailing partial interval has no checksum and so is compared only
+// by length: if a and b differ in length, the index of the interval where the
+// shorter ends is returned if no earlier interval differs.
+func CompareChecksums(a io.ReadSeeker, b io.ReadSeeker, cfg ChecksummedConfig) (int64, error) {
+	if err := cfg.Validate(); err != nil {
+		return -1, err
+	}
+	checksumSize := cfg.NewHash().Size()
+	blockSize := int64(cfg.Interval + checksumSize)
+	endA, err := a.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1, err
+	}
+	endB, err := b.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1, err
+	}
+	blocks := endA / blockSize
+	if endB/blockSize < blocks {
+		blocks = endB / blockSize
+	}
+	checksumA := make([]byte, checksumSize)
+	checksumB := make([]byte, checksumSize)
+	for i := int64(0); i < blocks; i++ {
+		if err = readStoredChecksum(a, cfg.Interval, i, checksumA); err != nil {
+			return -1, err
+		}
+		if err = readStoredChecksum(b, cfg.Interval, i, checksumB); err != nil {
+			return -1, err
+		}
+		if !bytes.Equal(checksumA, checksumB) {
+			return i, nil
+		}
+	}
+	if endA != endB {
+		return blocks, nil
+	}
+	return -1, nil
+}
+
+// readStoredChecksum reads into checksum the stored checksum of the interval
+// at index.
+func readStoredChecksum(r io.ReadSeeker, interval int, index int64, checksum []byte) error {
+	if _, err := r.Seek(index*int64(interval+len(checksum))+int64(interval), io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.ReadFull(r, checksum)
+	return err
+}
+
+// Diff returns the indexes of the intervals whose checksums differ between
+// m and other, including those only one of them has. Manifests of different
+// intervals or checksum sizes can't be compared and are an error.
+func (m *ChecksumManifest) Diff(other *ChecksumManifest) ([]int64, error) {
+	if m.Interval != other.Interval || m.ChecksumSize != other.ChecksumSize {
+		return nil, fmt.Errorf("manifests differ in layout")
+	}
+	n := len(m.Checksums)
+	if len(other.Checksums) > n {
+		n = len(other.Checksums)
+	}
+	var diff []int64
+	for i := 0; i < n; i++ {
+		if i >= len(m.Checksums) || i >= len(other.Checksums) || !bytes.Equal(m.Checksums[i], other.Checksums[i]) {
+			diff = append(diff, int64(i))
+		}
+	}
+	return diff, nil
+}
+
+// WriteChecksumManifest writes m to w in a compact binary form that can be
+// read back with ReadChecksumManifest.
+//
+// The content is ChecksumManifestMagic, a 1 byte version, uvarints of the
+// Interval, ChecksumSize, Length, and count of checksums, the checksums
+// themselves concatenated, and finally a big endian 4 byte CRC32 IEEE of
+// everything before it.
+func WriteChecksumManifest(w io.Writer, m *ChecksumManifest) error {
+	for i, c := range m.Checksums {
+		if len(c) != m.ChecksumSize {
+			return fmt.Errorf("checksum %d has size %d rather than %d", i, len(c), m.ChecksumSize)
+		}
+	}
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	bw.WriteString(ChecksumManifestMagic)
+	bw.WriteByte(ChecksumManifestVersion)
+	var b [binary.MaxVarintLen64]byte
+	for _, u := range []uint64{uint64(m.Interval), uint64(m.ChecksumSize), uint64(m.Length), uint64(len(m.Checksums))} {
+		bw.Write(b[:binary.PutUvarint(b[:], u)])
+	}
+	for _, c := range m.Checksums {
+		bw.Write(c)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	_, err := w.Write(crc.Sum(nil))
+	return err
+}
+
+// ReadChecksumManifest reads a ChecksumManifest written by
+// WriteChecksumManifest, reading no further than its end.
+func ReadChecksumManifest(r io.Reader) (*ChecksumManifest, error) {
+	crc := crc32.NewIEEE()
+	br := &byteReader{r: io.TeeReader(r, crc)}
+	magic := make([]byte, len(ChecksumManifestMagic)+1)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, manifestErr(err)
+	}
+	if string(magic[:len(ChecksumManifestMagic)]) != ChecksumManifestMagic {
+		return nil, fmt.Errorf("not a checksum manifest")
+	}
+	if magic[len(ChecksumManifestMagic)] != ChecksumManifestVersion {
+		return nil, fmt.Errorf("unknown checksum manifest version %d", magic[len(ChecksumManifestMagic)])
+	}
+	var u [4]uint64
+	for i := range u {
+		var err error
+		if u[i], err = binary.ReadUvarint(br); err != nil {
+			return nil, manifestErr(err)
+		}
+	}
+	m := &ChecksumManifest{Interval: int(u[0]), ChecksumSize: int(u[1]), Length: int64(u[2])}
+	if m.Interval < MinChecksumInterval || m.Interval > MaxChecksumInterval || m.ChecksumSize < 1 || m.ChecksumSize > MaxChecksumSize || u[2] > math.MaxInt64 || u[3] > u[2]/u[0] || u[3] > math.MaxInt32/u[1] {
+		return nil, fmt.Errorf("invalid checksum manifest")
+	}
+	all, err := readGrowing(br, nil, u[3]*u[1])
+	if err != nil {
+		return nil, manifestErr(err)
+	}
+	want := crc.Sum(nil)
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil {
+		return nil, manifestErr(err)
+	}
+	if !bytes.Equal(got, want) {
+		return nil, ErrChecksumMismatch
+	}
+	m.Checksums = make([][]byte, u[3])
+	for i := range m.Checksums {
+		m.Checksums[i] = all[i*m.ChecksumSize : (i+1)*m.ChecksumSize]
+	}
+	return m, nil
+}
+
+// byteReader adds io.ByteReader to an io.Reader without reading ahead.
+type byteReader struct {
+	r io.Reader
+	b [1]byte
+}
+
+func (br *byteReader) Read(v []byte) (int, error) {
+	return br.r.Read(v)
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(br.r, br.b[:])
+	return br.b[0], err
+}
+
+// manifestErr reports running out of content as truncation.
+func manifestErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("checksum manifest truncated")
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func TestChecksumManifest(t *testing.T) {
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	buf := &bytes.Buffer{}
+	cw, err := NewChecksummedWriterWith(buf, WithInterval(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	m, err := BuildChecksumManifest(bytes.NewReader(b), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Interval != 16 || m.ChecksumSize != 4 || m.Length != 40 || len(m.Checksums) != 2 {
+		t.Fatalf("%#v", m)
+	}
+	if !bytes.Equal(m.Checksums[1], b[36:40]) {
+		t.Fatal(m.Checksums[1])
+	}
+	out := &bytes.Buffer{}
+	if err = WriteChecksumManifest(out, m); err != nil {
+		t.Fatal(err)
+	}
+	serialized := append([]byte(nil), out.Bytes()...)
+	out.WriteString("trailing")
+	m2, err := ReadChecksumManifest(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "trailing" {
+		t.Fatal(out.String())
+	}
+	if diff, err := m.Diff(m2); err != nil || len(diff) != 0 || m2.Length != 40 {
+		t.Fatal(diff, err)
+	}
+	b[5] ^= 1
+	b[16] ^= 1
+	m3, err := BuildChecksumManifest(bytes.NewReader(b[:20]), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := m.Diff(m3); err != nil || len(diff) != 2 || diff[0] != 0 || diff[1] != 1 {
+		t.Fatal(diff, err)
+	}
+	if _, err = m.Diff(&ChecksumManifest{Interval: 32, ChecksumSize: 4}); err == nil {
+		t.Fatal("expected layout error")
+	}
+	if _, err = ReadChecksumManifest(bytes.NewReader(serialized[:len(serialized)-1])); err == nil {
+		t.Fatal("expected truncation error")
+	}
+	serialized[len(serialized)-6] ^= 1
+	if _, err = ReadChecksumManifest(bytes.NewReader(serialized)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if _, err = ReadChecksumManifest(bytes.NewReader([]byte("BRIMIOFT\x01"))); err == nil {
+		t.Fatal("expected magic error")
+	}
+	// A huge claimed count must fail on the missing content rather than
+	// allocating for it.
+	crafted := append([]byte(ChecksumManifestMagic), ChecksumManifestVersion, 0x80, 0x08, 64)
+	crafted = append(crafted, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)
+	crafted = append(crafted, 0xff, 0xff, 0xff, 0xff, 0x07)
+	if _, err = ReadChecksumManifest(bytes.NewReader(crafted)); err == nil {
+		t.Fatal("expected truncation error")
+	}
+}
+
+func TestCompareChecksums(t *testing.T) {
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	buf := &bytes.Buffer{}
+	cw, err := NewChecksummedWriterWith(buf, WithInterval(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz1234"))
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	a := buf.Bytes()
+	b := append([]byte(nil), a...)
+	if i, err := CompareChecksums(bytes.NewReader(a), bytes.NewReader(b), cfg); err != nil || i != -1 {
+		t.Fatal(i, err)
+	}
+	// Content damage without a matching checksum is not seen.
+	b[25] ^= 1
+	if i, err := CompareChecksums(bytes.NewReader(a), bytes.NewReader(b), cfg); err != nil || i != -1 {
+		t.Fatal(i, err)
+	}
+	b[38] ^= 1
+	if i, err := CompareChecksums(bytes.NewReader(a), bytes.NewReader(b), cfg); err != nil || i != 1 {
+		t.Fatal(i, err)
+	}
+	if i, err := CompareChecksums(bytes.NewReader(a), bytes.NewReader(a[:45]), cfg); err != nil || i != 2 {
+		t.Fatal(i, err)
+	}
+	if i, err := CompareChecksums(bytes.NewReader(a[:30]), bytes.NewReader(a), cfg); err != nil || i != 1 {
+		t.Fatal(i, err)
+	}
+}
@@ -0,0 +1,118 @@
+package brimio
+
+import (
+	"container/heap"
+	"io"
+)
+
+// NewSegmentIterator returns a RecordIterator over the Records of a
+// checksummed segment, verifying each interval before any of its Records are
+// returned; corruption results in ErrChecksumMismatch from Next.
+func NewSegmentIterator(delegate io.ReadSeeker, cfg ChecksummedConfig) RecordIterator {
+	return NewRecordReader(NewChecksummedReaderWithOptions(delegate, cfg.Interval, cfg.NewHash, &ChecksummedReaderOptions{AutoVerify: true}))
+}
+
+// MergeIterator is a RecordIterator yielding the Records of several
+// RecordIterators, each already sorted by key, in overall key order. For
+// equal keys, Records from iterators given earlier come first, so giving
+// the newest segments first suits a TombstoneResolver.
+type MergeIterator struct {
+	heap mergeHeap
+	// next is the iterator whose Record was last returned and so must be
+	// advanced before continuing.
+	next *mergeItem
+	err  error
+}
+
+type mergeItem struct {
+	iterator RecordIterator
+	priority int
+	record   Record
+}
+
+type mergeHeap struct {
+	items   []*mergeItem
+	compare func(a []byte, b []byte) int
+}
+
+func (mh *mergeHeap) Len() int {
+	return len(mh.items)
+}
+
+func (mh *mergeHeap) Less(i int, j int) bool {
+	if c := mh.compare(mh.items[i].record.Key, mh.items[j].record.Key); c != 0 {
+		return c < 0
+	}
+	return mh.items[i].priority < mh.items[j].priority
+}
+
+func (mh *mergeHeap) Swap(i int, j int) {
+	mh.items[i], mh.items[j] = mh.items[j], mh.items[i]
+}
+
+func (mh *mergeHeap) Push(x interface{}) {
+	mh.items = append(mh.items, x.(*mergeItem))
+}
+
+func (mh *mergeHeap) Pop() interface{} {
+	item := mh.items[len(mh.items)-1]
+	mh.items = mh.items[:len(mh.items)-1]
+	return item
+}
+
+// NewMergeIterator returns a MergeIterator over the RecordIterators given,
+// with keys ordered by compare, such as bytes.Compare.
+func NewMergeIterator(iterators []RecordIterator, compare func(a []byte, b []byte) int) *MergeIterator {
+	mi := &MergeIterator{heap: mergeHeap{compare: compare}}
+	for i, it := range iterators {
+		item := &mergeItem{iterator: it, priority: i}
+		if err := mi.advance(item); err != nil {
+			mi.err = err
+			break
+		}
+	}
+	heap.Init(&mi.heap)
+	return mi
+}
+
+// advance reads the next Record of the item, adding it to the heap if there
+// is one.
+func (mi *MergeIterator) advance(item *mergeItem) error {
+	r, err := item.iterator.Next()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	item.record = r
+	mi.heap.items = append(mi.heap.items, item)
+	return nil
+}
+
+// Next implements the RecordIterator interface. Any error from an underlying
+// RecordIterator is returned, and keeps being returned, as is.
+func (mi *MergeIterator) Next() (Record, error) {
+	if mi.err != nil {
+		return Record{}, mi.err
+	}
+	if mi.next != nil {
+		item := mi.next
+		mi.next = nil
+		r, err := item.iterator.Next()
+		switch err {
+		case nil:
+			item.record = r
+			heap.Push(&mi.heap, item)
+		case io.EOF:
+		default:
+			mi.err = err
+			return Record{}, err
+		}
+	}
+	if mi.heap.Len() == 0 {
+		return Record{}, io.EOF
+	}
+	mi.next = heap.Pop(&mi.heap).(*mergeItem)
+	return mi.next.record, nil
+}
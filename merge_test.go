@@ -0,0 +1,63 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+func TestMergeIterator(t *testing.T) {
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	segment := func(records ...Record) []byte {
+		buf := &bytes.Buffer{}
+		rw := NewRecordWriter(NewChecksummedWriterHash(buf, cfg.Interval, cfg.NewHash))
+		for _, r := range records {
+			rw.WriteRecord(r)
+		}
+		rw.Close()
+		return buf.Bytes()
+	}
+	newest := segment(NewTombstone([]byte("b")), Record{Key: []byte("d"), Value: []byte("d2")})
+	middle := segment(Record{Key: []byte("a"), Value: []byte("a1")}, Record{Key: []byte("d"), Value: []byte("d1")}, Record{Key: []byte("e"), Value: []byte("e1")})
+	oldest := segment(Record{Key: []byte("b"), Value: []byte("b0")}, Record{Key: []byte("c"), Value: []byte("c0")}, Record{Key: []byte("d"), Value: []byte("d0")})
+	read := func(it RecordIterator) (string, error) {
+		var got []string
+		for {
+			r, err := it.Next()
+			if err == io.EOF {
+				return fmt.Sprint(got), nil
+			}
+			if err != nil {
+				return fmt.Sprint(got), err
+			}
+			got = append(got, string(r.Key)+"="+string(r.Value))
+		}
+	}
+	its := func(segments ...[]byte) []RecordIterator {
+		var its []RecordIterator
+		for _, s := range segments {
+			its = append(its, NewSegmentIterator(bytes.NewReader(s), cfg))
+		}
+		return its
+	}
+	got, err := read(NewMergeIterator(its(newest, middle, oldest), bytes.Compare))
+	if err != nil || got != "[a=a1 b= b=b0 c=c0 d=d2 d=d1 d=d0 e=e1]" {
+		t.Fatal(got, err)
+	}
+	tr := NewTombstoneResolver(NewMergeIterator(its(newest, middle, oldest), bytes.Compare), bytes.Compare)
+	tr.DropTombstones = true
+	got, err = read(tr)
+	if err != nil || got != "[a=a1 c=c0 d=d2 e=e1]" {
+		t.Fatal(got, err)
+	}
+	corrupt := append([]byte{}, oldest...)
+	corrupt[2] ^= 1
+	_, err = read(NewMergeIterator(its(newest, middle, corrupt), bytes.Compare))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+}
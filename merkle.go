@@ -0,0 +1,121 @@
+package brimio
+
+import (
+	"bytes"
+	"io"
+)
+
+// MerkleTree is built over the per-interval checksums of checksummed content,
+// so replicas can compare Roots and, if they differ, drill down with
+// MerkleDiff to find the divergent intervals without reading all of the
+// content.
+//
+// Levels[0] holds a leaf for each interval: its stored checksum, or for any
+// trailing partial interval the hash of its content. Each following level
+// hashes pairs of nodes from the level below, left then right, with an odd
+// final node carried up as is; the last level holds just the root.
+type MerkleTree struct {
+	Levels [][][]byte
+}
+
+// BuildMerkleTree returns the MerkleTree of the checksummed content in r,
+// reading only the stored checksums and any trailing partial interval. The
+// position of r afterward is undefined.
+func BuildMerkleTree(r io.ReadSeeker, cfg ChecksummedConfig) (*MerkleTree, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	checksumSize := cfg.NewHash().Size()
+	blockSize := int64(cfg.Interval + checksumSize)
+	end, err := r.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+	var leaves [][]byte
+	for i := int64(0); i < end/blockSize; i++ {
+		if _, err = r.Seek(i*blockSize+int64(cfg.Interval), 0); err != nil {
+			return nil, err
+		}
+		leaf := make([]byte, checksumSize)
+		if _, err = io.ReadFull(r, leaf); err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, leaf)
+	}
+	if partial := end % blockSize; partial > 0 {
+		if _, err = r.Seek(end-partial, 0); err != nil {
+			return nil, err
+		}
+		hash := cfg.NewHash()
+		if _, err = io.CopyN(hash, r, partial); err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, hash.Sum(nil))
+	}
+	mt := &MerkleTree{Levels: [][][]byte{leaves}}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				break
+			}
+			hash := cfg.NewHash()
+			hash.Write(level[i])
+			hash.Write(level[i+1])
+			next = append(next, hash.Sum(nil))
+		}
+		mt.Levels = append(mt.Levels, next)
+		level = next
+	}
+	return mt, nil
+}
+
+// Root returns the root hash of the MerkleTree, or nil for empty content.
+func (mt *MerkleTree) Root() []byte {
+	top := mt.Levels[len(mt.Levels)-1]
+	if len(top) == 0 {
+		return nil
+	}
+	return top[0]
+}
+
+// MerkleDiff returns the indexes of the intervals that differ between the
+// MerkleTrees given, descending only into subtrees whose hashes differ. Trees
+// of differing lengths have their extra intervals reported as differing.
+func MerkleDiff(a *MerkleTree, b *MerkleTree) []int64 {
+	if len(a.Levels[0]) != len(b.Levels[0]) {
+		// With different shapes the inner nodes can't be compared, so just
+		// compare the leaves.
+		la, lb := a.Levels[0], b.Levels[0]
+		if len(la) < len(lb) {
+			la, lb = lb, la
+		}
+		var diff []int64
+		for i := range la {
+			if i >= len(lb) || !bytes.Equal(la[i], lb[i]) {
+				diff = append(diff, int64(i))
+			}
+		}
+		return diff
+	}
+	var diff []int64
+	var walk func(level int, i int)
+	walk = func(level int, i int) {
+		if bytes.Equal(a.Levels[level][i], b.Levels[level][i]) {
+			return
+		}
+		if level == 0 {
+			diff = append(diff, int64(i))
+			return
+		}
+		walk(level-1, i*2)
+		if i*2+1 < len(a.Levels[level-1]) {
+			walk(level-1, i*2+1)
+		}
+	}
+	if top := len(a.Levels) - 1; len(a.Levels[top]) > 0 {
+		walk(top, 0)
+	}
+	return diff
+}
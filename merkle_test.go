@@ -0,0 +1,55 @@
+package brimio
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"testing"
+)
+
+func TestMerkleTree(t *testing.T) {
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	content := bytes.Repeat([]byte("0123456789abcdef"), 5)
+	content = append(content, "xyz"...)
+	write := func(v []byte) []byte {
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriterHash(buf, cfg.Interval, cfg.NewHash)
+		cw.Write(v)
+		cw.Close()
+		return buf.Bytes()
+	}
+	a, err := BuildMerkleTree(bytes.NewReader(write(content)), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 6 leaves, then 3, 2, and the root.
+	if len(a.Levels) != 4 || len(a.Levels[0]) != 6 || len(a.Levels[1]) != 3 || len(a.Levels[3]) != 1 {
+		t.Fatal(len(a.Levels))
+	}
+	c2 := append([]byte{}, content...)
+	c2[20] = 'X'
+	c2[81] = 'X'
+	b, err := BuildMerkleTree(bytes.NewReader(write(c2)), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.Root(), b.Root()) {
+		t.Fatal()
+	}
+	if d := fmt.Sprint(MerkleDiff(a, b)); d != "[1 5]" {
+		t.Fatal(d)
+	}
+	b, _ = BuildMerkleTree(bytes.NewReader(write(content)), cfg)
+	if !bytes.Equal(a.Root(), b.Root()) || MerkleDiff(a, b) != nil {
+		t.Fatal()
+	}
+	b, _ = BuildMerkleTree(bytes.NewReader(write(content[:40])), cfg)
+	if d := fmt.Sprint(MerkleDiff(a, b)); d != "[2 3 4 5]" {
+		t.Fatal(d)
+	}
+	e, _ := BuildMerkleTree(bytes.NewReader(nil), cfg)
+	if e.Root() != nil || MerkleDiff(e, e) != nil {
+		t.Fatal()
+	}
+}
@@ -0,0 +1,180 @@
+package brimio
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metrics renders the Stats of named ChecksummedReaders and
+// ChecksummedWriters, the IOCounts of IORegistries, and the AllocStats in the
+// Prometheus text exposition format, so services can expose them on an
+// existing /metrics endpoint. It is safe for concurrent use.
+//
+// Each reader and writer's counters carry a name label of the name it was
+// added with, and each IORegistry's a label label of their label.
+type Metrics struct {
+	lock       sync.Mutex
+	readers    map[string]ChecksummedReader
+	writers    map[string]ChecksummedWriter
+	registries []*IORegistry
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{readers: make(map[string]ChecksummedReader), writers: make(map[string]ChecksummedWriter)}
+}
+
+// AddReader includes the Stats of cr under the name given, replacing any
+// reader already added under that name.
+func (m *Metrics) AddReader(name string, cr ChecksummedReader) {
+	m.lock.Lock()
+	m.readers[name] = cr
+	m.lock.Unlock()
+}
+
+// AddWriter includes the Stats of cw under the name given, replacing any
+// writer already added under that name.
+func (m *Metrics) AddWriter(name string, cw ChecksummedWriter) {
+	m.lock.Lock()
+	m.writers[name] = cw
+	m.lock.Unlock()
+}
+
+// AddIORegistry includes the IOCounts of each label of reg.
+func (m *Metrics) AddIORegistry(reg *IORegistry) {
+	m.lock.Lock()
+	m.registries = append(m.registries, reg)
+	m.lock.Unlock()
+}
+
+// Remove stops including the reader and writer added under the name given,
+// such as once they are closed.
+func (m *Metrics) Remove(name string) {
+	m.lock.Lock()
+	delete(m.readers, name)
+	delete(m.writers, name)
+	m.lock.Unlock()
+}
+
+type metricSample struct {
+	labels string
+	value  uint64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	samples []metricSample
+}
+
+// WriteTo implements the io.WriterTo interface, writing a snapshot of the
+// metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	families := []*metricFamily{
+		{name: "brimio_reader_bytes_read_total", help: "Content returned by ChecksummedReaders, not including checksums."},
+		{name: "brimio_reader_blocks_verified_total", help: "Intervals checked against their checksums by ChecksummedReaders."},
+		{name: "brimio_reader_verify_failures_total", help: "Intervals found not checksum valid by ChecksummedReaders."},
+		{name: "brimio_reader_physical_bytes_read_total", help: "Underlying content read by ChecksummedReaders, checksums included."},
+		{name: "brimio_reader_checksum_bytes_read_total", help: "Checksums read by ChecksummedReaders."},
+		{name: "brimio_writer_bytes_written_total", help: "Content accepted by ChecksummedWriters, not including checksums."},
+		{name: "brimio_writer_checksums_emitted_total", help: "Checksums written out by ChecksummedWriters."},
+		{name: "brimio_io_read_bytes_total", help: "Bytes read through LabeledReaders."},
+		{name: "brimio_io_read_ops_total", help: "Read calls through LabeledReaders."},
+		{name: "brimio_io_write_bytes_total", help: "Bytes written through LabeledWriters."},
+		{name: "brimio_io_write_ops_total", help: "Write calls through LabeledWriters."},
+	}
+	m.lock.Lock()
+	for _, name := range sortedKeys(m.readers) {
+		s := m.readers[name].Stats()
+		labels := metricLabels("name", name)
+		families[0].samples = append(families[0].samples, metricSample{labels, s.BytesRead})
+		families[1].samples = append(families[1].samples, metricSample{labels, s.BlocksVerified})
+		families[2].samples = append(families[2].samples, metricSample{labels, s.VerifyFailures})
+		families[3].samples = append(families[3].samples, metricSample{labels, s.PhysicalBytesRead})
+		families[4].samples = append(families[4].samples, metricSample{labels, s.ChecksumBytesRead})
+	}
+	for _, name := range sortedKeys(m.writers) {
+		s := m.writers[name].Stats()
+		labels := metricLabels("name", name)
+		families[5].samples = append(families[5].samples, metricSample{labels, s.BytesWritten})
+		families[6].samples = append(families[6].samples, metricSample{labels, s.ChecksumsEmitted})
+	}
+	// Counts for the same label from several registries are summed.
+	counts := make(map[string]IOCounts)
+	for _, reg := range m.registries {
+		for label, c := range reg.Snapshot() {
+			sum := counts[label]
+			sum.ReadBytes += c.ReadBytes
+			sum.ReadOps += c.ReadOps
+			sum.WriteBytes += c.WriteBytes
+			sum.WriteOps += c.WriteOps
+			counts[label] = sum
+		}
+	}
+	m.lock.Unlock()
+	for _, label := range sortedKeys(counts) {
+		c := counts[label]
+		labels := metricLabels("label", label)
+		families[7].samples = append(families[7].samples, metricSample{labels, c.ReadBytes})
+		families[8].samples = append(families[8].samples, metricSample{labels, c.ReadOps})
+		families[9].samples = append(families[9].samples, metricSample{labels, c.WriteBytes})
+		families[10].samples = append(families[10].samples, metricSample{labels, c.WriteOps})
+	}
+	a := CurrentAllocStats()
+	for _, f := range []struct {
+		name  string
+		help  string
+		value uint64
+	}{
+		{"brimio_alloc_read_calls_total", "Read calls audited; see AllocStats.", a.ReadCalls},
+		{"brimio_alloc_read_allocs_total", "Heap allocations during audited Read calls.", a.ReadAllocs},
+		{"brimio_alloc_write_calls_total", "Write calls audited; see AllocStats.", a.WriteCalls},
+		{"brimio_alloc_write_allocs_total", "Heap allocations during audited Write calls.", a.WriteAllocs},
+		{"brimio_alloc_verify_calls_total", "Verify calls audited; see AllocStats.", a.VerifyCalls},
+		{"brimio_alloc_verify_allocs_total", "Heap allocations during audited Verify calls.", a.VerifyAllocs},
+	} {
+		families = append(families, &metricFamily{name: f.name, help: f.help, samples: []metricSample{{"", f.value}}})
+	}
+	var buf bytes.Buffer
+	for _, f := range families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n", f.name, f.help, f.name)
+		for _, s := range f.samples {
+			fmt.Fprintf(&buf, "%s%s %d\n", f.name, s.labels, s.value)
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// metricLabels returns the label set for a single label, escaping its value
+// as the exposition format requires.
+func metricLabels(name string, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return "{" + name + `="` + value + `"}`
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]ChecksummedReader:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]ChecksummedWriter:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]IOCounts:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
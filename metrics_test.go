@@ -0,0 +1,45 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewChecksummedWriter(&buf, 4, crc32.NewIEEE)
+	cw.Write([]byte("123456789"))
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 4, crc32.NewIEEE)
+	cr.Read(make([]byte, 3))
+	m := NewMetrics()
+	m.AddWriter("seg\"1", cw)
+	m.AddReader("seg\"1", cr)
+	m.AddReader("gone", cr)
+	m.Remove("gone")
+	reg := NewIORegistry()
+	NewLabeledWriter(&bytes.Buffer{}, reg, "tenant").Write([]byte("abc"))
+	m.AddIORegistry(reg)
+	var out bytes.Buffer
+	if _, err := m.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	s := out.String()
+	for _, want := range []string{
+		"# TYPE brimio_reader_bytes_read_total counter\n",
+		"brimio_reader_bytes_read_total{name=\"seg\\\"1\"} 3\n",
+		"brimio_writer_bytes_written_total{name=\"seg\\\"1\"} 9\n",
+		"brimio_writer_checksums_emitted_total{name=\"seg\\\"1\"} 2\n",
+		"brimio_reader_physical_bytes_read_total{name=\"seg\\\"1\"} 3\n",
+		"brimio_io_write_bytes_total{label=\"tenant\"} 3\n",
+		"brimio_alloc_read_calls_total ",
+	} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("%#v missing %#v", s, want)
+		}
+	}
+	if strings.Contains(s, "gone") {
+		t.Fatalf("%#v", s)
+	}
+}
@@ -0,0 +1,145 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// HugePages reports what kind of huge page backing an Mmap was granted.
+type HugePages int
+
+const (
+	// HugePagesNone means the mapping uses regular pages.
+	HugePagesNone HugePages = iota
+	// HugePagesTransparent means the mapping uses regular pages but the
+	// kernel has been advised to back it with transparent huge pages where
+	// it can.
+	HugePagesTransparent
+	// HugePagesExplicit means the mapping was made with MAP_HUGETLB, which
+	// generally requires the file to be on hugetlbfs.
+	HugePagesExplicit
+)
+
+func (h HugePages) String() string {
+	switch h {
+	case HugePagesNone:
+		return "none"
+	case HugePagesTransparent:
+		return "transparent"
+	case HugePagesExplicit:
+		return "explicit"
+	}
+	return "unknown"
+}
+
+// MmapOptions are the optional behaviors of an Mmap given to NewMmap.
+type MmapOptions struct {
+	// Writable maps the file for writing as well as reading; the file must
+	// have been opened for writing.
+	Writable bool
+	// HugePages requests huge pages for the mapping, useful for very large
+	// index files. MAP_HUGETLB is tried first, then a regular mapping with a
+	// transparent huge page hint, then a plain regular mapping; the
+	// HugePages method reports what was granted.
+	HugePages bool
+}
+
+// Mmap is a memory mapping of a file. Implements the io.ReaderAt, io.WriterAt,
+// and io.Closer interfaces.
+//
+// The mapping covers the size of the file when mapped and cannot grow; a file
+// to be written must be sized beforehand, such as with os.File.Truncate.
+type Mmap struct {
+	data     []byte
+	writable bool
+	huge     HugePages
+}
+
+// NewMmap maps all of the file f with the options given, which may be nil.
+func NewMmap(f *os.File, opts *MmapOptions) (*Mmap, error) {
+	if opts == nil {
+		opts = &MmapOptions{}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size <= 0 {
+		return nil, fmt.Errorf("cannot map empty file %s", f.Name())
+	}
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("file %s too large to map", f.Name())
+	}
+	data, huge, err := mmap(f, int(size), opts.Writable, opts.HugePages)
+	if err != nil {
+		return nil, err
+	}
+	return &Mmap{data: data, writable: opts.Writable, huge: huge}, nil
+}
+
+// Bytes returns the mapped memory; it is invalid once the Mmap is closed.
+func (m *Mmap) Bytes() []byte {
+	return m.data
+}
+
+// Len returns the length of the mapping.
+func (m *Mmap) Len() int {
+	return len(m.data)
+}
+
+// HugePages returns what kind of huge page backing the mapping was granted.
+func (m *Mmap) HugePages() HugePages {
+	return m.huge
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (m *Mmap) ReadAt(v []byte, offset int64) (int, error) {
+	if m.data == nil {
+		return 0, fmt.Errorf("closed")
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	if offset >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(v, m.data[offset:])
+	if n < len(v) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// WriteAt implements the io.WriterAt interface; writes may not extend past
+// the end of the mapping.
+func (m *Mmap) WriteAt(v []byte, offset int64) (int, error) {
+	if m.data == nil {
+		return 0, fmt.Errorf("closed")
+	}
+	if !m.writable {
+		return 0, fmt.Errorf("not mapped writable")
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	if offset >= int64(len(m.data)) {
+		return 0, io.ErrShortWrite
+	}
+	n := copy(m.data[offset:], v)
+	if n < len(v) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Close implements the io.Closer interface, unmapping the memory.
+func (m *Mmap) Close() error {
+	if m.data == nil {
+		return fmt.Errorf("already closed")
+	}
+	err := munmap(m.data)
+	m.data = nil
+	return err
+}
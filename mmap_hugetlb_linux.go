@@ -0,0 +1,8 @@
+//go:build linux && !arm
+// +build linux,!arm
+
+package brimio
+
+import "syscall"
+
+const mapHugeTLB = syscall.MAP_HUGETLB
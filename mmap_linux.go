@@ -0,0 +1,30 @@
+package brimio
+
+import (
+	"os"
+	"syscall"
+)
+
+func mmap(f *os.File, size int, writable bool, huge bool) ([]byte, HugePages, error) {
+	prot := syscall.PROT_READ
+	if writable {
+		prot |= syscall.PROT_WRITE
+	}
+	if huge {
+		if data, err := syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED|mapHugeTLB); err == nil {
+			return data, HugePagesExplicit, nil
+		}
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, HugePagesNone, err
+	}
+	if huge && syscall.Madvise(data, syscall.MADV_HUGEPAGE) == nil {
+		return data, HugePagesTransparent, nil
+	}
+	return data, HugePagesNone, nil
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
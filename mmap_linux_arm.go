@@ -0,0 +1,4 @@
+package brimio
+
+// mapHugeTLB is MAP_HUGETLB, which the syscall package lacks for linux/arm.
+const mapHugeTLB = 0x40000
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package brimio
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func mmap(f *os.File, size int, writable bool, huge bool) ([]byte, HugePages, error) {
+	return nil, HugePagesNone, fmt.Errorf("mmap not supported on %s", runtime.GOOS)
+}
+
+func munmap(data []byte) error {
+	return nil
+}
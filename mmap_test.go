@@ -0,0 +1,64 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMmap(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	f.Write([]byte("1234567890"))
+	m, err := NewMmap(f, &MmapOptions{Writable: true, HugePages: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.HugePages().String() == "unknown" {
+		t.Fatal(m.HugePages())
+	}
+	if m.Len() != 10 {
+		t.Fatal(m.Len())
+	}
+	n, err := m.WriteAt([]byte("abc"), 8)
+	if err != io.ErrShortWrite {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatal(n)
+	}
+	v := make([]byte, 5)
+	n, err = m.ReadAt(v, 6)
+	if err != io.EOF {
+		t.Fatal(err)
+	}
+	if string(v[:n]) != "78ab" {
+		t.Fatalf("%#v", string(v[:n]))
+	}
+	if err = m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	v, err = ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678ab" {
+		t.Fatalf("%#v", string(v))
+	}
+	m, err = NewMmap(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err = m.WriteAt([]byte("x"), 0); err == nil {
+		t.Fatal(err)
+	}
+}
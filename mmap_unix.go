@@ -0,0 +1,23 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap has no huge page options on these platforms so always maps regularly.
+func mmap(f *os.File, size int, writable bool, huge bool) ([]byte, HugePages, error) {
+	prot := syscall.PROT_READ
+	if writable {
+		prot |= syscall.PROT_WRITE
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	return data, HugePagesNone, err
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,149 @@
+package brimio
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"hash"
+)
+
+// checksumMatcher is implemented by hashes, such as those from NewMultiHash,
+// that decide for themselves whether a stored checksum matches the content
+// written to them.
+type checksumMatcher interface {
+	matches(stored []byte) bool
+}
+
+// checksumMatches returns whether stored is the checksum of the content
+// written to h, appending h's Sum to scratch if it has to be computed.
+func checksumMatches(h hash.Hash, stored []byte, scratch []byte) bool {
+	if m, ok := h.(checksumMatcher); ok {
+		return m.matches(stored)
+	}
+	return bytes.Equal(stored, h.Sum(scratch))
+}
+
+// NewMultiHash returns a hashing function, for use with any of the
+// constructors taking a func() hash.Hash, whose checksums are those of each
+// of the hashing functions given concatenated, such as a fast CRC alongside
+// a stronger hash for defense in depth. Readers consider an interval checksum
+// valid only if all of its checksums match.
+func NewMultiHash(newHashes ...func() hash.Hash) func() hash.Hash {
+	return func() hash.Hash { return newMultiHash(newHashes, false) }
+}
+
+// NewMultiHashAny is the same as NewMultiHash except readers consider an
+// interval checksum valid if any one of its checksums matches, such as to
+// tolerate damage to just the checksums.
+func NewMultiHashAny(newHashes ...func() hash.Hash) func() hash.Hash {
+	return func() hash.Hash { return newMultiHash(newHashes, true) }
+}
+
+type multiHash struct {
+	hashes []hash.Hash
+	any    bool
+	size   int
+}
+
+func newMultiHash(newHashes []func() hash.Hash, any bool) *multiHash {
+	mh := &multiHash{hashes: make([]hash.Hash, len(newHashes)), any: any}
+	for i, newHash := range newHashes {
+		mh.hashes[i] = newHash()
+		mh.size += mh.hashes[i].Size()
+	}
+	return mh
+}
+
+func (mh *multiHash) Write(v []byte) (int, error) {
+	for _, h := range mh.hashes {
+		h.Write(v)
+	}
+	return len(v), nil
+}
+
+func (mh *multiHash) Sum(b []byte) []byte {
+	for _, h := range mh.hashes {
+		b = h.Sum(b)
+	}
+	return b
+}
+
+func (mh *multiHash) Reset() {
+	for _, h := range mh.hashes {
+		h.Reset()
+	}
+}
+
+// setIndex passes the interval's index on to the hashes that care, such as
+// those from NewIndexedHash.
+func (mh *multiHash) setIndex(index int64) {
+	for _, h := range mh.hashes {
+		setChecksumIndex(h, index)
+	}
+}
+
+func (mh *multiHash) Size() int {
+	return mh.size
+}
+
+func (mh *multiHash) BlockSize() int {
+	if len(mh.hashes) == 0 {
+		return 1
+	}
+	return mh.hashes[0].BlockSize()
+}
+
+func (mh *multiHash) matches(stored []byte) bool {
+	if len(stored) != mh.size {
+		return false
+	}
+	var sum []byte
+	for _, h := range mh.hashes {
+		sum = h.Sum(sum[:0])
+		ok := bytes.Equal(stored[:len(sum)], sum)
+		stored = stored[len(sum):]
+		if ok && mh.any {
+			return true
+		}
+		if !ok && !mh.any {
+			return false
+		}
+	}
+	return !mh.any
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, if each of
+// the hashes does, so ChecksummedWriter checkpoints work with a multiHash.
+func (mh *multiHash) MarshalBinary() ([]byte, error) {
+	var u [binary.MaxVarintLen64]byte
+	var b []byte
+	for _, h := range mh.hashes {
+		state, err := marshalHash(h)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, u[:binary.PutUvarint(u[:], uint64(len(state)))]...)
+		b = append(b, state...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (mh *multiHash) UnmarshalBinary(b []byte) error {
+	for _, h := range mh.hashes {
+		l, n := binary.Uvarint(b)
+		if n <= 0 || uint64(len(b)-n) < l {
+			return fmt.Errorf("truncated hash state")
+		}
+		u, ok := h.(encoding.BinaryUnmarshaler)
+		if !ok {
+			return fmt.Errorf("hash %T does not support checkpoints", h)
+		}
+		if err := u.UnmarshalBinary(b[n : n+int(l)]); err != nil {
+			return err
+		}
+		b = b[n+int(l):]
+	}
+	return nil
+}
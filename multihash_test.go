@@ -0,0 +1,44 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
+	"testing"
+)
+
+func TestMultiHash(t *testing.T) {
+	newCRC := func() hash.Hash { return crc32.NewIEEE() }
+	newFNV := func() hash.Hash { return fnv.New64a() }
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriterHash(buf, 16, NewMultiHash(newCRC, newFNV))
+	cw.Write([]byte("12345678901234567890"))
+	cw.Close()
+	if buf.Len() != 16+4+8+4 {
+		t.Fatal(buf.Len())
+	}
+	h := fnv.New64a()
+	h.Write([]byte("1234567890123456"))
+	if !bytes.Equal(buf.Bytes()[20:28], h.Sum(nil)) {
+		t.Fatalf("%#v", buf.Bytes())
+	}
+	for _, test := range []struct {
+		corrupt int
+		all     bool
+		any     bool
+	}{{-1, true, true}, {17, false, true}, {22, false, true}, {3, false, false}} {
+		b := append([]byte{}, buf.Bytes()...)
+		if test.corrupt >= 0 {
+			b[test.corrupt] ^= 0xff
+		}
+		ok, err := NewChecksummedReaderHash(bytes.NewReader(b), 16, NewMultiHash(newCRC, newFNV)).Verify()
+		if err != nil || ok != test.all {
+			t.Fatal(test, ok, err)
+		}
+		ok, err = NewChecksummedReaderHash(bytes.NewReader(b), 16, NewMultiHashAny(newCRC, newFNV)).Verify()
+		if err != nil || ok != test.any {
+			t.Fatal(test, ok, err)
+		}
+	}
+}
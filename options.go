@@ -0,0 +1,464 @@
+package brimio
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// DefaultChecksumInterval is the interval NewChecksummedWriterWith and
+// NewChecksummedReaderWith use when not given WithInterval.
+const DefaultChecksumInterval = 65536
+
+// ChecksummedOption configures NewChecksummedWriterWith or
+// NewChecksummedReaderWith. Options that only apply to one are ignored by the
+// other.
+type ChecksummedOption func(*checksummedOptions)
+
+type checksummedOptions struct {
+	interval         int
+	newHash          func() hash.Hash
+	hashSet          bool
+	hashName         string
+	header           bool
+	footer           bool
+	padFinal         bool
+	cores            int
+	buffers          int
+	placement        ChecksumPlacement
+	keepDelegateOpen bool
+	synchronized     bool
+	sync             bool
+	syncEvery        int
+	sparse           int
+	reader           ChecksummedReaderOptions
+}
+
+// WithInterval sets the interval of content between checksums;
+// DefaultChecksumInterval if not given.
+func WithInterval(interval int) ChecksummedOption {
+	return func(o *checksummedOptions) { o.interval = interval }
+}
+
+// WithHash sets the hashing function used for checksums; CRC32 IEEE if not
+// given.
+func WithHash(newHash func() hash.Hash) ChecksummedOption {
+	return func(o *checksummedOptions) {
+		o.newHash = newHash
+		o.hashSet = true
+	}
+}
+
+// WithHashName sets the hashing function used for checksums to the one
+// registered under name with RegisterChecksummedHeaderHash or built in, as
+// needed for the writer to write a header.
+func WithHashName(name string) ChecksummedOption {
+	return func(o *checksummedOptions) { o.hashName = name }
+}
+
+// WithHeader has the writer start with a ChecksummedHeader describing the
+// content, and the reader take the interval and hashing function from that
+// header instead of its other options; offsets are then relative to the
+// content after the header.
+func WithHeader() ChecksummedOption {
+	return func(o *checksummedOptions) { o.header = true }
+}
+
+// WithFooter has the writer end the content at Close with a
+// ChecksummedFooter recording its length and digest, and the reader check
+// for that footer, returning ErrNoChecksummedFooter if it is missing or
+// records a different length, such as when the content was cut short.
+// Offsets and sizes are then those of the content before the footer. The
+// digest is only checked by VerifyChecksummedFooter.
+func WithFooter() ChecksummedOption {
+	return func(o *checksummedOptions) { o.footer = true }
+}
+
+// WithPadFinal has the writer zero pad any final partial interval to a
+// whole one, checksummed like the rest, so every interval of the underlying
+// content is the same size, and the reader hide that padding again. The
+// length of the content before padding is recorded in the footer, so this
+// requires WithFooter.
+func WithPadFinal() ChecksummedOption {
+	return func(o *checksummedOptions) { o.padFinal = true }
+}
+
+// WithCores has the writer hash intervals in parallel on the number of cores
+// given, with the number of interval sized buffers given bounding its memory
+// use, as NewPooledChecksummedWriter does.
+func WithCores(cores int, buffers int) ChecksummedOption {
+	return func(o *checksummedOptions) {
+		o.cores = cores
+		o.buffers = buffers
+	}
+}
+
+// WithSparse has a checksum cover every n intervals at once rather than
+// each one, cutting the overhead of hashing and storing checksums for very
+// high throughput streams at the cost of verifying, and reporting
+// corruption, at that coarser granularity. The reader must be given the
+// same n, unless the layout comes from a header written WithHeader, which
+// records the combined interval.
+func WithSparse(n int) ChecksummedOption {
+	return func(o *checksummedOptions) { o.sparse = n }
+}
+
+// WithPlacement sets where checksums are stored; ChecksumTrailing if not
+// given.
+func WithPlacement(placement ChecksumPlacement) ChecksummedOption {
+	return func(o *checksummedOptions) { o.placement = placement }
+}
+
+// WithKeepDelegateOpen has Close and CloseWithError behave as
+// CloseWithoutDelegate, leaving the underlying content open.
+func WithKeepDelegateOpen() ChecksummedOption {
+	return func(o *checksummedOptions) { o.keepDelegateOpen = true }
+}
+
+// WithSyncEvery has the writer call Sync at every Flush and, if intervals is
+// greater than 0, whenever that many more intervals have been checksummed
+// since the last, so a durability policy can be set once rather than by
+// every caller. As with Sync itself, this does nothing unless the underlying
+// io.Writer has a Sync method, as *os.File does. A Sync failing is returned
+// by the Write, ReadFrom, or Flush that called it.
+func WithSyncEvery(intervals int) ChecksummedOption {
+	return func(o *checksummedOptions) {
+		o.sync = true
+		o.syncEvery = intervals
+	}
+}
+
+// WithSynchronized has the reader guard every call with a mutex, as
+// NewSynchronizedChecksummedReader does; it has no effect on writers.
+func WithSynchronized() ChecksummedOption {
+	return func(o *checksummedOptions) { o.synchronized = true }
+}
+
+// WithAutoVerify sets ChecksummedReaderOptions.AutoVerify.
+func WithAutoVerify() ChecksummedOption {
+	return func(o *checksummedOptions) { o.reader.AutoVerify = true }
+}
+
+// WithFlushed sets ChecksummedReaderOptions.Flushed.
+func WithFlushed() ChecksummedOption {
+	return func(o *checksummedOptions) { o.reader.Flushed = true }
+}
+
+// WithOnCorruption sets ChecksummedReaderOptions.OnCorruption.
+func WithOnCorruption(onCorruption func(blockIndex int64, offset int64)) ChecksummedOption {
+	return func(o *checksummedOptions) { o.reader.OnCorruption = onCorruption }
+}
+
+// WithCache sets ChecksummedReaderOptions.Cache.
+func WithCache(cache *VerifiedBlockCache) ChecksummedOption {
+	return func(o *checksummedOptions) { o.reader.Cache = cache }
+}
+
+// WithSkipCorrupted sets ChecksummedReaderOptions.SkipCorrupted, and
+// ZeroCorrupted as given.
+func WithSkipCorrupted(zero bool) ChecksummedOption {
+	return func(o *checksummedOptions) {
+		o.reader.SkipCorrupted = true
+		o.reader.ZeroCorrupted = zero
+	}
+}
+
+// WithReplaceCorrupted sets ChecksummedReaderOptions.ReplaceCorrupted.
+func WithReplaceCorrupted(replace func(blockIndex int64, offset int64, block []byte) bool) ChecksummedOption {
+	return func(o *checksummedOptions) { o.reader.ReplaceCorrupted = replace }
+}
+
+// withReaderOptions sets all of the ChecksummedReaderOptions at once, as
+// NewChecksummedReaderWithOptions is given them.
+func withReaderOptions(opts *ChecksummedReaderOptions) ChecksummedOption {
+	return func(o *checksummedOptions) {
+		if opts != nil {
+			o.reader = *opts
+			o.placement = opts.Placement
+		}
+	}
+}
+
+func newChecksummedOptions(opts []ChecksummedOption) (*checksummedOptions, error) {
+	o := &checksummedOptions{interval: DefaultChecksumInterval}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.sparse != 0 {
+		if o.sparse < 1 || o.interval > MaxChecksumInterval/o.sparse {
+			return nil, fmt.Errorf("invalid sparse %d for interval %d", o.sparse, o.interval)
+		}
+		o.interval *= o.sparse
+	}
+	if o.hashName != "" {
+		checksummedHeaderHashesLock.RLock()
+		o.newHash = checksummedHeaderHashes[o.hashName]
+		checksummedHeaderHashesLock.RUnlock()
+		if o.newHash == nil {
+			return nil, fmt.Errorf("unknown hash %q", o.hashName)
+		}
+	} else if !o.hashSet {
+		o.newHash = func() hash.Hash { return crc32.NewIEEE() }
+		o.hashName = "crc32-ieee"
+	}
+	return o, nil
+}
+
+// NewChecksummedWriterWith returns a ChecksummedWriter that delegates
+// requests to an underlying io.Writer and embeds checksums of the content as
+// configured by the options given, or an error if they can't be used.
+//
+// The other ChecksummedWriter constructors are shorthand for this one with
+// particular options.
+func NewChecksummedWriterWith(delegate io.Writer, opts ...ChecksummedOption) (ChecksummedWriter, error) {
+	o, err := newChecksummedOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateChecksummed(o.interval, o.newHash); err != nil {
+		return nil, err
+	}
+	if o.padFinal && !o.footer {
+		return nil, fmt.Errorf("pad final requires WithFooter")
+	}
+	if o.syncEvery < 0 {
+		return nil, fmt.Errorf("invalid sync every %d", o.syncEvery)
+	}
+	if o.cores != 0 || o.buffers != 0 {
+		if o.cores < 1 || o.buffers < 1 {
+			return nil, fmt.Errorf("invalid cores %d or buffers %d", o.cores, o.buffers)
+		}
+		if o.placement != ChecksumTrailing {
+			return nil, fmt.Errorf("multiple cores not supported with leading checksums")
+		}
+	}
+	if o.header {
+		if o.hashName == "" {
+			return nil, fmt.Errorf("header requires WithHashName")
+		}
+		h := ChecksummedHeader{
+			Version:      ChecksummedHeaderVersion,
+			Interval:     o.interval,
+			HashName:     o.hashName,
+			ChecksumSize: o.newHash().Size(),
+		}
+		if err = WriteChecksummedHeader(delegate, h); err != nil {
+			return nil, err
+		}
+	}
+	var cw ChecksummedWriter
+	switch {
+	case o.cores != 0:
+		cw = newMultiCoreChecksummedWriter(delegate, o.interval, o.newHash, o.cores, o.buffers)
+	case o.placement != ChecksumTrailing:
+		lw := &leadingChecksumWriter{delegate: delegate, interval: o.interval}
+		lw.buf = make([]byte, 0, o.interval+o.newHash().Size())
+		cw = &leadingChecksummedWriter{
+			checksummedWriterImpl: newChecksummedWriterImpl(lw, o.interval, o.newHash),
+			lw:                    lw,
+		}
+	default:
+		cw = newChecksummedWriterImpl(delegate, o.interval, o.newHash)
+	}
+	if o.sync {
+		cw = &syncingChecksummedWriter{ChecksummedWriter: cw, interval: o.interval, every: uint64(o.syncEvery)}
+	}
+	if o.footer {
+		fcw := newFooterChecksummedWriter(cw, delegate)
+		if o.padFinal {
+			fcw.padInterval = o.interval
+		}
+		cw = fcw
+	}
+	if o.keepDelegateOpen {
+		cw = &keepOpenChecksummedWriter{cw}
+	}
+	return cw, nil
+}
+
+// NewChecksummedReaderWith returns a ChecksummedReader that delegates
+// requests to an underlying io.ReadSeeker expecting checksums of the content
+// as configured by the options given, or an error if they can't be used.
+//
+// The other ChecksummedReader constructors are shorthand for this one with
+// particular options.
+func NewChecksummedReaderWith(delegate io.ReadSeeker, opts ...ChecksummedOption) (ChecksummedReader, error) {
+	o, err := newChecksummedOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.header {
+		if _, err = delegate.Seek(0, 0); err != nil {
+			return nil, err
+		}
+		h, err := ReadChecksummedHeader(delegate)
+		if err != nil {
+			return nil, err
+		}
+		if o.newHash, err = h.NewHash(); err != nil {
+			return nil, err
+		}
+		o.interval = h.Interval
+		delegate = &offsetReadSeeker{delegate: delegate, base: int64(h.Len())}
+	}
+	if err = validateChecksummed(o.interval, o.newHash); err != nil {
+		return nil, err
+	}
+	if o.padFinal && !o.footer {
+		return nil, fmt.Errorf("pad final requires WithFooter")
+	}
+	var footer ChecksummedFooter
+	if o.footer {
+		if delegate, footer, err = newFooterReadSeeker(delegate, o.interval, o.newHash().Size(), o.padFinal); err != nil {
+			return nil, err
+		}
+	}
+	if o.placement == ChecksumLeading {
+		delegate = newLeadingChecksumReadSeeker(delegate, o.interval, o.newHash().Size())
+	}
+	cri := newChecksummedReaderImpl(delegate, o.interval, o.newHash)
+	r := &o.reader
+	if r.AutoVerify || r.Cache != nil || r.SkipCorrupted || r.ReplaceCorrupted != nil || r.Flushed {
+		cri.block = make([]byte, cri.blockSize())
+		cri.blockIndex = -1
+	}
+	cri.onCorruption = r.OnCorruption
+	cri.cache = r.Cache
+	cri.skipCorrupted = r.SkipCorrupted
+	cri.zeroCorrupted = r.ZeroCorrupted
+	cri.replaceCorrupted = r.ReplaceCorrupted
+	cri.flushed = r.Flushed
+	var cr ChecksummedReader = cri
+	if o.keepDelegateOpen {
+		cr = &keepOpenChecksummedReader{cri}
+	}
+	if o.padFinal {
+		cr = &paddedChecksummedReader{ChecksummedReader: cr, length: footer.Length}
+	}
+	if o.synchronized {
+		cr = NewSynchronizedChecksummedReader(cr)
+	}
+	return cr, nil
+}
+
+// mustChecksummedWriter panics with err, if any, for the constructors that
+// have always panicked on options that can't be used.
+func mustChecksummedWriter(cw ChecksummedWriter, err error) ChecksummedWriter {
+	if err != nil {
+		panic(err)
+	}
+	return cw
+}
+
+// mustChecksummedReader is mustChecksummedWriter for readers.
+func mustChecksummedReader(cr ChecksummedReader, err error) ChecksummedReader {
+	if err != nil {
+		panic(err)
+	}
+	return cr
+}
+
+// keepOpenChecksummedWriter is a ChecksummedWriter whose Close leaves the
+// underlying io.Writer open, for WithKeepDelegateOpen.
+type keepOpenChecksummedWriter struct {
+	ChecksummedWriter
+}
+
+func (kw *keepOpenChecksummedWriter) Close() error {
+	return kw.CloseWithoutDelegate()
+}
+
+func (kw *keepOpenChecksummedWriter) CloseWithError(err error) error {
+	return kw.CloseWithoutDelegate()
+}
+
+// syncingChecksummedWriter is a ChecksummedWriter that calls Sync as
+// configured by WithSyncEvery. Large writes are passed on in pieces of every
+// intervals so none goes long without a Sync.
+type syncingChecksummedWriter struct {
+	ChecksummedWriter
+	interval int
+	every    uint64
+	// synced is the count of checksums emitted at the last Sync.
+	synced uint64
+}
+
+// sync calls Sync if every intervals have been checksummed since the last
+// time.
+func (scw *syncingChecksummedWriter) sync() error {
+	emitted := scw.Stats().ChecksumsEmitted
+	if emitted-scw.synced < scw.every {
+		return nil
+	}
+	scw.synced = emitted
+	return scw.Sync()
+}
+
+func (scw *syncingChecksummedWriter) Write(v []byte) (int, error) {
+	if scw.every == 0 {
+		return scw.ChecksummedWriter.Write(v)
+	}
+	size := int(scw.every) * scw.interval
+	var n int
+	for len(v) > 0 {
+		piece := v
+		if size > 0 && len(piece) > size {
+			piece = piece[:size]
+		}
+		m, err := scw.ChecksummedWriter.Write(piece)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		if err = scw.sync(); err != nil {
+			return n, err
+		}
+		v = v[m:]
+	}
+	return n, nil
+}
+
+func (scw *syncingChecksummedWriter) WriteContext(ctx context.Context, v []byte) (int, error) {
+	return writeContext(ctx, scw, v, scw.interval)
+}
+
+func (scw *syncingChecksummedWriter) ReadFrom(r io.Reader) (int64, error) {
+	if scw.every == 0 {
+		return scw.ChecksummedWriter.ReadFrom(r)
+	}
+	size := int64(scw.every) * int64(scw.interval)
+	var total int64
+	for {
+		n, err := scw.ChecksummedWriter.ReadFrom(io.LimitReader(r, size))
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if err = scw.sync(); err != nil {
+			return total, err
+		}
+		if n < size {
+			return total, nil
+		}
+	}
+}
+
+func (scw *syncingChecksummedWriter) Flush() error {
+	if err := scw.ChecksummedWriter.Flush(); err != nil {
+		return err
+	}
+	scw.synced = scw.Stats().ChecksumsEmitted
+	return scw.Sync()
+}
+
+// keepOpenChecksummedReader is a ChecksummedReader whose Close leaves the
+// underlying io.ReadSeeker open, for WithKeepDelegateOpen.
+type keepOpenChecksummedReader struct {
+	*checksummedReaderImpl
+}
+
+func (kr *keepOpenChecksummedReader) Close() error {
+	return kr.CloseWithoutDelegate()
+}
@@ -0,0 +1,180 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+type closeCounter struct {
+	bytes.Buffer
+	closes int
+}
+
+func (cc *closeCounter) Close() error {
+	cc.closes++
+	return nil
+}
+
+type syncCounter struct {
+	bytes.Buffer
+	syncs []int
+}
+
+func (sc *syncCounter) Sync() error {
+	sc.syncs = append(sc.syncs, sc.Len())
+	return nil
+}
+
+func TestChecksummedOptions(t *testing.T) {
+	buf := &closeCounter{}
+	cw, err := NewChecksummedWriterWith(buf, WithInterval(16), WithHashName("sha256"), WithHeader(), WithKeepDelegateOpen())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("12345678901234567890"))
+	if err = cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.closes != 0 {
+		t.Fatal(buf.closes)
+	}
+	// The same as the older constructor.
+	buf2 := &bytes.Buffer{}
+	cw2, err := NewChecksummedWriterWithHeader(buf2, 16, "sha256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw2.Write([]byte("12345678901234567890"))
+	cw2.Close()
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Fatalf("%#v", buf.String())
+	}
+	var corrupted []int64
+	cr, err := NewChecksummedReaderWith(bytes.NewReader(buf.Bytes()), WithHeader(), WithAutoVerify(), WithOnCorruption(func(blockIndex int64, offset int64) {
+		corrupted = append(corrupted, blockIndex)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345678901234567890" || corrupted != nil {
+		t.Fatalf("%#v %#v", string(v), corrupted)
+	}
+	// Defaults are DefaultChecksumInterval and CRC32 IEEE.
+	buf2.Reset()
+	cw, err = NewChecksummedWriterWith(buf2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write(make([]byte, DefaultChecksumInterval))
+	cw.Close()
+	if buf2.Len() != DefaultChecksumInterval+4 {
+		t.Fatal(buf2.Len())
+	}
+	cr, err = NewChecksummedReaderWith(bytes.NewReader(buf2.Bytes()), WithSkipCorrupted(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid, err := cr.Verify(); err != nil || !valid {
+		t.Fatalf("%#v %#v", valid, err)
+	}
+	for _, opts := range [][]ChecksummedOption{
+		{WithInterval(0)},
+		{WithHash(nil)},
+		{WithHashName("nope")},
+		{WithHash(sha256.New), WithHeader()},
+		{WithCores(0, 2)},
+		{WithCores(2, 2), WithPlacement(ChecksumLeading)},
+	} {
+		if _, err = NewChecksummedWriterWith(&bytes.Buffer{}, opts...); err == nil {
+			t.Fatalf("%#v", opts)
+		}
+	}
+	if _, err = NewChecksummedReaderWith(bytes.NewReader(buf2.Bytes()), WithHeader()); err != ErrNoChecksummedHeader {
+		t.Fatal(err)
+	}
+}
+
+func TestChecksummedSparse(t *testing.T) {
+	content := "12345678901234567890ghijklmnopqrstuvwxyz"
+	buf := &bytes.Buffer{}
+	cw, err := NewChecksummedWriterWith(buf, WithInterval(4), WithSparse(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte(content))
+	cw.Close()
+	// One checksum per 16 bytes rather than per 4.
+	if buf.Len() != 48 {
+		t.Fatal(buf.Len())
+	}
+	b := buf.Bytes()
+	b[25] = 'X'
+	var corrupted []int64
+	cr, err := NewChecksummedReaderWith(bytes.NewReader(b), WithInterval(4), WithSparse(4), WithOnCorruption(func(blockIndex int64, offset int64) {
+		corrupted = append(corrupted, blockIndex)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := cr.VerifyAll(nil); err != nil || len(c) != 1 || c[0] != 1 {
+		t.Fatal(c, err)
+	}
+	v, err := ioutil.ReadAll(cr)
+	if err != nil || string(v) != content[:21]+"X"+content[22:] {
+		t.Fatal(string(v), err)
+	}
+	for _, sparse := range []int{-1, MaxChecksumInterval} {
+		if _, err = NewChecksummedWriterWith(buf, WithInterval(4), WithSparse(sparse)); err == nil {
+			t.Fatal(sparse)
+		}
+	}
+}
+
+func TestChecksummedSyncEvery(t *testing.T) {
+	content := []byte("12345678901234567890ghijklmnopqrstuvwxyz")
+	sc := &syncCounter{}
+	cw, err := NewChecksummedWriterWith(sc, WithInterval(4), WithSyncEvery(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A single large Write still syncs every 3 intervals of 8 bytes.
+	cw.Write(content[:30])
+	if fmt.Sprint(sc.syncs) != "[24 48]" {
+		t.Fatal(sc.syncs)
+	}
+	if _, err = cw.ReadFrom(bytes.NewReader(content[30:])); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sc.syncs) != "[24 48 80]" {
+		t.Fatal(sc.syncs)
+	}
+	if err = cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sc.syncs) != "[24 48 80 80]" {
+		t.Fatal(sc.syncs)
+	}
+	// With 0, only Flush syncs.
+	sc = &syncCounter{}
+	if cw, err = NewChecksummedWriterWith(sc, WithInterval(4), WithSyncEvery(0)); err != nil {
+		t.Fatal(err)
+	}
+	cw.Write(content)
+	if len(sc.syncs) != 0 {
+		t.Fatal(sc.syncs)
+	}
+	cw.Flush()
+	if fmt.Sprint(sc.syncs) != "[80]" {
+		t.Fatal(sc.syncs)
+	}
+	if _, err = NewChecksummedWriterWith(sc, WithSyncEvery(-1)); err == nil {
+		t.Fatal("expected error")
+	}
+}
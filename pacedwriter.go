@@ -0,0 +1,72 @@
+package brimio
+
+import (
+	"io"
+	"time"
+)
+
+// PacedWriter spreads large writes to an underlying io.Writer into evenly
+// spaced smaller ones, so a burst of background writes to a shared disk
+// doesn't stall co-located foreground I/O behind it.
+//
+// Each Write is passed on in pieces of at most MaxBurst bytes, with at least
+// Interval between the start of one piece and the next; writes arriving after
+// a quiet period go out at once. A MaxBurst or Interval of 0 disables pacing.
+type PacedWriter struct {
+	MaxBurst int
+	Interval time.Duration
+	// Clock is the source of time for pacing; SystemClock if nil.
+	Clock    Clock
+	delegate io.Writer
+	next     time.Time
+}
+
+// NewPacedWriter returns a PacedWriter that delegates to an underlying
+// io.Writer, writing at most maxBurst bytes every interval.
+func NewPacedWriter(delegate io.Writer, maxBurst int, interval time.Duration) *PacedWriter {
+	return &PacedWriter{MaxBurst: maxBurst, Interval: interval, delegate: delegate}
+}
+
+// Write implements the io.Writer interface, returning once the last piece of
+// p has been written.
+func (pw *PacedWriter) Write(p []byte) (int, error) {
+	if pw.MaxBurst <= 0 || pw.Interval <= 0 {
+		return pw.delegate.Write(p)
+	}
+	var total int
+	for len(p) > 0 {
+		clock := clockOrSystem(pw.Clock)
+		now := clock.Now()
+		if d := pw.next.Sub(now); d > 0 {
+			clock.Sleep(d)
+			now = pw.next
+		}
+		pw.next = now.Add(pw.Interval)
+		piece := p
+		if len(piece) > pw.MaxBurst {
+			piece = piece[:pw.MaxBurst]
+		}
+		n, err := pw.delegate.Write(piece)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < len(piece) {
+			return total, io.ErrShortWrite
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+// Close implements the io.Closer interface, closing the underlying io.Writer
+// if it can be closed.
+func (pw *PacedWriter) Close() error {
+	return closeDelegate(pw.delegate, nil)
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.Writer.
+func (pw *PacedWriter) Unwrap() interface{} {
+	return unwrapDelegate(pw.delegate)
+}
@@ -0,0 +1,49 @@
+package brimio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type pieceRecorder struct {
+	clock  Clock
+	pieces []int
+	times  []time.Time
+	buf    bytes.Buffer
+}
+
+func (pr *pieceRecorder) Write(p []byte) (int, error) {
+	pr.pieces = append(pr.pieces, len(p))
+	pr.times = append(pr.times, pr.clock.Now())
+	return pr.buf.Write(p)
+}
+
+func TestPacedWriter(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	pr := &pieceRecorder{clock: clock}
+	pw := NewPacedWriter(pr, 4, 10*time.Millisecond)
+	pw.Clock = clock
+	n, err := pw.Write([]byte("1234567890"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 10 || pr.buf.String() != "1234567890" {
+		t.Fatal(n, pr.buf.String())
+	}
+	if len(pr.pieces) != 3 || pr.pieces[0] != 4 || pr.pieces[1] != 4 || pr.pieces[2] != 2 {
+		t.Fatal(pr.pieces)
+	}
+	if d := pr.times[2].Sub(pr.times[0]); d != 20*time.Millisecond {
+		t.Fatal(d)
+	}
+	pw.MaxBurst = 0
+	pr.pieces = nil
+	pw.Write([]byte("1234567890"))
+	if len(pr.pieces) != 1 || pr.pieces[0] != 10 {
+		t.Fatal(pr.pieces)
+	}
+	if err = pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
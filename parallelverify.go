@@ -0,0 +1,126 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelVerifyBatch is how many intervals each worker of VerifyParallel
+// claims at a time, so reads stay large and sequential per worker.
+const parallelVerifyBatch = 64
+
+// VerifyParallel verifies every complete interval of the size bytes of
+// checksummed content in r, checksums included, with workers goroutines
+// reading disjoint runs of intervals concurrently, such as to keep a fast
+// device busy. It returns the indexes, in order, of the intervals that are
+// not checksum valid, or nil if all are valid. As with VerifyAll, any
+// trailing partial interval is not verified.
+//
+// The first read error stops all the workers and is returned.
+func VerifyParallel(r io.ReaderAt, size int64, cfg ChecksummedConfig, workers int) ([]int64, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return verifyParallel(r, size, cfg, workers, nil)
+}
+
+// VerifyParallelPooled is VerifyParallel with a worker for each partition of
+// pool, each locked to its OS thread and reading into buffers from its own
+// partition, so on NUMA systems the memory a worker reads into can stay
+// local to the node it runs on. Each read fills as many whole intervals as
+// fit in one of the pool's buffers, which must fit at least one.
+func VerifyParallelPooled(r io.ReaderAt, size int64, cfg ChecksummedConfig, pool *BufferPool) ([]int64, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if blockSize := cfg.Interval + cfg.NewHash().Size(); pool.Size() < blockSize {
+		return nil, fmt.Errorf("buffer size %d less than interval and checksum size %d", pool.Size(), blockSize)
+	}
+	return verifyParallel(r, size, cfg, pool.Partitions(), pool)
+}
+
+// verifyParallel is VerifyParallel and VerifyParallelPooled, with pool nil
+// for the former.
+func verifyParallel(r io.ReaderAt, size int64, cfg ChecksummedConfig, workers int, pool *BufferPool) ([]int64, error) {
+	blockSize := int64(cfg.Interval + cfg.NewHash().Size())
+	blocks := size / blockSize
+	var next int64
+	var failed int32
+	var lock sync.Mutex
+	var corrupt []int64
+	var firstErr error
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			var buf []byte
+			if pool != nil {
+				// The thread keeps the worker, and the memory it first
+				// touches, on one node as far as the operating system
+				// allows.
+				runtime.LockOSThread()
+				defer runtime.UnlockOSThread()
+				buf = pool.Get(worker)
+				defer pool.Put(worker, buf)
+			} else {
+				buf = make([]byte, blockSize)
+			}
+			perRead := int64(len(buf)) / blockSize
+			hash := cfg.NewHash()
+			var found []int64
+			for atomic.LoadInt32(&failed) == 0 {
+				first := atomic.AddInt64(&next, parallelVerifyBatch) - parallelVerifyBatch
+				if first >= blocks {
+					break
+				}
+				last := first + parallelVerifyBatch
+				if last > blocks {
+					last = blocks
+				}
+				for i := first; i < last; i += perRead {
+					count := perRead
+					if i+count > last {
+						count = last - i
+					}
+					b := buf[:count*blockSize]
+					// io.ReaderAt allows io.EOF with a full read at the end.
+					if n, err := r.ReadAt(b, i*blockSize); err != nil && !(err == io.EOF && n == len(b)) {
+						lock.Lock()
+						if firstErr == nil {
+							firstErr = err
+						}
+						lock.Unlock()
+						atomic.StoreInt32(&failed, 1)
+						return
+					}
+					for j := int64(0); j < count; j++ {
+						block := b[j*blockSize : (j+1)*blockSize]
+						hash.Reset()
+						setChecksumIndex(hash, i+j)
+						hash.Write(block[:cfg.Interval])
+						if !checksumMatches(hash, block[cfg.Interval:], nil) {
+							found = append(found, i+j)
+						}
+					}
+				}
+			}
+			lock.Lock()
+			corrupt = append(corrupt, found...)
+			lock.Unlock()
+		}(w)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	sort.Slice(corrupt, func(i int, j int) bool { return corrupt[i] < corrupt[j] })
+	return corrupt, nil
+}
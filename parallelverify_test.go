@@ -0,0 +1,90 @@
+package brimio
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+func TestVerifyParallel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(cw, "%016d", i)
+	}
+	cw.Write([]byte("partial"))
+	cw.Close()
+	b := buf.Bytes()
+	for _, i := range []int{3, 70, 199} {
+		b[i*20+5] ^= 0xff
+	}
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	for _, workers := range []int{0, 1, 4} {
+		corrupt, err := VerifyParallel(bytes.NewReader(b), int64(len(b)), cfg, workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(corrupt) != "[3 70 199]" {
+			t.Fatal(workers, corrupt)
+		}
+	}
+	if _, err := VerifyParallel(bytes.NewReader(b[:100]), int64(len(b)), cfg, 2); err == nil {
+		t.Fatal(err)
+	}
+	whole := b[:200*20]
+	if corrupt, err := VerifyParallel(eofReaderAt{bytes.NewReader(whole)}, int64(len(whole)), cfg, 2); err != nil || fmt.Sprint(corrupt) != "[3 70 199]" {
+		t.Fatal(corrupt, err)
+	}
+}
+
+func TestVerifyParallelPooled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(cw, "%016d", i)
+	}
+	cw.Write([]byte("partial"))
+	cw.Close()
+	b := buf.Bytes()
+	for _, i := range []int{3, 70, 199} {
+		b[i*20+5] ^= 0xff
+	}
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	// Buffers of one interval, several with room left over, and more than
+	// a worker's whole batch.
+	for _, size := range []int{20, 65, 2000} {
+		pool := NewBufferPool(size, 3)
+		for i := 0; i < 2; i++ {
+			corrupt, err := VerifyParallelPooled(eofReaderAt{bytes.NewReader(b)}, int64(len(b)), cfg, pool)
+			if err != nil {
+				t.Fatal(size, err)
+			}
+			if fmt.Sprint(corrupt) != "[3 70 199]" {
+				t.Fatal(size, corrupt)
+			}
+		}
+	}
+	if _, err := VerifyParallelPooled(bytes.NewReader(b), int64(len(b)), cfg, NewBufferPool(19, 2)); err == nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyParallelPooled(bytes.NewReader(b[:100]), int64(len(b)), cfg, NewBufferPool(200, 2)); err == nil {
+		t.Fatal(err)
+	}
+}
+
+// eofReaderAt returns io.EOF along with reads that reach the end of its
+// content, as io.ReaderAt allows.
+type eofReaderAt struct {
+	*bytes.Reader
+}
+
+func (r eofReaderAt) ReadAt(v []byte, offset int64) (int, error) {
+	n, err := r.Reader.ReadAt(v, offset)
+	if err == nil && offset+int64(n) == r.Size() {
+		err = io.EOF
+	}
+	return n, err
+}
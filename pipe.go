@@ -0,0 +1,85 @@
+package brimio
+
+import (
+	"os"
+	"sync"
+)
+
+// PipeEndpoint is one end of a named pipe; a FIFO on Unix systems or a named
+// pipe on Windows. Implements the io.ReadWriteCloser interface.
+//
+// OpenPipeEndpoint returns without waiting for a peer on all platforms; the
+// first Read or Write will block until the other side has connected and the
+// operation can proceed.
+//
+// Unix FIFOs carry data in a single direction, so there the first Read or
+// Write decides which end of the FIFO this endpoint is, and each side must
+// then only read or only write; Windows named pipes are opened duplex.
+type PipeEndpoint struct {
+	file        *os.File
+	name        string
+	created     bool
+	connect     func(write bool) error
+	connectOnce sync.Once
+	connectErr  error
+}
+
+// OpenPipeEndpoint opens the named pipe name, creating it if it does not yet
+// exist. On Unix name is a file system path; on Windows it is a pipe name
+// which will be prefixed with `\\.\pipe\` if it isn't already.
+//
+// The endpoint that created the pipe will also clean it up on Close, removing
+// the FIFO from the file system on Unix.
+func OpenPipeEndpoint(name string) (*PipeEndpoint, error) {
+	return openPipeEndpoint(name)
+}
+
+// Name returns the platform specific name of the pipe.
+func (p *PipeEndpoint) Name() string {
+	return p.name
+}
+
+// Created returns true if this endpoint created the pipe and so will clean it
+// up on Close.
+func (p *PipeEndpoint) Created() bool {
+	return p.created
+}
+
+// Read implements the io.Reader interface.
+func (p *PipeEndpoint) Read(v []byte) (int, error) {
+	if err := p.ready(false); err != nil {
+		return 0, err
+	}
+	return p.file.Read(v)
+}
+
+// Write implements the io.Writer interface.
+func (p *PipeEndpoint) Write(v []byte) (int, error) {
+	if err := p.ready(true); err != nil {
+		return 0, err
+	}
+	return p.file.Write(v)
+}
+
+// Close implements the io.Closer interface, cleaning up the pipe if this
+// endpoint created it.
+func (p *PipeEndpoint) Close() error {
+	err := p.cleanup()
+	if p.file == nil {
+		return err
+	}
+	if err2 := p.file.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (p *PipeEndpoint) ready(write bool) error {
+	if p.connect == nil {
+		return nil
+	}
+	p.connectOnce.Do(func() {
+		p.connectErr = p.connect(write)
+	})
+	return p.connectErr
+}
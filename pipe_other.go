@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package brimio
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func openPipeEndpoint(name string) (*PipeEndpoint, error) {
+	return nil, fmt.Errorf("named pipes not supported on %s", runtime.GOOS)
+}
+
+func (p *PipeEndpoint) cleanup() error {
+	return nil
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func openPipeEndpoint(name string) (*PipeEndpoint, error) {
+	var created bool
+	if err := syscall.Mkfifo(name, 0600); err == nil {
+		created = true
+	} else if err != syscall.EEXIST {
+		return nil, &os.PathError{Op: "mkfifo", Path: name, Err: err}
+	} else if fi, err := os.Stat(name); err != nil {
+		return nil, err
+	} else if fi.Mode()&os.ModeNamedPipe == 0 {
+		return nil, fmt.Errorf("%s exists and is not a named pipe", name)
+	}
+	p := &PipeEndpoint{name: name, created: created}
+	// The FIFO is opened read-only or write-only on first use, as opening it
+	// read-write would make this endpoint its own peer. Either open blocks
+	// until the other end is opened too.
+	p.connect = func(write bool) error {
+		flag := os.O_RDONLY
+		if write {
+			flag = os.O_WRONLY
+		}
+		f, err := os.OpenFile(name, flag, 0)
+		if err != nil {
+			return err
+		}
+		p.file = f
+		return nil
+	}
+	return p, nil
+}
+
+func (p *PipeEndpoint) cleanup() error {
+	if !p.created {
+		return nil
+	}
+	return os.Remove(p.name)
+}
@@ -0,0 +1,58 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPipeEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "fifo")
+	w, err := OpenPipeEndpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Created() {
+		t.Fatal(w.Created())
+	}
+	r, err := OpenPipeEndpoint(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Created() {
+		t.Fatal(r.Created())
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := w.Write([]byte("12345"))
+		if err2 := w.Close(); err == nil {
+			err = err2
+		}
+		errs <- err
+	}()
+	v, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "12345" {
+		t.Fatalf("%#v", string(v))
+	}
+	if err = <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(name); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package brimio
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	pipePrefix                = `\\.\pipe\`
+	pipeAccessDuplex          = 0x00000003
+	pipeTypeByte              = 0x00000000
+	pipeReadmodeByte          = 0x00000000
+	pipeWait                  = 0x00000000
+	fileFlagFirstPipeInstance = 0x00080000
+	pipeBufferSize            = 65536
+
+	errorPipeBusy         syscall.Errno = 231
+	errorPipeConnected    syscall.Errno = 535
+	errorPipeNotConnected syscall.Errno = 233
+)
+
+var (
+	modkernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipeW    = modkernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe    = modkernel32.NewProc("ConnectNamedPipe")
+	procDisconnectNamedPipe = modkernel32.NewProc("DisconnectNamedPipe")
+)
+
+func openPipeEndpoint(name string) (*PipeEndpoint, error) {
+	if !strings.HasPrefix(name, pipePrefix) {
+		name = pipePrefix + name
+	}
+	name16, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	r, _, e := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(name16)),
+		pipeAccessDuplex|fileFlagFirstPipeInstance,
+		pipeTypeByte|pipeReadmodeByte|pipeWait,
+		1,
+		pipeBufferSize,
+		pipeBufferSize,
+		0,
+		0,
+	)
+	h := syscall.Handle(r)
+	if h == syscall.InvalidHandle {
+		if e == syscall.ERROR_ACCESS_DENIED || e == errorPipeBusy {
+			// The pipe already exists so connect to it as a client.
+			f, err := os.OpenFile(name, os.O_RDWR, 0)
+			if err != nil {
+				return nil, err
+			}
+			return &PipeEndpoint{file: f, name: name}, nil
+		}
+		return nil, &os.PathError{Op: "CreateNamedPipe", Path: name, Err: e}
+	}
+	p := &PipeEndpoint{file: os.NewFile(uintptr(h), name), name: name, created: true}
+	p.connect = func(write bool) error {
+		r, _, e := procConnectNamedPipe.Call(uintptr(h), 0)
+		if r == 0 && e != errorPipeConnected {
+			return &os.PathError{Op: "ConnectNamedPipe", Path: name, Err: e}
+		}
+		return nil
+	}
+	return p, nil
+}
+
+func (p *PipeEndpoint) cleanup() error {
+	if !p.created {
+		return nil
+	}
+	// The pipe itself goes away once its last handle is closed.
+	if r, _, e := procDisconnectNamedPipe.Call(p.file.Fd()); r == 0 && e != errorPipeNotConnected {
+		return &os.PathError{Op: "DisconnectNamedPipe", Path: p.name, Err: e}
+	}
+	return nil
+}
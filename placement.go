@@ -0,0 +1,204 @@
+package brimio
+
+import (
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ChecksumPlacement is where each interval's checksum is stored relative to
+// the interval's content.
+type ChecksumPlacement int
+
+const (
+	// ChecksumTrailing stores each checksum after its interval, the layout
+	// of ChecksummedWriter by default.
+	ChecksumTrailing ChecksumPlacement = iota
+	// ChecksumLeading stores each checksum before its interval, as some
+	// other formats do. As with ChecksumTrailing, a trailing partial interval
+	// has no checksum.
+	ChecksumLeading
+)
+
+// ChecksummedWriterOptions are the optional behaviors of a ChecksummedWriter
+// given to NewChecksummedWriterWithOptions.
+type ChecksummedWriterOptions struct {
+	// Placement is where checksums are written; ChecksumTrailing if not
+	// set. With ChecksumLeading, content is held back until its interval is
+	// complete so its checksum can be written first, any trailing partial
+	// interval being written by Close; Flush and Checkpoint are not
+	// supported, as early checksums would leave the intervals' starts
+	// ambiguous.
+	Placement ChecksumPlacement
+}
+
+// NewChecksummedWriterWithOptions returns a ChecksummedWriter like
+// NewChecksummedWriterHash does but with the optional behaviors given.
+func NewChecksummedWriterWithOptions(delegate io.Writer, checksumInterval int, newHash func() hash.Hash, opts *ChecksummedWriterOptions) ChecksummedWriter {
+	var placement ChecksumPlacement
+	if opts != nil {
+		placement = opts.Placement
+	}
+	return mustChecksummedWriter(NewChecksummedWriterWith(delegate, WithInterval(checksumInterval), WithHash(newHash), WithPlacement(placement)))
+}
+
+// leadingChecksummedWriter is a ChecksummedWriter writing through a
+// leadingChecksumWriter, which it finishes when closed without its delegate.
+type leadingChecksummedWriter struct {
+	*checksummedWriterImpl
+	lw *leadingChecksumWriter
+}
+
+func (lcw *leadingChecksummedWriter) Flush() error {
+	return fmt.Errorf("Flush not supported with leading checksums")
+}
+
+func (lcw *leadingChecksummedWriter) Checkpoint() (ChecksummedWriterCheckpoint, error) {
+	return ChecksummedWriterCheckpoint{}, fmt.Errorf("Checkpoint not supported with leading checksums")
+}
+
+func (lcw *leadingChecksummedWriter) CloseWithoutDelegate() error {
+	lcw.checksummedWriterImpl.CloseWithoutDelegate()
+	return lcw.lw.finish()
+}
+
+// leadingChecksumWriter turns content in the trailing checksum layout into
+// the leading one, buffering each interval and its checksum and writing them
+// swapped.
+type leadingChecksumWriter struct {
+	delegate io.Writer
+	interval int
+	buf      []byte
+	err      error
+}
+
+func (lw *leadingChecksumWriter) Write(v []byte) (int, error) {
+	var n int
+	for len(v) > 0 {
+		if lw.err != nil {
+			return n, lw.err
+		}
+		c := cap(lw.buf) - len(lw.buf)
+		if c > len(v) {
+			c = len(v)
+		}
+		lw.buf = append(lw.buf, v[:c]...)
+		n += c
+		v = v[c:]
+		if len(lw.buf) == cap(lw.buf) {
+			if _, lw.err = lw.delegate.Write(lw.buf[lw.interval:]); lw.err == nil {
+				_, lw.err = lw.delegate.Write(lw.buf[:lw.interval])
+			}
+			lw.buf = lw.buf[:0]
+		}
+	}
+	return n, lw.err
+}
+
+// finish writes any trailing partial interval, which has no checksum.
+func (lw *leadingChecksumWriter) finish() error {
+	if lw.err == nil && len(lw.buf) > 0 {
+		_, lw.err = lw.delegate.Write(lw.buf)
+		lw.buf = lw.buf[:0]
+	}
+	return lw.err
+}
+
+func (lw *leadingChecksumWriter) Close() error {
+	return lw.CloseWithError(nil)
+}
+
+func (lw *leadingChecksumWriter) CloseWithError(err error) error {
+	err2 := lw.finish()
+	if err = closeDelegate(lw.delegate, err); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (lw *leadingChecksumWriter) Unwrap() interface{} {
+	return unwrapDelegate(lw.delegate)
+}
+
+func (lw *leadingChecksumWriter) Sync() error {
+	return syncDelegate(lw.delegate)
+}
+
+// leadingChecksumReadSeeker presents content in the leading checksum layout
+// as if it were in the trailing one, so checksummedReaderImpl can read it.
+// Positions are those of the trailing layout; as the layouts are the same
+// length, so is the content.
+type leadingChecksumReadSeeker struct {
+	delegate     io.ReadSeeker
+	interval     int64
+	checksumSize int64
+	pos          int64
+	size         int64
+}
+
+func newLeadingChecksumReadSeeker(delegate io.ReadSeeker, interval int, checksumSize int) *leadingChecksumReadSeeker {
+	return &leadingChecksumReadSeeker{delegate: delegate, interval: int64(interval), checksumSize: int64(checksumSize), size: -1}
+}
+
+func (lrs *leadingChecksumReadSeeker) Read(v []byte) (int, error) {
+	blockSize := lrs.interval + lrs.checksumSize
+	blockStart := lrs.pos / blockSize * blockSize
+	// The content may have grown since the size was last checked.
+	if blockStart+blockSize > lrs.size {
+		size, err := lrs.delegate.Seek(0, 2)
+		if err != nil {
+			return 0, err
+		}
+		lrs.size = size
+	}
+	physical := lrs.pos
+	if blockStart+blockSize <= lrs.size {
+		if o := lrs.pos - blockStart; o < lrs.interval {
+			physical = blockStart + lrs.checksumSize + o
+			if int64(len(v)) > lrs.interval-o {
+				v = v[:lrs.interval-o]
+			}
+		} else {
+			physical = blockStart + o - lrs.interval
+			if int64(len(v)) > blockSize-o {
+				v = v[:blockSize-o]
+			}
+		}
+	}
+	if _, err := lrs.delegate.Seek(physical, 0); err != nil {
+		return 0, err
+	}
+	n, err := lrs.delegate.Read(v)
+	lrs.pos += int64(n)
+	return n, err
+}
+
+func (lrs *leadingChecksumReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 0:
+	case 1:
+		offset += lrs.pos
+	case 2:
+		size, err := lrs.delegate.Seek(0, 2)
+		if err != nil {
+			return lrs.pos, err
+		}
+		lrs.size = size
+		offset += size
+	default:
+		return lrs.pos, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return lrs.pos, fmt.Errorf("negative position %d", offset)
+	}
+	lrs.pos = offset
+	return offset, nil
+}
+
+func (lrs *leadingChecksumReadSeeker) Close() error {
+	return closeDelegate(lrs.delegate, nil)
+}
+
+func (lrs *leadingChecksumReadSeeker) Unwrap() interface{} {
+	return unwrapDelegate(lrs.delegate)
+}
@@ -0,0 +1,75 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestChecksumLeading(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	newHash := func() hash.Hash { return crc32.NewIEEE() }
+	cw := NewChecksummedWriterWithOptions(f, 16, newHash, &ChecksummedWriterOptions{Placement: ChecksumLeading})
+	if _, err = cw.Write([]byte("12345678901234567890")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cw.Write([]byte("ghijklmnopqrstuvwxyz")); err != nil {
+		t.Fatal(err)
+	}
+	if err = cw.Flush(); err == nil {
+		t.Fatal(err)
+	}
+	if err = cw.CloseWithoutDelegate(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash1 := crc32.NewIEEE()
+	hash1.Write([]byte("1234567890123456"))
+	hash2 := crc32.NewIEEE()
+	hash2.Write([]byte("7890ghijklmnopqr"))
+	if !bytes.Equal(v, []byte(string(hash1.Sum(nil))+"1234567890123456"+string(hash2.Sum(nil))+"7890ghijklmnopqrstuvwxyz")) {
+		t.Fatalf("%#v", string(v))
+	}
+	f.Seek(0, 0)
+	cr := NewChecksummedReaderWithOptions(f, 16, newHash, &ChecksummedReaderOptions{AutoVerify: true, Placement: ChecksumLeading})
+	if size, err := cr.Size(); err != nil || size != 40 {
+		t.Fatalf("%#v %#v", size, err)
+	}
+	if _, err = cr.Seek(10, 0); err != nil {
+		t.Fatal(err)
+	}
+	v, err = ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "1234567890ghijklmnopqrstuvwxyz" {
+		t.Fatalf("%#v", string(v))
+	}
+	cr.Seek(0, 0)
+	if valid, err := cr.Verify(); err != nil || !valid {
+		t.Fatalf("%#v %#v", valid, err)
+	}
+	// Corrupting the second interval's content is detected.
+	f.WriteAt([]byte("!"), 24)
+	cr.Seek(16, 0)
+	if _, err = io.ReadFull(cr, make([]byte, 4)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	cr.Seek(16, 0)
+	if valid, err := cr.Verify(); err != nil || valid {
+		t.Fatalf("%#v %#v", valid, err)
+	}
+}
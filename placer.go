@@ -0,0 +1,104 @@
+package brimio
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultPlacerLoadHalfLife is how quickly a Placer forgets about the bytes
+// recently placed in a directory.
+const DefaultPlacerLoadHalfLife = time.Minute
+
+// Placer chooses among data directories for new segments, spreading them
+// across disks by free space and recent write load.
+//
+// Each directory is scored by its available space divided by one plus the
+// bytes recently placed there, that load decaying by half every
+// LoadHalfLife; the best scoring directory is chosen, with ties going to
+// each directory in turn.
+type Placer struct {
+	// Dirs are the directories to choose from.
+	Dirs []string
+	// MinFree is the available space, in bytes, a directory must keep after
+	// a placement to be chosen.
+	MinFree uint64
+	// LoadHalfLife is how long it takes for the load of bytes placed in a
+	// directory to halve.
+	LoadHalfLife time.Duration
+	// Clock is the source of time for decaying loads; SystemClock if nil.
+	Clock  Clock
+	lock   sync.Mutex
+	next   int
+	loads  map[string]*placerLoad
+	fsInfo func(path string) (FsInfo, error)
+}
+
+type placerLoad struct {
+	bytes float64
+	when  time.Time
+}
+
+// NewPlacer returns a Placer choosing among the directories given.
+func NewPlacer(dirs []string) *Placer {
+	return &Placer{
+		Dirs:         dirs,
+		LoadHalfLife: DefaultPlacerLoadHalfLife,
+		loads:        make(map[string]*placerLoad),
+		fsInfo:       GetFsInfo,
+	}
+}
+
+// Place returns the directory to put a new segment of about size bytes in,
+// counting it toward that directory's load.
+func (p *Placer) Place(size int64) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	now := clockOrSystem(p.Clock).Now()
+	best := -1
+	var bestScore float64
+	var firstErr error
+	for i := range p.Dirs {
+		d := (p.next + i) % len(p.Dirs)
+		fi, err := p.fsInfo(p.Dirs[d])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if fi.Available < p.MinFree+uint64(size) {
+			continue
+		}
+		score := float64(fi.Available) / (1 + p.load(p.Dirs[d], now))
+		if best < 0 || score > bestScore {
+			best = d
+			bestScore = score
+		}
+	}
+	if best < 0 {
+		if firstErr != nil {
+			return "", firstErr
+		}
+		return "", fmt.Errorf("no directory with %d bytes free", p.MinFree+uint64(size))
+	}
+	p.next = best + 1
+	l := p.loads[p.Dirs[best]]
+	l.bytes += float64(size)
+	return p.Dirs[best], nil
+}
+
+// load returns the decayed load of dir as of now.
+func (p *Placer) load(dir string, now time.Time) float64 {
+	l := p.loads[dir]
+	if l == nil {
+		l = &placerLoad{when: now}
+		p.loads[dir] = l
+	}
+	if p.LoadHalfLife > 0 && now.After(l.when) {
+		l.bytes *= math.Pow(0.5, float64(now.Sub(l.when))/float64(p.LoadHalfLife))
+	}
+	l.when = now
+	return l.bytes
+}
@@ -0,0 +1,52 @@
+package brimio
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPlacer(t *testing.T) {
+	free := map[string]uint64{"a": 1000, "b": 1000, "c": 10}
+	p := NewPlacer([]string{"a", "b", "c"})
+	p.MinFree = 50
+	p.fsInfo = func(path string) (FsInfo, error) {
+		if path == "x" {
+			return FsInfo{}, fmt.Errorf("bad dir")
+		}
+		return FsInfo{Available: free[path]}, nil
+	}
+	clock := NewManualClock(time.Unix(1000, 0))
+	p.Clock = clock
+	var got []string
+	for i := 0; i < 4; i++ {
+		d, err := p.Place(100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, d)
+	}
+	// Equal free space and load alternate; c never has room.
+	if fmt.Sprint(got) != "[a b a b]" {
+		t.Fatal(got)
+	}
+	// With a's load decayed away, its extra free space wins even though b
+	// has had no load for as long.
+	free["a"] = 2000
+	clock.Advance(time.Hour)
+	if d, _ := p.Place(100); d != "a" {
+		t.Fatal(d)
+	}
+	// Load counts against a while it's recent.
+	free["a"] = 1100
+	if d, _ := p.Place(100); d != "b" {
+		t.Fatal(d)
+	}
+	if _, err := p.Place(5000); err == nil {
+		t.Fatal()
+	}
+	p.Dirs = []string{"x"}
+	if _, err := p.Place(1); err == nil || err.Error() != "bad dir" {
+		t.Fatal(err)
+	}
+}
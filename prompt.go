@@ -0,0 +1,83 @@
+package brimio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotInteractive is returned by PromptingReader when asked to prompt
+// without an interactive terminal to answer.
+var ErrNotInteractive = fmt.Errorf("input is not an interactive terminal")
+
+// IsTerminal returns true if f is an interactive terminal rather than a file,
+// pipe, or other device such as /dev/null. It asks the terminal driver, with
+// the TCGETS or TIOCGETA ioctl on Unix systems and GetConsoleMode on Windows;
+// elsewhere it falls back to whether f is a character device.
+func IsTerminal(f *os.File) bool {
+	return isTerminal(f)
+}
+
+// PromptingReader asks questions on an output and reads the answers from an
+// input, refusing to do so when the input isn't interactive so that data
+// piped in is never mistaken for answers. Useful for requiring confirmation
+// before destructive operations such as wiping or repairing in place.
+//
+// Implements the io.Reader interface, reading through the same buffer the
+// answers are read from.
+type PromptingReader struct {
+	// Interactive indicates whether prompts may be answered; it defaults to
+	// whether the input is a terminal but may be overridden, such as for a
+	// command line flag that asserts prompts should be answered from the
+	// input regardless.
+	Interactive bool
+	in          *bufio.Reader
+	out         io.Writer
+}
+
+// NewPromptingReader returns a PromptingReader that writes prompts to out and
+// reads answers from in.
+func NewPromptingReader(in io.Reader, out io.Writer) *PromptingReader {
+	pr := &PromptingReader{in: bufio.NewReader(in), out: out}
+	if f, ok := in.(*os.File); ok {
+		pr.Interactive = IsTerminal(f)
+	}
+	return pr
+}
+
+// Read implements the io.Reader interface.
+func (pr *PromptingReader) Read(v []byte) (int, error) {
+	return pr.in.Read(v)
+}
+
+// Prompt writes the question and returns the line of input answering it,
+// with surrounding whitespace removed.
+func (pr *PromptingReader) Prompt(question string) (string, error) {
+	if !pr.Interactive {
+		return "", ErrNotInteractive
+	}
+	if _, err := io.WriteString(pr.out, question); err != nil {
+		return "", err
+	}
+	line, err := pr.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Confirm writes the question followed by " [y/N] " and returns true only if
+// the answer is y or yes.
+func (pr *PromptingReader) Confirm(question string) (bool, error) {
+	answer, err := pr.Prompt(question + " [y/N] ")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
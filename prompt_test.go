@@ -0,0 +1,61 @@
+package brimio
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPromptingReader(t *testing.T) {
+	out := &bytes.Buffer{}
+	pr := NewPromptingReader(strings.NewReader("yes\nno\nrest"), out)
+	if pr.Interactive {
+		t.Fatal(pr.Interactive)
+	}
+	ok, err := pr.Confirm("Wipe?")
+	if err != ErrNotInteractive {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal(ok)
+	}
+	pr.Interactive = true
+	ok, err = pr.Confirm("Wipe?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal(ok)
+	}
+	ok, err = pr.Confirm("Repair?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal(ok)
+	}
+	if out.String() != "Wipe? [y/N] Repair? [y/N] " {
+		t.Fatalf("%#v", out.String())
+	}
+	v, err := ioutil.ReadAll(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "rest" {
+		t.Fatalf("%#v", string(v))
+	}
+}
+
+func TestIsTerminalDevNull(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+	// A character device, but not a terminal.
+	if IsTerminal(f) {
+		t.Fatal(os.DevNull)
+	}
+}
@@ -0,0 +1,11 @@
+package brimio
+
+import (
+	"os"
+	"syscall"
+)
+
+func isTerminal(f *os.File) bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(f.Fd()), &mode) == nil
+}
@@ -0,0 +1,93 @@
+package brimio
+
+import (
+	"fmt"
+	"hash"
+	"io"
+)
+
+// RangeDigest returns the digest by h of length bytes of the content of r
+// starting at offset, such as a digest of part of an object for a client,
+// without copying the content out first. A range extending past the end of
+// the content is an error.
+//
+// If the underlying content of r is an io.ReaderAt, as *os.File is, the
+// intervals in the range are read with it directly, verified, and their
+// content hashed, leaving the position of r untouched. Otherwise r is read
+// from offset, verifying as its options say, and its position restored
+// afterward.
+func RangeDigest(r ChecksummedReader, offset int64, length int64, h hash.Hash) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range %d+%d", offset, length)
+	}
+	if cri, ok := r.(*checksummedReaderImpl); ok {
+		if ra, ok := cri.delegate.(io.ReaderAt); ok {
+			return RangeDigestAt(ra, ChecksummedConfig{Interval: cri.checksumInterval, NewHash: cri.newHash}, offset, length, h)
+		}
+	}
+	original, err := r.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = r.Seek(offset, 0); err != nil {
+		return nil, err
+	}
+	_, err = io.CopyN(h, r, length)
+	if _, err2 := r.Seek(original, 0); err == nil {
+		err = err2
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// RangeDigestAt is RangeDigest for checksummed content read directly from ra
+// with the layout given. Each interval in the range is read whole and
+// verified, returning ErrChecksumMismatch if one is not checksum valid; any
+// trailing partial interval has no checksum and is hashed unverified. It is
+// safe to call concurrently if ra is.
+func RangeDigestAt(ra io.ReaderAt, cfg ChecksummedConfig, offset int64, length int64, h hash.Hash) ([]byte, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range %d+%d", offset, length)
+	}
+	interval := int64(cfg.Interval)
+	verify := cfg.NewHash()
+	block := make([]byte, cfg.Interval+verify.Size())
+	for length > 0 {
+		index := offset / interval
+		n, err := ra.ReadAt(block, index*int64(len(block)))
+		if n == len(block) {
+			verify.Reset()
+			setChecksumIndex(verify, index)
+			verify.Write(block[:interval])
+			if !checksumMatches(verify, block[interval:], nil) {
+				return nil, ErrChecksumMismatch
+			}
+			n = cfg.Interval
+		} else if err != io.EOF && err != nil {
+			return nil, err
+		} else if n > cfg.Interval {
+			// The checksum itself was cut short.
+			return nil, ErrChecksumMismatch
+		}
+		start := offset - index*interval
+		end := start + length
+		if end > int64(n) {
+			end = int64(n)
+		}
+		if end <= start {
+			return nil, io.ErrUnexpectedEOF
+		}
+		h.Write(block[start:end])
+		offset += end - start
+		length -= end - start
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,69 @@
+package brimio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRangeDigest(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	content := "12345678901234567890ghijklmnopqrstuvwxyz"
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte(content))
+	want := sha256.Sum256([]byte(content[10:38]))
+	cr := NewChecksummedReader(f, 16, crc32.NewIEEE)
+	cr.Seek(5, 0)
+	// Through the ReaderAt of the *os.File.
+	v, err := RangeDigest(cr, 10, 28, sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, want[:]) {
+		t.Fatalf("%x", v)
+	}
+	if o, _ := cr.Seek(0, 1); o != 5 {
+		t.Fatal(o)
+	}
+	// Through reading, as a plain io.ReadSeeker has no ReaderAt.
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr = NewChecksummedReader(struct{ io.ReadSeeker }{bytes.NewReader(b)}, 16, crc32.NewIEEE)
+	cr.Seek(5, 0)
+	if v, err = RangeDigest(cr, 10, 28, sha256.New()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v, want[:]) {
+		t.Fatalf("%x", v)
+	}
+	if o, _ := cr.Seek(0, 1); o != 5 {
+		t.Fatal(o)
+	}
+	if _, err = RangeDigest(cr, 30, 11, sha256.New()); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	if _, err = RangeDigestAt(f, cfg, 30, 11, sha256.New()); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	empty := sha256.Sum256(nil)
+	if v, err = RangeDigestAt(f, cfg, 40, 0, sha256.New()); err != nil || !bytes.Equal(v, empty[:]) {
+		t.Fatalf("%x %#v", v, err)
+	}
+	f.WriteAt([]byte("!"), 21)
+	if _, err = RangeDigestAt(f, cfg, 20, 1, sha256.New()); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,157 @@
+package brimio
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RangeLocker serializes access to byte ranges of shared content, such as a
+// file updated with WriteAt from several goroutines: exclusive locks on
+// overlapping ranges wait for one another, as do shared and exclusive locks,
+// while shared locks may overlap freely.
+//
+// A RangeLocker from NewFileRangeLocker also takes open file description
+// (OFD) byte-range locks on Linux, coordinating with other processes using
+// such locks on the same file; elsewhere it only coordinates within the
+// process.
+type RangeLocker struct {
+	lock sync.Mutex
+	cond *sync.Cond
+	held map[*RangeLock]bool
+	file *os.File
+}
+
+// RangeLock is a held lock on a range, from RangeLocker.Lock or RLock.
+type RangeLock struct {
+	locker *RangeLocker
+	offset int64
+	length int64
+	shared bool
+}
+
+// NewRangeLocker returns a RangeLocker coordinating within the process.
+func NewRangeLocker() *RangeLocker {
+	rl := &RangeLocker{held: make(map[*RangeLock]bool)}
+	rl.cond = sync.NewCond(&rl.lock)
+	return rl
+}
+
+// NewFileRangeLocker returns a RangeLocker that also takes OFD byte-range
+// locks on f where supported.
+func NewFileRangeLocker(f *os.File) *RangeLocker {
+	rl := NewRangeLocker()
+	rl.file = f
+	return rl
+}
+
+// Lock waits for and returns an exclusive lock on the length bytes at
+// offset; a length of 0 means through any end of the content.
+func (rl *RangeLocker) Lock(offset int64, length int64) (*RangeLock, error) {
+	return rl.acquire(offset, length, false)
+}
+
+// RLock waits for and returns a shared lock on the length bytes at offset;
+// a length of 0 means through any end of the content.
+func (rl *RangeLocker) RLock(offset int64, length int64) (*RangeLock, error) {
+	return rl.acquire(offset, length, true)
+}
+
+func (rl *RangeLocker) acquire(offset int64, length int64, shared bool) (*RangeLock, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range %d %d", offset, length)
+	}
+	l := &RangeLock{locker: rl, offset: offset, length: length, shared: shared}
+	rl.lock.Lock()
+	for rl.conflicts(l) {
+		rl.cond.Wait()
+	}
+	rl.held[l] = true
+	rl.lock.Unlock()
+	if rl.file != nil {
+		if err := ofdLock(rl.file, offset, length, shared, true); err != nil {
+			rl.release(l)
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// conflicts returns whether l can't be granted alongside the locks held.
+func (rl *RangeLocker) conflicts(l *RangeLock) bool {
+	for h := range rl.held {
+		if (!h.shared || !l.shared) && h.overlaps(l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rl *RangeLocker) release(l *RangeLock) {
+	rl.lock.Lock()
+	delete(rl.held, l)
+	rl.cond.Broadcast()
+	rl.lock.Unlock()
+}
+
+func (l *RangeLock) overlaps(o *RangeLock) bool {
+	return (l.length == 0 || o.offset < l.offset+l.length) && (o.length == 0 || l.offset < o.offset+o.length)
+}
+
+// Unlock releases the RangeLock; it must not be used afterward.
+func (l *RangeLock) Unlock() error {
+	var err error
+	if l.locker.file != nil {
+		err = l.locker.ofdRelease(l)
+	}
+	l.locker.release(l)
+	return err
+}
+
+// ofdRelease releases the portions of l's range of the OFD lock not still
+// covered by other locks held within the process; OFD locks aren't counted,
+// so releasing all of it would drop those too.
+func (rl *RangeLocker) ofdRelease(l *RangeLock) error {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	// Gaps are [start, end) with an end of -1 meaning unbounded.
+	gaps := [][2]int64{{l.offset, l.end()}}
+	for h := range rl.held {
+		if h == l || !h.overlaps(l) {
+			continue
+		}
+		var next [][2]int64
+		he := h.end()
+		for _, g := range gaps {
+			if (g[1] >= 0 && g[1] <= h.offset) || (he >= 0 && g[0] >= he) {
+				next = append(next, g)
+				continue
+			}
+			if g[0] < h.offset {
+				next = append(next, [2]int64{g[0], h.offset})
+			}
+			if he >= 0 && (g[1] < 0 || g[1] > he) {
+				next = append(next, [2]int64{he, g[1]})
+			}
+		}
+		gaps = next
+	}
+	for _, g := range gaps {
+		length := int64(0)
+		if g[1] >= 0 {
+			length = g[1] - g[0]
+		}
+		if err := ofdLock(rl.file, g[0], length, false, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// end returns the end of l's range, or -1 if it is unbounded.
+func (l *RangeLock) end() int64 {
+	if l.length == 0 {
+		return -1
+	}
+	return l.offset + l.length
+}
@@ -0,0 +1,27 @@
+package brimio
+
+import (
+	"os"
+	"syscall"
+)
+
+// fOFDSetlkw is F_OFD_SETLKW, which the syscall package doesn't define.
+const fOFDSetlkw = 38
+
+// ofdLock takes, waiting as needed, a shared or exclusive OFD byte-range
+// lock on f, or releases it if lock is false.
+func ofdLock(f *os.File, offset int64, length int64, shared bool, lock bool) error {
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: 0, Start: offset, Len: length}
+	if shared {
+		lk.Type = syscall.F_RDLCK
+	}
+	if !lock {
+		lk.Type = syscall.F_UNLCK
+	}
+	for {
+		err := syscall.FcntlFlock(f.Fd(), fOFDSetlkw, &lk)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package brimio
+
+import "os"
+
+func ofdLock(f *os.File, offset int64, length int64, shared bool, lock bool) error {
+	return nil
+}
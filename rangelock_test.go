@@ -0,0 +1,106 @@
+package brimio
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRangeLocker(t *testing.T) {
+	rl := NewRangeLocker()
+	a, err := rl.Lock(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Disjoint and shared locks don't wait.
+	b, err := rl.Lock(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := rl.RLock(20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := rl.RLock(25, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lock sync.Mutex
+	var order []string
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		l, err := rl.Lock(5, 10)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		lock.Lock()
+		order = append(order, "overlap")
+		lock.Unlock()
+		l.Unlock()
+	}()
+	go func() {
+		defer wg.Done()
+		l, err := rl.Lock(100, 1)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		lock.Lock()
+		order = append(order, "unbounded")
+		lock.Unlock()
+		l.Unlock()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	lock.Lock()
+	if len(order) != 0 {
+		t.Fatal(order)
+	}
+	lock.Unlock()
+	a.Unlock()
+	b.Unlock()
+	c.Unlock()
+	d.Unlock()
+	wg.Wait()
+	if len(order) != 2 {
+		t.Fatal(order)
+	}
+	if _, err = rl.Lock(-1, 1); err == nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileRangeLocker(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	rl := NewFileRangeLocker(f)
+	a, err := rl.RLock(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := rl.RLock(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := rl.Lock(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,130 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"io"
+	"syscall"
+	"time"
+)
+
+// DefaultRawFDRetries is the number of times a RawFD will retry an operation
+// that fails with EINTR or EAGAIN before giving up and returning that error.
+const DefaultRawFDRetries = 100
+
+// RawFD implements io.ReadWriteCloser directly on an integer file descriptor,
+// useful for devices and pipes that *os.File doesn't wrap conveniently.
+//
+// Reads and writes interrupted with EINTR are retried. If the RawFD is
+// nonblocking, operations that fail with EAGAIN will wait for the descriptor
+// to become ready and then be retried. All retries are bounded by Retries; once
+// exhausted the last EINTR or EAGAIN error is returned.
+type RawFD struct {
+	// Retries is the maximum number of times to retry an operation failing
+	// with EINTR or EAGAIN.
+	Retries int
+	// Timeout is the maximum time to wait for the descriptor to become ready
+	// after an EAGAIN; 0 means wait indefinitely. Only used when nonblocking.
+	Timeout     time.Duration
+	fd          int
+	nonblocking bool
+}
+
+// NewRawFD returns a RawFD that will operate on the descriptor fd as is,
+// retrying operations interrupted with EINTR.
+func NewRawFD(fd int) *RawFD {
+	return &RawFD{Retries: DefaultRawFDRetries, fd: fd}
+}
+
+// NewNonblockingRawFD returns a RawFD that will put the descriptor fd into
+// nonblocking mode and wait up to timeout for the descriptor to become ready
+// whenever an operation fails with EAGAIN.
+func NewNonblockingRawFD(fd int, timeout time.Duration) (*RawFD, error) {
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		return nil, err
+	}
+	return &RawFD{Retries: DefaultRawFDRetries, Timeout: timeout, fd: fd, nonblocking: true}, nil
+}
+
+// Fd returns the underlying file descriptor.
+func (r *RawFD) Fd() int {
+	return r.fd
+}
+
+// Read implements the io.Reader interface.
+func (r *RawFD) Read(v []byte) (int, error) {
+	if len(v) == 0 {
+		return 0, nil
+	}
+	var n int
+	var err error
+	for i := 0; ; i++ {
+		n, err = syscall.Read(r.fd, v)
+		if !r.retry(err, i, false) {
+			break
+		}
+	}
+	if err != nil {
+		if n < 0 {
+			n = 0
+		}
+		return n, err
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Write implements the io.Writer interface, continuing until all of v is
+// written or an error occurs.
+func (r *RawFD) Write(v []byte) (int, error) {
+	var n int
+	for i := 0; len(v) > 0; {
+		n2, err := syscall.Write(r.fd, v)
+		if n2 > 0 {
+			n += n2
+			v = v[n2:]
+			i = 0
+			continue
+		}
+		if !r.retry(err, i, true) {
+			if err == nil {
+				err = io.ErrShortWrite
+			}
+			return n, err
+		}
+		i++
+	}
+	return n, nil
+}
+
+// Close implements the io.Closer interface. Close is not retried on EINTR as
+// the state of the descriptor is unspecified afterwards.
+func (r *RawFD) Close() error {
+	if r.fd < 0 {
+		return syscall.EBADF
+	}
+	err := syscall.Close(r.fd)
+	r.fd = -1
+	return err
+}
+
+// retry returns true if the err from the attempt'th try of an operation
+// warrants another try; waiting for readiness first when appropriate.
+func (r *RawFD) retry(err error, attempt int, write bool) bool {
+	if attempt >= r.Retries {
+		return false
+	}
+	switch err {
+	case syscall.EINTR:
+		return true
+	case syscall.EAGAIN:
+		if !r.nonblocking {
+			return false
+		}
+		return waitFD(r.fd, write, r.Timeout) == nil
+	}
+	return false
+}
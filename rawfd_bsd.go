@@ -0,0 +1,24 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// poll calls poll, waiting indefinitely if wait is negative and rounding any
+// other wait up to whole milliseconds.
+func poll(pfd *pollFd, wait time.Duration) (int, error) {
+	ms := -1
+	if wait >= 0 {
+		ms = int((wait + time.Millisecond - 1) / time.Millisecond)
+	}
+	n, _, errno := syscall.Syscall(syscall.SYS_POLL, uintptr(unsafe.Pointer(pfd)), 1, uintptr(ms))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
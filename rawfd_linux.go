@@ -0,0 +1,22 @@
+package brimio
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// poll calls ppoll, as not every linux architecture has poll itself, waiting
+// indefinitely if wait is negative.
+func poll(pfd *pollFd, wait time.Duration) (int, error) {
+	var ts *syscall.Timespec
+	if wait >= 0 {
+		t := syscall.NsecToTimespec(int64(wait))
+		ts = &t
+	}
+	n, _, errno := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(pfd)), 1, uintptr(unsafe.Pointer(ts)), 0, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
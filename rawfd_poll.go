@@ -0,0 +1,55 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"syscall"
+	"time"
+)
+
+// pollFd is struct pollfd, the same on each of these platforms.
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+const (
+	pollIn  = 0x1
+	pollOut = 0x4
+)
+
+// waitFD blocks until fd is ready for reading (or writing if write is true),
+// or until timeout elapses if timeout is greater than 0. It uses poll(2),
+// which unlike select(2) works with descriptors of any number. An error or
+// hang up on fd counts as ready, so the retried operation reports it.
+func waitFD(fd int, write bool, timeout time.Duration) error {
+	pfd := pollFd{fd: int32(fd), events: pollIn}
+	if write {
+		pfd.events = pollOut
+	}
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		wait := time.Duration(-1)
+		if timeout > 0 {
+			if wait = time.Until(deadline); wait < 0 {
+				wait = 0
+			}
+		}
+		n, err := poll(&pfd, wait)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return syscall.ETIMEDOUT
+		}
+		return nil
+	}
+}
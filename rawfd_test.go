@@ -0,0 +1,70 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package brimio
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRawFD(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+	rfd, err := syscall.Dup(int(pr.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wfd, err := syscall.Dup(int(pw.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewNonblockingRawFD(rfd, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewRawFD(wfd)
+	v := make([]byte, 10)
+	n, err := r.Read(v)
+	if err != syscall.EAGAIN {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatal(n)
+	}
+	n, err = w.Write([]byte("12345"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Fatal(n)
+	}
+	n, err = r.Read(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v[:n]) != "12345" {
+		t.Fatalf("%#v", string(v[:n]))
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pw.Close()
+	n, err = r.Read(v)
+	if err != io.EOF {
+		t.Fatal(err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Close(); err != syscall.EBADF {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,80 @@
+package brimio
+
+import (
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ChecksummedReadWriteSeeker is a ChecksummedReader that can also Write
+// anywhere within the checksummed content, such as to use a checksummed file
+// as a mutable store of fixed size records.
+type ChecksummedReadWriteSeeker interface {
+	ChecksummedReader
+	// Write implements the io.Writer interface, overwriting or extending
+	// the content at the current position as ChecksummedWriterAt.WriteAt
+	// does, rereading and rechecksumming each interval affected, and then
+	// advancing the position past the content written.
+	Write(v []byte) (n int, err error)
+}
+
+// NewChecksummedReadWriteSeeker returns a ChecksummedReadWriteSeeker that
+// delegates requests to an underlying io.ReadWriteSeeker containing
+// checksums of the content at given intervals using the hashing function
+// given.
+func NewChecksummedReadWriteSeeker(delegate io.ReadWriteSeeker, interval int, newHash func() hash.Hash) ChecksummedReadWriteSeeker {
+	return &checksummedReadWriteSeekerImpl{
+		checksummedReaderImpl: newChecksummedReaderImpl(delegate, interval, newHash),
+		writerAt:              newChecksummedWriterAtImpl(&readWriteSeekerAt{delegate: delegate}, interval, newHash),
+	}
+}
+
+type checksummedReadWriteSeekerImpl struct {
+	*checksummedReaderImpl
+	writerAt *checksummedWriterAtImpl
+}
+
+func (crws *checksummedReadWriteSeekerImpl) Write(v []byte) (int, error) {
+	if crws.delegate == errDelegate {
+		return 0, fmt.Errorf("closed")
+	}
+	o, err := crws.Seek(0, 1)
+	if err != nil {
+		return 0, err
+	}
+	n, err := crws.writerAt.WriteAt(v, o)
+	// Writing moved the underlying position, so put it back where the
+	// reader expects.
+	if _, err2 := crws.Seek(o+int64(n), 0); err == nil {
+		err = err2
+	}
+	return n, err
+}
+
+// readWriteSeekerAt adapts an io.ReadWriteSeeker to a ReadWriterAt by
+// seeking before each call; it is not safe for concurrent use.
+type readWriteSeekerAt struct {
+	delegate io.ReadWriteSeeker
+}
+
+func (rwsa *readWriteSeekerAt) ReadAt(v []byte, offset int64) (int, error) {
+	if _, err := rwsa.delegate.Seek(offset, 0); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(rwsa.delegate, v)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (rwsa *readWriteSeekerAt) WriteAt(v []byte, offset int64) (int, error) {
+	if _, err := rwsa.delegate.Seek(offset, 0); err != nil {
+		return 0, err
+	}
+	return rwsa.delegate.Write(v)
+}
+
+func (rwsa *readWriteSeekerAt) Unwrap() interface{} {
+	return unwrapDelegate(rwsa.delegate)
+}
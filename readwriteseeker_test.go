@@ -0,0 +1,54 @@
+package brimio
+
+import (
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestChecksummedReadWriteSeeker(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.CloseWithoutDelegate()
+	crws := NewChecksummedReadWriteSeeker(f, 16, func() hash.Hash { return crc32.NewIEEE() })
+	if _, err = crws.Seek(10, 0); err != nil {
+		t.Fatal(err)
+	}
+	n, err := crws.Write([]byte("ABCDEFGHIJ"))
+	if err != nil || n != 10 {
+		t.Fatal(n, err)
+	}
+	v := make([]byte, 4)
+	if _, err = crws.Read(v); err != nil || string(v) != "ghij" {
+		t.Fatal(err, string(v))
+	}
+	if _, err = crws.Seek(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = crws.Write([]byte("!@#$")); err != nil {
+		t.Fatal(err)
+	}
+	if corrupt, err := crws.VerifyAll(nil); err != nil || corrupt != nil {
+		t.Fatal(corrupt, err)
+	}
+	crws.Seek(0, 0)
+	all, err := ioutil.ReadAll(crws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(all) != "1234567890ABCDEFGHIJghijklmnopqrstuvwxyz!@#$" {
+		t.Fatalf("%#v", string(all))
+	}
+	crws.CloseWithoutDelegate()
+	if _, err = crws.Write([]byte("x")); err == nil {
+		t.Fatal(err)
+	}
+}
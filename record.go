@@ -0,0 +1,223 @@
+package brimio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Record is a key and value pair, or a tombstone marking the key deleted.
+//
+// Serialized it is a 1 byte flags field, bit 0 set for tombstones, then a
+// uvarint length and that many bytes of key; records that are not tombstones
+// follow with a uvarint length and that many bytes of value.
+type Record struct {
+	Key       []byte
+	Value     []byte
+	Tombstone bool
+}
+
+const recordFlagTombstone = 1
+
+// NewTombstone returns a tombstone Record for the key given.
+func NewTombstone(key []byte) Record {
+	return Record{Key: key, Tombstone: true}
+}
+
+// RecordIterator is implemented by sources of Records, such as RecordReader.
+type RecordIterator interface {
+	// Next returns the next Record or io.EOF once there are no more. The
+	// Record's Key and Value are only valid until the following call.
+	Next() (Record, error)
+}
+
+// RecordWriter writes serialized Records to an underlying io.Writer, such as
+// a ChecksummedWriter.
+type RecordWriter struct {
+	delegate io.Writer
+	buf      *bufio.Writer
+}
+
+// NewRecordWriter returns a RecordWriter that delegates to an underlying
+// io.Writer.
+func NewRecordWriter(delegate io.Writer) *RecordWriter {
+	return &RecordWriter{delegate: delegate, buf: bufio.NewWriter(delegate)}
+}
+
+// WriteRecord writes the Record given.
+func (rw *RecordWriter) WriteRecord(r Record) error {
+	var flags byte
+	if r.Tombstone {
+		flags |= recordFlagTombstone
+	}
+	var b [binary.MaxVarintLen64]byte
+	rw.buf.WriteByte(flags)
+	rw.buf.Write(b[:binary.PutUvarint(b[:], uint64(len(r.Key)))])
+	_, err := rw.buf.Write(r.Key)
+	if !r.Tombstone {
+		rw.buf.Write(b[:binary.PutUvarint(b[:], uint64(len(r.Value)))])
+		_, err = rw.buf.Write(r.Value)
+	}
+	// Errors are sticky within the bufio.Writer, so the last write reports
+	// any earlier failure too.
+	return err
+}
+
+// Flush writes any buffered Records to the underlying io.Writer.
+func (rw *RecordWriter) Flush() error {
+	return rw.buf.Flush()
+}
+
+// Close implements the io.Closer interface, flushing any buffered Records and
+// then closing the underlying io.Writer if it can be closed.
+func (rw *RecordWriter) Close() error {
+	return rw.CloseWithError(nil)
+}
+
+// CloseWithError closes the underlying io.Writer with the err given, using its
+// CloseWithError if it has one, without flushing any buffered Records. If err
+// is nil this is the same as Close; should the flush fail, the underlying
+// io.Writer is closed with that error instead.
+func (rw *RecordWriter) CloseWithError(err error) error {
+	if err == nil {
+		if err = rw.buf.Flush(); err == nil {
+			return closeDelegate(rw.delegate, nil)
+		}
+		closeDelegate(rw.delegate, err)
+		return err
+	}
+	return closeDelegate(rw.delegate, err)
+}
+
+// Unwrap implements the Unwrapper interface, returning the underlying
+// io.Writer.
+func (rw *RecordWriter) Unwrap() interface{} {
+	return unwrapDelegate(rw.delegate)
+}
+
+// RecordReader reads serialized Records from an underlying io.Reader, such as
+// a ChecksummedReader with AutoVerify set.
+type RecordReader struct {
+	buf   *bufio.Reader
+	key   []byte
+	value []byte
+}
+
+// NewRecordReader returns a RecordReader that delegates to an underlying
+// io.Reader.
+func NewRecordReader(delegate io.Reader) *RecordReader {
+	return &RecordReader{buf: bufio.NewReader(delegate)}
+}
+
+// Next implements the RecordIterator interface.
+func (rr *RecordReader) Next() (Record, error) {
+	flags, err := rr.buf.ReadByte()
+	if err != nil {
+		return Record{}, err
+	}
+	if flags&^recordFlagTombstone != 0 {
+		return Record{}, fmt.Errorf("unknown record flags %x", flags)
+	}
+	r := Record{Tombstone: flags&recordFlagTombstone != 0}
+	if rr.key, err = rr.readBytes(rr.key); err != nil {
+		return Record{}, err
+	}
+	r.Key = rr.key
+	if !r.Tombstone {
+		if rr.value, err = rr.readBytes(rr.value); err != nil {
+			return Record{}, err
+		}
+		r.Value = rr.value
+	}
+	return r, nil
+}
+
+func (rr *RecordReader) readBytes(b []byte) ([]byte, error) {
+	l, err := binary.ReadUvarint(rr.buf)
+	if err != nil {
+		return b, recordErr(err)
+	}
+	if l <= uint64(cap(b)) {
+		b = b[:l]
+		_, err = io.ReadFull(rr.buf, b)
+		return b, recordErr(err)
+	}
+	b, err = readGrowing(rr.buf, b, l)
+	return b, recordErr(err)
+}
+
+// readGrowing reads exactly l bytes from r into b, reusing b's capacity. As l
+// usually comes from content not yet verified, b only grows as content
+// actually arrives, so a corrupt length costs no more memory than the content
+// it claims is there.
+func readGrowing(r io.Reader, b []byte, l uint64) ([]byte, error) {
+	b = b[:0]
+	for uint64(len(b)) < l {
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+		end := cap(b)
+		if uint64(end) > l {
+			end = int(l)
+		}
+		n, err := io.ReadFull(r, b[len(b):end])
+		b = b[:len(b)+n]
+		if err != nil {
+			if err == io.EOF && len(b) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return b, err
+		}
+	}
+	return b, nil
+}
+
+// recordErr reports running out of content partway through a record as
+// io.ErrUnexpectedEOF.
+func recordErr(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// TombstoneResolver is a RecordIterator over another that yields Records in
+// key order with newer Records first for equal keys, such as a MergeIterator,
+// resolving each key to just its newest Record.
+//
+// If DropTombstones is set, keys whose newest Record is a tombstone are left
+// out entirely; this is only safe when compacting into the oldest data, as
+// otherwise older Records elsewhere would reappear.
+type TombstoneResolver struct {
+	DropTombstones bool
+	delegate       RecordIterator
+	compare        func(a []byte, b []byte) int
+	last           []byte
+	started        bool
+}
+
+// NewTombstoneResolver returns a TombstoneResolver over the RecordIterator
+// given, with keys ordered by compare, such as bytes.Compare.
+func NewTombstoneResolver(delegate RecordIterator, compare func(a []byte, b []byte) int) *TombstoneResolver {
+	return &TombstoneResolver{delegate: delegate, compare: compare}
+}
+
+// Next implements the RecordIterator interface.
+func (tr *TombstoneResolver) Next() (Record, error) {
+	for {
+		r, err := tr.delegate.Next()
+		if err != nil {
+			return r, err
+		}
+		if tr.started && tr.compare(r.Key, tr.last) == 0 {
+			continue
+		}
+		tr.started = true
+		tr.last = append(tr.last[:0], r.Key...)
+		if r.Tombstone && tr.DropTombstones {
+			continue
+		}
+		return r, nil
+	}
+}
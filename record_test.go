@@ -0,0 +1,108 @@
+package brimio
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+func TestRecordReaderWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	rw := NewRecordWriter(cw)
+	rw.WriteRecord(Record{Key: []byte("a"), Value: []byte("apple")})
+	rw.WriteRecord(NewTombstone([]byte("b")))
+	rw.WriteRecord(Record{Key: []byte("c"), Value: []byte{}})
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(buf.Bytes()), 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{AutoVerify: true})
+	rr := NewRecordReader(cr)
+	var got []string
+	for {
+		r, err := rr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(r.Key)+"="+string(r.Value)+map[bool]string{true: "!"}[r.Tombstone])
+	}
+	if len(got) != 3 || got[0] != "a=apple" || got[1] != "b=!" || got[2] != "c=" {
+		t.Fatal(got)
+	}
+	// Cut short partway through the last record.
+	rr = NewRecordReader(bytes.NewReader([]byte{0, 1, 'a', 5, 'a', 'p'}))
+	if _, err := rr.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	// A corrupt length far beyond the content must not be allocated up front.
+	rr = NewRecordReader(bytes.NewReader([]byte{0, 1, 'a', 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 'a', 'p'}))
+	if _, err := rr.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	// Larger values than the reused buffers still read back whole.
+	buf.Reset()
+	rw = NewRecordWriter(buf)
+	big := bytes.Repeat([]byte("0123456789"), 10000)
+	rw.WriteRecord(Record{Key: []byte("a"), Value: []byte("x")})
+	rw.WriteRecord(Record{Key: []byte("b"), Value: big})
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rr = NewRecordReader(bytes.NewReader(buf.Bytes()))
+	rr.Next()
+	if r, err := rr.Next(); err != nil || !bytes.Equal(r.Value, big) {
+		t.Fatal(err, len(r.Value))
+	}
+}
+
+func TestRecordWriterCloseWithError(t *testing.T) {
+	pr, pw := io.Pipe()
+	rw := NewRecordWriter(pw)
+	rw.WriteRecord(Record{Key: []byte("a"), Value: []byte("apple")})
+	errAbort := fmt.Errorf("abort")
+	if err := rw.CloseWithError(errAbort); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pr.Read(make([]byte, 1)); err != errAbort {
+		t.Fatal(err)
+	}
+}
+
+func TestTombstoneResolver(t *testing.T) {
+	for _, drop := range []bool{false, true} {
+		rs := recordSliceIterator{
+			{Key: []byte("a"), Value: []byte("1")},
+			NewTombstone([]byte("b")),
+			{Key: []byte("b"), Value: []byte("old")},
+			{Key: []byte("c"), Value: []byte("new")},
+			{Key: []byte("c"), Value: []byte("old")},
+			NewTombstone([]byte("d")),
+		}
+		tr := NewTombstoneResolver(&rs, bytes.Compare)
+		tr.DropTombstones = drop
+		var got []string
+		for {
+			r, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, string(r.Key)+"="+string(r.Value))
+		}
+		want := "[a=1 b= c=new d=]"
+		if drop {
+			want = "[a=1 c=new]"
+		}
+		if s := fmt.Sprint(got); s != want {
+			t.Fatal(drop, s)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// RepairPlan describes how to repair the corrupt intervals of checksummed
+// content from replicas of it, such as copies on other disks or hosts. Create
+// one with NewRepairPlan and then Execute it.
+type RepairPlan struct {
+	// Fetches are the corrupt intervals that can be repaired and where from.
+	Fetches []RepairFetch
+	// Unrepairable are the indexes of corrupt intervals that no source has a
+	// checksum valid copy of.
+	Unrepairable []int64
+	// Workers is how many fetches Execute will run in parallel; defaults to
+	// 1.
+	Workers int
+	// BytesPerSecond, if greater than 0, limits how fast Execute will fetch
+	// from the sources.
+	BytesPerSecond int64
+	// Clock is the source of time for limiting; SystemClock if nil.
+	Clock   Clock
+	target  ReadWriterAt
+	size    int64
+	cfg     ChecksummedConfig
+	sources []io.ReaderAt
+}
+
+// RepairFetch identifies a corrupt interval and the sources that had a
+// checksum valid copy of it when the RepairPlan was made, in order of
+// preference.
+type RepairFetch struct {
+	Block   int64
+	Sources []int
+}
+
+// NewRepairPlan verifies every complete interval of the size bytes of
+// checksummed content in target and, for each found not checksum valid,
+// checks which of the sources given have a checksum valid copy of it. The
+// sources must have the same layout as target. Sources are preferred in the
+// order given.
+//
+// Any trailing partial interval has no checksum and is not considered.
+func NewRepairPlan(target ReadWriterAt, size int64, cfg ChecksummedConfig, sources []io.ReaderAt) (*RepairPlan, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	rp := &RepairPlan{Workers: 1, target: target, size: size, cfg: cfg, sources: sources}
+	corrupt, err := rp.verify()
+	if err != nil {
+		return nil, err
+	}
+	block := make([]byte, rp.blockSize())
+	for _, i := range corrupt {
+		f := RepairFetch{Block: i}
+		for j, s := range sources {
+			if ok, err := rp.read(s, i, block); err == nil && ok {
+				f.Sources = append(f.Sources, j)
+			}
+		}
+		if len(f.Sources) == 0 {
+			rp.Unrepairable = append(rp.Unrepairable, i)
+		} else {
+			rp.Fetches = append(rp.Fetches, f)
+		}
+	}
+	return rp, nil
+}
+
+func (rp *RepairPlan) blockSize() int64 {
+	return int64(rp.cfg.Interval + rp.cfg.NewHash().Size())
+}
+
+// read reads the interval at index from r into block, returning whether it
+// is checksum valid.
+func (rp *RepairPlan) read(r io.ReaderAt, index int64, block []byte) (bool, error) {
+	n, err := r.ReadAt(block, index*int64(len(block)))
+	if n < len(block) {
+		if err == io.EOF {
+			err = nil
+		}
+		return false, err
+	}
+	hash := rp.cfg.NewHash()
+	setChecksumIndex(hash, index)
+	hash.Write(block[:rp.cfg.Interval])
+	return checksumMatches(hash, block[rp.cfg.Interval:], nil), nil
+}
+
+// verify returns the indexes of the complete intervals of the target that
+// are not checksum valid.
+func (rp *RepairPlan) verify() ([]int64, error) {
+	var corrupt []int64
+	block := make([]byte, rp.blockSize())
+	for i := int64(0); i < rp.size/int64(len(block)); i++ {
+		ok, err := rp.read(rp.target, i, block)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			corrupt = append(corrupt, i)
+		}
+	}
+	return corrupt, nil
+}
+
+// Execute fetches each planned interval from its sources, in their order of
+// preference, re-verifying each copy before writing it to the target. Once
+// done the entire target is verified again, returning the indexes of any
+// intervals that are still not checksum valid, or nil if all are valid.
+//
+// Errors from sources just move on to the next source; an error is only
+// returned if the target itself fails.
+func (rp *RepairPlan) Execute() ([]int64, error) {
+	workers := rp.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	fetchChan := make(chan RepairFetch)
+	errChan := make(chan error, workers)
+	clock := clockOrSystem(rp.Clock)
+	limiter := &rateLimiter{bytesPerSecond: rp.BytesPerSecond, clock: clock, start: clock.Now()}
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			block := make([]byte, rp.blockSize())
+			for f := range fetchChan {
+				for _, j := range f.Sources {
+					limiter.wait(int64(len(block)))
+					if ok, err := rp.read(rp.sources[j], f.Block, block); err != nil || !ok {
+						continue
+					}
+					if _, err := rp.target.WriteAt(block, f.Block*int64(len(block))); err != nil {
+						errChan <- err
+						return
+					}
+					break
+				}
+			}
+		}()
+	}
+	var err error
+	for _, f := range rp.Fetches {
+		select {
+		case fetchChan <- f:
+			continue
+		case err = <-errChan:
+		}
+		break
+	}
+	close(fetchChan)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errChan:
+		default:
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error writing repair: %s", err)
+	}
+	return rp.verify()
+}
+
+// rateLimiter paces work to stay under bytesPerSecond on average. If stop is
+// not nil, closing it cuts short any wait.
+type rateLimiter struct {
+	bytesPerSecond int64
+	clock          Clock
+	stop           <-chan struct{}
+	lock           sync.Mutex
+	start          time.Time
+	total          int64
+}
+
+// wait waits until n more bytes of work are due, returning false if stop was
+// closed first.
+func (rl *rateLimiter) wait(n int64) bool {
+	if rl.bytesPerSecond <= 0 {
+		return true
+	}
+	rl.lock.Lock()
+	rl.total += n
+	// In floating point, as total times a second in nanoseconds overflows
+	// an int64 after about 9.2GB, long before a continuous scrub ends.
+	due := rl.start.Add(time.Duration(float64(rl.total) / float64(rl.bytesPerSecond) * float64(time.Second)))
+	rl.lock.Unlock()
+	d := due.Sub(rl.clock.Now())
+	if d <= 0 {
+		return true
+	}
+	// Only the system clock's sleeps take real time worth interrupting.
+	if rl.stop == nil || rl.clock != SystemClock {
+		rl.clock.Sleep(d)
+		return true
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-rl.stop:
+		return false
+	}
+}
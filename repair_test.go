@@ -0,0 +1,82 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRepairPlan(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz12345678901234567890"))
+	cw.Close()
+	good := buf.Bytes()
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	b := append([]byte{}, good...)
+	b[1] = 'X'
+	b[25] = 'X'
+	b[45] = 'X'
+	if _, err = f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	// The first replica only has the first interval, the others have a
+	// corrupt first and second interval.
+	r1 := append([]byte{}, good...)[:20]
+	r2 := append([]byte{}, good...)
+	r2[1] = 'Y'
+	r2[21] = 'Y'
+	r3 := append([]byte{}, good...)
+	r3[1] = 'Z'
+	r3[21] = 'Z'
+	cfg := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	rp, err := NewRepairPlan(f, int64(len(b)), cfg, []io.ReaderAt{bytes.NewReader(r1), bytes.NewReader(r2), bytes.NewReader(r3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rp.Fetches) != 2 || rp.Fetches[0].Block != 0 || len(rp.Fetches[0].Sources) != 1 || rp.Fetches[0].Sources[0] != 0 || rp.Fetches[1].Block != 2 || len(rp.Fetches[1].Sources) != 2 || rp.Fetches[1].Sources[0] != 1 {
+		t.Fatalf("%#v", rp.Fetches)
+	}
+	if len(rp.Unrepairable) != 1 || rp.Unrepairable[0] != 1 {
+		t.Fatal(rp.Unrepairable)
+	}
+	rp.Workers = 2
+	rp.BytesPerSecond = 1 << 20
+	corrupt, err := rp.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupt) != 1 || corrupt[0] != 1 {
+		t.Fatal(corrupt)
+	}
+	v, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(v[:20], good[:20]) || !bytes.Equal(v[40:], good[40:]) {
+		t.Fatalf("%#v", string(v))
+	}
+}
+
+func TestRateLimiterLong(t *testing.T) {
+	start := time.Unix(0, 0)
+	clock := NewManualClock(start)
+	rl := &rateLimiter{bytesPerSecond: 10 << 20, clock: clock, start: start}
+	// 12.5GiB at 10MiB/s, well past where the delay used to overflow.
+	for i := 0; i < 100; i++ {
+		rl.wait(128 << 20)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 1280*time.Second {
+		t.Fatal(elapsed)
+	}
+}
@@ -0,0 +1,115 @@
+package brimio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestScrambledReader(t *testing.T) {
+	var r io.Reader = NewSeededScrambled(1)
+	a := make([]byte, 1000)
+	if _, err := io.ReadFull(r, a); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1000)
+	if n, err := NewSeededScrambled(1).Read(b); n != 1000 || err != nil {
+		t.Fatal(n, err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatal("same seed gave different data")
+	}
+	if n, err := io.CopyN(ioutil.Discard, NewScrambled(), 12345); n != 12345 || err != nil {
+		t.Fatal(n, err)
+	}
+	if v, err := ioutil.ReadAll(io.LimitReader(r, 100)); len(v) != 100 || err != nil {
+		t.Fatal(len(v), err)
+	}
+}
+
+func TestSecureScrambled(t *testing.T) {
+	a := make([]byte, 64)
+	b := make([]byte, 64)
+	if n, err := NewSecureScrambled().Read(a); n != 64 || err != nil {
+		t.Fatal(n, err)
+	}
+	if n, err := NewSecureScrambled().Read(b); n != 64 || err != nil {
+		t.Fatal(n, err)
+	}
+	if bytes.Equal(a, b) || bytes.Equal(a, make([]byte, 64)) {
+		t.Fatal("not random")
+	}
+}
+
+func TestFastScrambled(t *testing.T) {
+	a := make([]byte, 1003)
+	if n, err := NewFastScrambled(1).Read(a); n != 1003 || err != nil {
+		t.Fatal(n, err)
+	}
+	// The same stream regardless of how the reads are sized.
+	b := make([]byte, 1003)
+	s := NewFastScrambled(1)
+	s.Read(b[:3])
+	s.Read(b[3:5])
+	s.Read(b[5:16])
+	s.Read(b[16:997])
+	s.Read(b[997:])
+	if !bytes.Equal(a, b) {
+		t.Fatal("same seed gave different data")
+	}
+	NewFastScrambled(2).Read(b)
+	if bytes.Equal(a, b) {
+		t.Fatal("different seeds gave the same data")
+	}
+}
+
+func benchmarkScrambled(b *testing.B, s *Scrambled) {
+	v := make([]byte, 65536)
+	b.SetBytes(int64(len(v)))
+	for i := 0; i < b.N; i++ {
+		s.Read(v)
+	}
+}
+
+func BenchmarkScrambled(b *testing.B) {
+	benchmarkScrambled(b, NewSeededScrambled(1))
+}
+
+func BenchmarkFastScrambled(b *testing.B) {
+	benchmarkScrambled(b, NewFastScrambled(1))
+}
+
+func BenchmarkSecureScrambled(b *testing.B) {
+	benchmarkScrambled(b, NewSecureScrambled())
+}
+
+func TestSeededReaderAt(t *testing.T) {
+	sra := NewSeededReaderAt(1)
+	a := make([]byte, 1003)
+	if n, err := sra.ReadAt(a, 0); n != 1003 || err != nil {
+		t.Fatal(n, err)
+	}
+	b := make([]byte, 1003)
+	NewFastScrambled(1).Read(b)
+	if !bytes.Equal(a, b) {
+		t.Fatal("differs from NewFastScrambled")
+	}
+	// Any offset and order of reads gives the same bytes.
+	for _, r := range []Range{{Offset: 997, Length: 6}, {Offset: 3, Length: 13}, {Offset: 500, Length: 1}, {Offset: 7, Length: 1}, {Offset: 17, Length: 100}} {
+		v := make([]byte, r.Length)
+		if _, err := NewSeededReaderAt(1).ReadAt(v, r.Offset); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(v, a[r.Offset:r.Offset+r.Length]) {
+			t.Fatal(r)
+		}
+	}
+	v, err := ioutil.ReadAll(io.NewSectionReader(sra, 10, 20))
+	if err != nil || !bytes.Equal(v, a[10:30]) {
+		t.Fatal(v, err)
+	}
+	if _, err = sra.ReadAt(v, -1); err == nil {
+		t.Fatal("expected error")
+	}
+}
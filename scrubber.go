@@ -0,0 +1,179 @@
+package brimio
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Scrubber verifies every interval of checksummed content in the
+// background, at a limited rate, so long-running services can continuously
+// validate data at rest without crowding out foreground I/O.
+//
+// Set any options and then call Start; Stop ends scrubbing early, even while
+// waiting on BytesPerSecond, and Wait waits for it to finish on its own. The ChecksummedReader is used by the
+// Scrubber's goroutine alone until then.
+type Scrubber struct {
+	// BytesPerSecond, if greater than 0, limits how fast content is read.
+	BytesPerSecond int64
+	// Continuous has the Scrubber start over once it reaches the end,
+	// scrubbing until stopped; this is best used with BytesPerSecond.
+	Continuous bool
+	// OnCorrupt, if not nil, is called from the Scrubber's goroutine with
+	// the index of each interval found not checksum valid.
+	OnCorrupt func(blockIndex int64)
+	// Clock is the source of time for rate limiting and results;
+	// SystemClock if nil.
+	Clock Clock
+	// Index, if not nil, has each complete pass of a Scrubber from
+	// NewFileScrubber recorded in it, letting others skip verifying the file
+	// again while the record stands.
+	Index    *VerificationIndex
+	reader   ChecksummedReader
+	interval int
+	file     *os.File
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	started  bool
+	lock     sync.Mutex
+	result   *ScrubFileResult
+	err      error
+}
+
+// NewScrubber returns a Scrubber for the content of a ChecksummedReader
+// with the interval given.
+func NewScrubber(cr ChecksummedReader, interval int) *Scrubber {
+	return &Scrubber{reader: cr, interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+}
+
+// NewFileScrubber returns a Scrubber for the checksummed file at path,
+// opening it now and closing it once scrubbing ends.
+func NewFileScrubber(path string, cfg ChecksummedConfig) (*Scrubber, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s := NewScrubber(NewChecksummedReaderHash(f, cfg.Interval, cfg.NewHash), cfg.Interval)
+	s.file = f
+	return s, nil
+}
+
+// Start begins scrubbing in a new goroutine.
+func (s *Scrubber) Start() {
+	s.lock.Lock()
+	s.started = true
+	s.lock.Unlock()
+	go s.run()
+}
+
+// Stop ends scrubbing at the next interval and waits for it to finish,
+// returning the same as Wait. It may be called more than once, and before
+// Start, in which case scrubbing ends as soon as it starts.
+func (s *Scrubber) Stop() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.lock.Lock()
+	started := s.started
+	s.lock.Unlock()
+	if !started {
+		return nil
+	}
+	return s.Wait()
+}
+
+// Wait waits for scrubbing to finish, returning the first error encountered,
+// if any.
+func (s *Scrubber) Wait() error {
+	<-s.done
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.err
+}
+
+// Result returns the ScrubFileResult of the last complete pass over the
+// content, or nil if none has completed yet.
+func (s *Scrubber) Result() *ScrubFileResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.result
+}
+
+func (s *Scrubber) run() {
+	defer close(s.done)
+	err := s.scrub()
+	if s.file != nil {
+		if err2 := s.file.Close(); err == nil {
+			err = err2
+		}
+	}
+	s.lock.Lock()
+	s.err = err
+	s.lock.Unlock()
+}
+
+func (s *Scrubber) scrub() error {
+	if s.interval <= 0 {
+		return fmt.Errorf("invalid interval %d", s.interval)
+	}
+	clock := clockOrSystem(s.Clock)
+	limiter := &rateLimiter{bytesPerSecond: s.BytesPerSecond, clock: clock, stop: s.stop, start: clock.Now()}
+	for {
+		var fingerprint FileFingerprint
+		if s.file != nil && s.Index != nil {
+			fi, err := s.file.Stat()
+			if err != nil {
+				return err
+			}
+			fingerprint = FileFingerprint{Size: fi.Size(), ModTime: fi.ModTime()}
+		}
+		size, err := s.reader.Size()
+		if err != nil {
+			return err
+		}
+		blocks := size / int64(s.interval)
+		result := NewScrubFileResult(clock.Now(), blocks)
+		if blocks == 0 {
+			// Pace passes over empty content as if it were an interval long.
+			if !limiter.wait(int64(s.interval)) {
+				return nil
+			}
+		}
+		for i := int64(0); i < blocks; i++ {
+			select {
+			case <-s.stop:
+				return nil
+			default:
+			}
+			if !limiter.wait(int64(s.interval)) {
+				return nil
+			}
+			corrupt, err := s.reader.VerifyRange(i*int64(s.interval), int64(s.interval))
+			if err != nil {
+				return err
+			}
+			for _, c := range corrupt {
+				result.SetCorrupt(c)
+				if s.OnCorrupt != nil {
+					s.OnCorrupt(c)
+				}
+			}
+		}
+		s.lock.Lock()
+		s.result = result
+		s.lock.Unlock()
+		if s.file != nil && s.Index != nil {
+			s.Index.Record(s.file.Name(), fingerprint, len(result.CorruptBlocks()) == 0)
+		}
+		if !s.Continuous {
+			return nil
+		}
+		select {
+		case <-s.stop:
+			return nil
+		default:
+		}
+	}
+}
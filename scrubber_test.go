@@ -0,0 +1,98 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestScrubber(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz1234567890"))
+	cw.Close()
+	b := buf.Bytes()
+	b[25] = 'X'
+	clock := NewManualClock(time.Unix(1000, 0))
+	s := NewScrubber(NewChecksummedReader(bytes.NewReader(b), 16, crc32.NewIEEE), 16)
+	s.BytesPerSecond = 16
+	s.Clock = clock
+	var corrupt []int64
+	s.OnCorrupt = func(blockIndex int64) {
+		corrupt = append(corrupt, blockIndex)
+	}
+	s.Start()
+	if err := s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupt) != 1 || corrupt[0] != 1 {
+		t.Fatal(corrupt)
+	}
+	r := s.Result()
+	if r == nil || r.Blocks != 3 || len(r.CorruptBlocks()) != 1 || !r.IsCorrupt(1) {
+		t.Fatalf("%#v", r)
+	}
+	// Three intervals at one interval per second.
+	if d := clock.Now().Sub(time.Unix(1000, 0)); d != 3*time.Second {
+		t.Fatal(d)
+	}
+	s = NewScrubber(NewChecksummedReader(bytes.NewReader(b), 16, crc32.NewIEEE), 16)
+	s.Continuous = true
+	s.BytesPerSecond = 1 << 20
+	s.Start()
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	// Stopping interrupts the 16 second wait for the first interval.
+	s = NewScrubber(NewChecksummedReader(bytes.NewReader(b), 16, crc32.NewIEEE), 16)
+	s.BytesPerSecond = 1
+	s.Start()
+	start := time.Now()
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatal(d)
+	}
+	// Stopping before starting has scrubbing end at once.
+	s = NewScrubber(NewChecksummedReader(bytes.NewReader(b), 16, crc32.NewIEEE), 16)
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	if err := s.Wait(); err != nil || s.Result() != nil {
+		t.Fatal(err, s.Result())
+	}
+}
+
+func TestFileScrubber(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	s, err := NewFileScrubber(f.Name(), ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	if err = s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if r := s.Result(); r == nil || r.Blocks != 2 || r.CorruptBlocks() != nil {
+		t.Fatalf("%#v", r)
+	}
+	if _, err = NewFileScrubber(f.Name()+".missing", ChecksummedConfig{}); err == nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,268 @@
+package brimio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// ScrubResultsMagic starts the content of every serialized ScrubResults.
+const ScrubResultsMagic = "BRIMIOSR"
+
+// ScrubResultsVersion is the format version of ScrubResults written by this
+// package.
+const ScrubResultsVersion = 1
+
+// scrubResultsInterval is the checksum interval of serialized ScrubResults.
+const scrubResultsInterval = 4096
+
+// ScrubResults holds the outcome of scrubbing files, by path, so the results
+// of multiple scrubbers or runs can be stored, combined, and trended.
+type ScrubResults struct {
+	Files map[string]*ScrubFileResult
+}
+
+// ScrubFileResult is the outcome of scrubbing a single file.
+type ScrubFileResult struct {
+	// Scrubbed is when the file was scrubbed.
+	Scrubbed time.Time
+	// Blocks is the number of intervals in the file.
+	Blocks int64
+	// Corrupt is a bitmap of the intervals found not checksum valid, the
+	// lowest bit of the first byte being interval 0.
+	Corrupt []byte
+}
+
+// ScrubSummary gives totals across ScrubResults. This package has no command
+// line tools of its own; a tool printing summaries can use its String.
+type ScrubSummary struct {
+	Files         int
+	CorruptFiles  int
+	Blocks        int64
+	CorruptBlocks int64
+	Oldest        time.Time
+	Newest        time.Time
+}
+
+// NewScrubResults returns an empty ScrubResults.
+func NewScrubResults() *ScrubResults {
+	return &ScrubResults{Files: make(map[string]*ScrubFileResult)}
+}
+
+// NewScrubFileResult returns a ScrubFileResult for a file of blocks intervals
+// scrubbed at the time given, with no intervals yet marked corrupt.
+func NewScrubFileResult(scrubbed time.Time, blocks int64) *ScrubFileResult {
+	return &ScrubFileResult{Scrubbed: scrubbed, Blocks: blocks, Corrupt: make([]byte, (blocks+7)/8)}
+}
+
+// SetCorrupt marks the interval at index as not checksum valid. Indexes past
+// Blocks, or past the end of a Corrupt bitmap built too short, are ignored.
+func (sfr *ScrubFileResult) SetCorrupt(index int64) {
+	if sfr.has(index) {
+		sfr.Corrupt[index/8] |= 1 << uint(index%8)
+	}
+}
+
+// IsCorrupt returns true if the interval at index was marked not checksum
+// valid.
+func (sfr *ScrubFileResult) IsCorrupt(index int64) bool {
+	return sfr.has(index) && sfr.Corrupt[index/8]&(1<<uint(index%8)) != 0
+}
+
+// has returns true if index is within both Blocks and the Corrupt bitmap,
+// which callers building a ScrubFileResult directly may leave short.
+func (sfr *ScrubFileResult) has(index int64) bool {
+	return index >= 0 && index < sfr.Blocks && index/8 < int64(len(sfr.Corrupt))
+}
+
+// CorruptBlocks returns the indexes of the intervals marked not checksum
+// valid.
+func (sfr *ScrubFileResult) CorruptBlocks() []int64 {
+	var indexes []int64
+	for i := int64(0); i < sfr.Blocks; i++ {
+		if sfr.IsCorrupt(i) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Merge combines other into sr. For a file in both, the most recently
+// scrubbed result is kept; results from the same time have their corrupt
+// intervals combined.
+func (sr *ScrubResults) Merge(other *ScrubResults) {
+	for path, theirs := range other.Files {
+		ours := sr.Files[path]
+		switch {
+		case ours == nil || theirs.Scrubbed.After(ours.Scrubbed):
+			c := *theirs
+			c.Corrupt = append([]byte{}, theirs.Corrupt...)
+			sr.Files[path] = &c
+		case theirs.Scrubbed.Equal(ours.Scrubbed):
+			for _, i := range theirs.CorruptBlocks() {
+				if i >= ours.Blocks {
+					break
+				}
+				ours.SetCorrupt(i)
+			}
+		}
+	}
+}
+
+// Summary returns the ScrubSummary of sr.
+func (sr *ScrubResults) Summary() ScrubSummary {
+	var s ScrubSummary
+	for _, sfr := range sr.Files {
+		s.Files++
+		s.Blocks += sfr.Blocks
+		if c := int64(len(sfr.CorruptBlocks())); c > 0 {
+			s.CorruptFiles++
+			s.CorruptBlocks += c
+		}
+		if s.Oldest.IsZero() || sfr.Scrubbed.Before(s.Oldest) {
+			s.Oldest = sfr.Scrubbed
+		}
+		if sfr.Scrubbed.After(s.Newest) {
+			s.Newest = sfr.Scrubbed
+		}
+	}
+	return s
+}
+
+// String returns s on one line, such as for a command line tool to print.
+func (s ScrubSummary) String() string {
+	str := fmt.Sprintf("%d files, %d corrupt; %d blocks, %d corrupt", s.Files, s.CorruptFiles, s.Blocks, s.CorruptBlocks)
+	if s.Files > 0 {
+		str += fmt.Sprintf("; scrubbed %s to %s", s.Oldest.UTC().Format(time.RFC3339), s.Newest.UTC().Format(time.RFC3339))
+	}
+	return str
+}
+
+// WriteScrubResults writes sr to w as checksummed content with a
+// ChecksummedHeader, so it can be read back with ReadScrubResults.
+//
+// The content is ScrubResultsMagic, a 1 byte version, and a uvarint count of
+// files; then for each file, sorted by path, a uvarint length and that many
+// bytes of path, a varint Scrubbed time in Unix nanoseconds, a uvarint
+// Blocks, and the Corrupt bitmap. Zero bytes pad the content out to a whole
+// interval.
+func WriteScrubResults(w io.Writer, sr *ScrubResults) error {
+	cw, err := NewChecksummedWriterWithHeader(w, scrubResultsInterval, "crc32-ieee")
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(cw)
+	bw.WriteString(ScrubResultsMagic)
+	bw.WriteByte(ScrubResultsVersion)
+	paths := make([]string, 0, len(sr.Files))
+	for path := range sr.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var b [binary.MaxVarintLen64]byte
+	bw.Write(b[:binary.PutUvarint(b[:], uint64(len(paths)))])
+	for _, path := range paths {
+		sfr := sr.Files[path]
+		bw.Write(b[:binary.PutUvarint(b[:], uint64(len(path)))])
+		bw.WriteString(path)
+		bw.Write(b[:binary.PutVarint(b[:], sfr.Scrubbed.UnixNano())])
+		bw.Write(b[:binary.PutUvarint(b[:], uint64(sfr.Blocks))])
+		bitmap := make([]byte, (sfr.Blocks+7)/8)
+		copy(bitmap, sfr.Corrupt)
+		bw.Write(bitmap)
+	}
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	// Padding out the final interval means all the content is checksummed,
+	// rather than having a trailing partial interval.
+	if pad := cw.Stats().BytesWritten % scrubResultsInterval; pad > 0 {
+		if _, err = cw.Write(make([]byte, scrubResultsInterval-pad)); err != nil {
+			return err
+		}
+	}
+	return cw.CloseWithoutDelegate()
+}
+
+// ReadScrubResults reads ScrubResults written by WriteScrubResults,
+// returning ErrChecksumMismatch if any of it is not checksum valid.
+func ReadScrubResults(r io.Reader) (*ScrubResults, error) {
+	h, err := ReadChecksummedHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	newHash, err := h.NewHash()
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadAll(NewStreamingChecksummedReaderHash(r, h.Interval, newHash))
+	if err != nil {
+		return nil, err
+	}
+	// Only whole intervals are written, so anything else is a truncated
+	// interval that couldn't be verified.
+	if len(content)%h.Interval != 0 {
+		return nil, fmt.Errorf("scrub results truncated")
+	}
+	br := bytes.NewReader(content)
+	magic := make([]byte, len(ScrubResultsMagic)+1)
+	if _, err = io.ReadFull(br, magic); err != nil {
+		return nil, scrubResultsErr(err)
+	}
+	if string(magic[:len(ScrubResultsMagic)]) != ScrubResultsMagic {
+		return nil, fmt.Errorf("not scrub results")
+	}
+	if magic[len(ScrubResultsMagic)] != ScrubResultsVersion {
+		return nil, fmt.Errorf("unknown scrub results version %d", magic[len(ScrubResultsMagic)])
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, scrubResultsErr(err)
+	}
+	sr := NewScrubResults()
+	for ; count > 0; count-- {
+		l, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, scrubResultsErr(err)
+		}
+		// Lengths are bounded by what remains so corrupt ones can't cause
+		// huge allocations.
+		if l > uint64(br.Len()) {
+			return nil, scrubResultsErr(io.ErrUnexpectedEOF)
+		}
+		path := make([]byte, l)
+		if _, err = io.ReadFull(br, path); err != nil {
+			return nil, scrubResultsErr(err)
+		}
+		nanos, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, scrubResultsErr(err)
+		}
+		blocks, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, scrubResultsErr(err)
+		}
+		if blocks > uint64(br.Len())*8 {
+			return nil, scrubResultsErr(io.ErrUnexpectedEOF)
+		}
+		sfr := NewScrubFileResult(time.Unix(0, nanos), int64(blocks))
+		if _, err = io.ReadFull(br, sfr.Corrupt); err != nil {
+			return nil, scrubResultsErr(err)
+		}
+		sr.Files[string(path)] = sfr
+	}
+	return sr, nil
+}
+
+// scrubResultsErr reports running out of content as truncation.
+func scrubResultsErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("scrub results truncated")
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package brimio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestScrubResults(t *testing.T) {
+	t1 := time.Unix(1000, 0)
+	t2 := time.Unix(2000, 0)
+	sr := NewScrubResults()
+	a := NewScrubFileResult(t1, 20)
+	a.SetCorrupt(3)
+	a.SetCorrupt(17)
+	sr.Files["a"] = a
+	sr.Files["b"] = NewScrubFileResult(t1, 5)
+	buf := &bytes.Buffer{}
+	if err := WriteScrubResults(buf, sr); err != nil {
+		t.Fatal(err)
+	}
+	sr2, err := ReadScrubResults(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := sr2.Files["a"].CorruptBlocks(); len(c) != 2 || c[0] != 3 || c[1] != 17 || !sr2.Files["a"].Scrubbed.Equal(t1) {
+		t.Fatalf("%#v", sr2.Files["a"])
+	}
+	b := append([]byte{}, buf.Bytes()...)
+	b[len(b)-6] ^= 1
+	if _, err = ReadScrubResults(bytes.NewReader(b)); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+	if _, err = ReadScrubResults(bytes.NewReader(buf.Bytes()[:buf.Len()-8])); err == nil {
+		t.Fatal()
+	}
+	other := NewScrubResults()
+	a = NewScrubFileResult(t1, 20)
+	a.SetCorrupt(5)
+	other.Files["a"] = a
+	other.Files["b"] = NewScrubFileResult(t2, 5)
+	other.Files["b"].SetCorrupt(4)
+	other.Files["c"] = NewScrubFileResult(t2, 1)
+	sr2.Merge(other)
+	if c := sr2.Files["a"].CorruptBlocks(); len(c) != 3 || c[1] != 5 {
+		t.Fatal(c)
+	}
+	if !sr2.Files["b"].IsCorrupt(4) || !sr2.Files["b"].Scrubbed.Equal(t2) {
+		t.Fatalf("%#v", sr2.Files["b"])
+	}
+	s := sr2.Summary()
+	if s.Files != 3 || s.CorruptFiles != 2 || s.Blocks != 26 || s.CorruptBlocks != 4 || !s.Oldest.Equal(t1) || !s.Newest.Equal(t2) {
+		t.Fatalf("%#v", s)
+	}
+}
+
+func TestScrubResultsCorruptLengths(t *testing.T) {
+	for _, tail := range [][]byte{
+		// A path length beyond the content.
+		{1, 0xff, 0xff, 0xff, 0xff, 0x0f},
+		// A block count beyond 2^63.
+		{1, 1, 'a', 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+	} {
+		buf := &bytes.Buffer{}
+		cw, err := NewChecksummedWriterWithHeader(buf, scrubResultsInterval, "crc32-ieee")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := make([]byte, scrubResultsInterval)
+		copy(content, ScrubResultsMagic)
+		content[len(ScrubResultsMagic)] = ScrubResultsVersion
+		copy(content[len(ScrubResultsMagic)+1:], tail)
+		cw.Write(content)
+		if err = cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = ReadScrubResults(bytes.NewReader(buf.Bytes())); err == nil {
+			t.Fatal(tail)
+		}
+	}
+}
+
+func TestScrubFileResultShortBitmap(t *testing.T) {
+	// Built directly, without NewScrubFileResult sizing Corrupt to Blocks.
+	sfr := &ScrubFileResult{Blocks: 20, Corrupt: []byte{0x02}}
+	sfr.SetCorrupt(15)
+	if !sfr.IsCorrupt(1) || sfr.IsCorrupt(15) || sfr.IsCorrupt(19) {
+		t.Fatal(sfr.Corrupt)
+	}
+	if c := sfr.CorruptBlocks(); len(c) != 1 || c[0] != 1 {
+		t.Fatal(c)
+	}
+	sr := NewScrubResults()
+	sr.Files["a"] = sfr
+	s := sr.Summary()
+	if s.CorruptBlocks != 1 {
+		t.Fatal(s)
+	}
+	s.Oldest = time.Unix(1000, 0)
+	s.Newest = time.Unix(2000, 0)
+	if str := s.String(); str != "1 files, 1 corrupt; 20 blocks, 1 corrupt; scrubbed 1970-01-01T00:16:40Z to 1970-01-01T00:33:20Z" {
+		t.Fatal(str)
+	}
+	if str := (ScrubSummary{}).String(); str != "0 files, 0 corrupt; 0 blocks, 0 corrupt" {
+		t.Fatal(str)
+	}
+}
@@ -0,0 +1,240 @@
+package brimio
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// ScrubCandidate is what a ScrubPolicy knows of a file when deciding how
+// soon it should be scrubbed.
+type ScrubCandidate struct {
+	Path string
+	// LastVerified is when the file was last scrubbed by the ScrubScheduler;
+	// the zero time if never.
+	LastVerified time.Time
+	// ReadErrors is the number of read errors reported for the file since it
+	// was last scrubbed.
+	ReadErrors int
+	// Heat is the caller's score of how much the file matters, such as by
+	// how often it is read.
+	Heat float64
+	// Failures is the number of scrubs of the file in a row that failed with
+	// an error, and LastFailed when the last of them did.
+	Failures   int
+	LastFailed time.Time
+}
+
+// Scrubs that fail with an error are retried after a backoff starting at
+// scrubFailureBackoff and doubling with each failure in a row, up to
+// maxScrubFailureBackoff.
+const (
+	scrubFailureBackoff    = time.Minute
+	maxScrubFailureBackoff = 24 * time.Hour
+)
+
+// backingOff returns true if c failed to scrub too recently to retry yet.
+func (c *ScrubCandidate) backingOff(now time.Time) bool {
+	if c.Failures == 0 {
+		return false
+	}
+	backoff := maxScrubFailureBackoff
+	if c.Failures <= 20 {
+		if b := scrubFailureBackoff << uint(c.Failures-1); b < backoff {
+			backoff = b
+		}
+	}
+	return now.Before(c.LastFailed.Add(backoff))
+}
+
+// ScrubPolicy prioritizes files for a ScrubScheduler, so limited scrub
+// bandwidth goes first where corruption is likeliest or would hurt most.
+type ScrubPolicy interface {
+	// Priority returns how urgently the file should be scrubbed as of now;
+	// the file with the highest priority is scrubbed next.
+	Priority(c ScrubCandidate, now time.Time) float64
+}
+
+// ScrubPolicyFunc is a ScrubPolicy from an ordinary function.
+type ScrubPolicyFunc func(c ScrubCandidate, now time.Time) float64
+
+// Priority implements the ScrubPolicy interface.
+func (f ScrubPolicyFunc) Priority(c ScrubCandidate, now time.Time) float64 {
+	return f(c, now)
+}
+
+// ScrubByAge prioritizes files by the time since they were last scrubbed,
+// files never scrubbed coming first.
+var ScrubByAge ScrubPolicy = ScrubPolicyFunc(func(c ScrubCandidate, now time.Time) float64 {
+	if c.LastVerified.IsZero() {
+		return math.Inf(1)
+	}
+	return now.Sub(c.LastVerified).Seconds()
+})
+
+// ScrubByReadErrors prioritizes files by their read errors reported since
+// they were last scrubbed, falling back to age among files with as many.
+var ScrubByReadErrors ScrubPolicy = ScrubPolicyFunc(func(c ScrubCandidate, now time.Time) float64 {
+	return float64(c.ReadErrors) + ageFraction(c, now)
+})
+
+// ScrubByHeat prioritizes files by their caller-supplied heat scores,
+// falling back to age among files as hot.
+var ScrubByHeat ScrubPolicy = ScrubPolicyFunc(func(c ScrubCandidate, now time.Time) float64 {
+	return c.Heat + ageFraction(c, now)
+})
+
+// ageFraction maps the age of c into [0, 1), growing with age, to break ties
+// between otherwise equal priorities.
+func ageFraction(c ScrubCandidate, now time.Time) float64 {
+	if c.LastVerified.IsZero() {
+		return 0.999
+	}
+	age := now.Sub(c.LastVerified).Seconds()
+	if age <= 0 {
+		return 0
+	}
+	return age / (age + 86400) * 0.999
+}
+
+// ScrubScheduler chooses which of a set of checksummed files to scrub next,
+// as its ScrubPolicy says, and scrubs them one at a time. It is safe for
+// concurrent use, so read paths can report errors and heat while it scrubs.
+type ScrubScheduler struct {
+	// Policy orders the files; ScrubByAge if nil.
+	Policy ScrubPolicy
+	// Config is the layout of the files.
+	Config ChecksummedConfig
+	// BytesPerSecond, if greater than 0, limits how fast each file is read.
+	BytesPerSecond int64
+	// OnCorrupt, if not nil, is called with the path and index of each
+	// interval found not checksum valid.
+	OnCorrupt func(path string, blockIndex int64)
+	// Clock is the source of time for priorities and rate limiting;
+	// SystemClock if nil.
+	Clock Clock
+	// Index, if not nil, has each file's scrub recorded in it as with
+	// Scrubber.Index.
+	Index *VerificationIndex
+	lock  sync.Mutex
+	files map[string]*ScrubCandidate
+}
+
+// NewScrubScheduler returns a ScrubScheduler with no files for checksummed
+// files with the layout given, ordered by policy.
+func NewScrubScheduler(cfg ChecksummedConfig, policy ScrubPolicy) *ScrubScheduler {
+	return &ScrubScheduler{Policy: policy, Config: cfg, files: make(map[string]*ScrubCandidate)}
+}
+
+// Add includes the file at path in scheduling, if not already.
+func (ss *ScrubScheduler) Add(path string) {
+	ss.lock.Lock()
+	if ss.files[path] == nil {
+		ss.files[path] = &ScrubCandidate{Path: path}
+	}
+	ss.lock.Unlock()
+}
+
+// Remove excludes the file at path from scheduling.
+func (ss *ScrubScheduler) Remove(path string) {
+	ss.lock.Lock()
+	delete(ss.files, path)
+	ss.lock.Unlock()
+}
+
+// ReadError notes a read error with the file at path, if scheduled.
+func (ss *ScrubScheduler) ReadError(path string) {
+	ss.lock.Lock()
+	if c := ss.files[path]; c != nil {
+		c.ReadErrors++
+	}
+	ss.lock.Unlock()
+}
+
+// SetHeat sets the heat score of the file at path, if scheduled.
+func (ss *ScrubScheduler) SetHeat(path string, heat float64) {
+	ss.lock.Lock()
+	if c := ss.files[path]; c != nil {
+		c.Heat = heat
+	}
+	ss.lock.Unlock()
+}
+
+// Next returns the file to scrub next, the one of highest priority, ties
+// going to the lowest path; the bool is false if there are no files. Files
+// whose scrubs recently failed are passed over while any others remain, so
+// one failing file doesn't keep the rest from being scrubbed.
+func (ss *ScrubScheduler) Next() (ScrubCandidate, bool) {
+	policy := ss.Policy
+	if policy == nil {
+		policy = ScrubByAge
+	}
+	now := clockOrSystem(ss.Clock).Now()
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+	var best *ScrubCandidate
+	var bestPriority float64
+	var bestBackingOff bool
+	for _, c := range ss.files {
+		p := policy.Priority(*c, now)
+		b := c.backingOff(now)
+		if best == nil || (bestBackingOff && !b) || (b == bestBackingOff && (p > bestPriority || (p == bestPriority && c.Path < best.Path))) {
+			best = c
+			bestPriority = p
+			bestBackingOff = b
+		}
+	}
+	if best == nil {
+		return ScrubCandidate{}, false
+	}
+	return *best, true
+}
+
+// ScrubNext scrubs the file Next returns, returning its path and the
+// result; the path is empty if there are no files. The file's LastVerified
+// is updated and its ReadErrors and Failures cleared once the scrub
+// completes; if it fails instead, its Failures and LastFailed are updated.
+func (ss *ScrubScheduler) ScrubNext() (string, *ScrubFileResult, error) {
+	c, ok := ss.Next()
+	if !ok {
+		return "", nil, nil
+	}
+	s, err := NewFileScrubber(c.Path, ss.Config)
+	if err != nil {
+		return c.Path, nil, ss.failed(c.Path, err)
+	}
+	s.BytesPerSecond = ss.BytesPerSecond
+	s.Clock = ss.Clock
+	s.Index = ss.Index
+	if ss.OnCorrupt != nil {
+		s.OnCorrupt = func(blockIndex int64) {
+			ss.OnCorrupt(c.Path, blockIndex)
+		}
+	}
+	s.Start()
+	if err = s.Wait(); err != nil {
+		return c.Path, nil, ss.failed(c.Path, err)
+	}
+	result := s.Result()
+	ss.lock.Lock()
+	if f := ss.files[c.Path]; f != nil {
+		f.LastVerified = result.Scrubbed
+		f.ReadErrors = 0
+		f.Failures = 0
+	}
+	ss.lock.Unlock()
+	return c.Path, result, nil
+}
+
+// failed records that scrubbing the file at path failed with err, returning
+// err.
+func (ss *ScrubScheduler) failed(path string, err error) error {
+	now := clockOrSystem(ss.Clock).Now()
+	ss.lock.Lock()
+	if f := ss.files[path]; f != nil {
+		f.Failures++
+		f.LastFailed = now
+	}
+	ss.lock.Unlock()
+	return err
+}
@@ -0,0 +1,128 @@
+package brimio
+
+import (
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScrubScheduler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var paths []string
+	for _, name := range []string{"a", "b", "c"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+		cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+		cw.Close()
+		paths = append(paths, f.Name())
+	}
+	clock := NewManualClock(time.Unix(1000, 0))
+	ss := NewScrubScheduler(ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}, nil)
+	ss.Clock = clock
+	if path, r, err := ss.ScrubNext(); path != "" || r != nil || err != nil {
+		t.Fatal(path, r, err)
+	}
+	for _, path := range paths {
+		ss.Add(path)
+	}
+	// Never scrubbed, by path.
+	for _, want := range paths {
+		clock.Advance(time.Second)
+		path, r, err := ss.ScrubNext()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != want || r.Blocks != 2 || r.CorruptBlocks() != nil {
+			t.Fatalf("%s %#v", path, r)
+		}
+	}
+	// Oldest first.
+	if c, _ := ss.Next(); c.Path != paths[0] || !c.LastVerified.Equal(time.Unix(1001, 0)) {
+		t.Fatalf("%#v", c)
+	}
+	ss.Policy = ScrubByReadErrors
+	ss.ReadError(paths[2])
+	if c, _ := ss.Next(); c.Path != paths[2] || c.ReadErrors != 1 {
+		t.Fatalf("%#v", c)
+	}
+	if path, _, err := ss.ScrubNext(); err != nil || path != paths[2] {
+		t.Fatal(path, err)
+	}
+	if c, _ := ss.Next(); c.Path != paths[0] {
+		t.Fatalf("%#v", c)
+	}
+	ss.Policy = ScrubByHeat
+	ss.SetHeat(paths[1], 5)
+	if c, _ := ss.Next(); c.Path != paths[1] || c.Heat != 5 {
+		t.Fatalf("%#v", c)
+	}
+	ss.Remove(paths[1])
+	if c, _ := ss.Next(); c.Path != paths[0] {
+		t.Fatalf("%#v", c)
+	}
+}
+
+func TestScrubSchedulerFailureBackoff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	good := filepath.Join(dir, "b")
+	f, err := os.Create(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	missing := filepath.Join(dir, "a")
+	clock := NewManualClock(time.Unix(1000, 0))
+	ss := NewScrubScheduler(ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}, ScrubByReadErrors)
+	ss.Clock = clock
+	ss.Add(missing)
+	ss.Add(good)
+	ss.ReadError(missing)
+	if path, _, err := ss.ScrubNext(); path != missing || err == nil {
+		t.Fatal(path, err)
+	}
+	// The failing file is passed over while backing off.
+	if path, _, err := ss.ScrubNext(); path != good || err != nil {
+		t.Fatal(path, err)
+	}
+	if c, _ := ss.Next(); c.Path != good {
+		t.Fatalf("%#v", c)
+	}
+	clock.Advance(scrubFailureBackoff)
+	if path, _, err := ss.ScrubNext(); path != missing || err == nil {
+		t.Fatal(path, err)
+	}
+	// The backoff doubles with another failure.
+	clock.Advance(scrubFailureBackoff)
+	if c, _ := ss.Next(); c.Path != good {
+		t.Fatalf("%#v", c)
+	}
+	clock.Advance(scrubFailureBackoff)
+	if c, _ := ss.Next(); c.Path != missing || c.Failures != 2 {
+		t.Fatalf("%#v", c)
+	}
+	// With nothing else to scrub, a file backing off is still returned.
+	ss.Remove(good)
+	if path, _, err := ss.ScrubNext(); path != missing || err == nil {
+		t.Fatal(path, err)
+	}
+	if c, ok := ss.Next(); !ok || c.Path != missing || c.Failures != 3 {
+		t.Fatalf("%#v", c)
+	}
+}
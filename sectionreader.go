@@ -0,0 +1,86 @@
+package brimio
+
+import (
+	"fmt"
+	"io"
+)
+
+// ChecksummedSectionReader is an io.ReadSeeker over a window of the content
+// of a ChecksummedReader, such as one of many objects packed into a single
+// checksummed file. Offsets are relative to the start of the window, which
+// is translated to the underlying content, and then its checksums, by the
+// ChecksummedReader as usual.
+//
+// The ChecksummedReader is read from the section's position, seeking it
+// there whenever it isn't already, so it should not be otherwise used while
+// the section is in use.
+type ChecksummedSectionReader struct {
+	r     ChecksummedReader
+	base  int64
+	off   int64
+	limit int64
+	// at is the position of r, relative to base, if known, or -1.
+	at int64
+}
+
+// NewChecksummedSectionReader returns a ChecksummedSectionReader reading n
+// bytes of the content of r starting at offset.
+func NewChecksummedSectionReader(r ChecksummedReader, offset int64, n int64) *ChecksummedSectionReader {
+	return &ChecksummedSectionReader{r: r, base: offset, limit: offset + n, off: offset, at: -1}
+}
+
+// Read implements the io.Reader interface, returning io.EOF at the end of
+// the section. A section extending past the end of the content is cut short
+// with io.ErrUnexpectedEOF.
+func (csr *ChecksummedSectionReader) Read(v []byte) (int, error) {
+	if csr.off >= csr.limit {
+		return 0, io.EOF
+	}
+	if csr.at != csr.off {
+		if _, err := csr.r.Seek(csr.off, io.SeekStart); err != nil {
+			csr.at = -1
+			return 0, err
+		}
+		csr.at = csr.off
+	}
+	if max := csr.limit - csr.off; int64(len(v)) > max {
+		v = v[:max]
+	}
+	n, err := csr.r.Read(v)
+	csr.off += int64(n)
+	csr.at = csr.off
+	if err != nil {
+		csr.at = -1
+		if err == io.EOF && csr.off < csr.limit {
+			err = io.ErrUnexpectedEOF
+		} else if err == io.EOF {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+// Seek implements the io.Seeker interface, relative to the section; io.SeekEnd
+// is relative to its end.
+func (csr *ChecksummedSectionReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		offset += csr.base
+	case io.SeekCurrent:
+		offset += csr.off
+	case io.SeekEnd:
+		offset += csr.limit
+	default:
+		return csr.off - csr.base, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < csr.base {
+		return csr.off - csr.base, fmt.Errorf("negative position %d", offset-csr.base)
+	}
+	csr.off = offset
+	return offset - csr.base, nil
+}
+
+// Size returns the length of the section.
+func (csr *ChecksummedSectionReader) Size() int64 {
+	return csr.limit - csr.base
+}
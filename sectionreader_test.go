@@ -0,0 +1,45 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestChecksummedSectionReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE)
+	csr := NewChecksummedSectionReader(cr, 14, 10)
+	if csr.Size() != 10 {
+		t.Fatal(csr.Size())
+	}
+	v, err := ioutil.ReadAll(csr)
+	if err != nil || string(v) != "567890ghij" {
+		t.Fatal(string(v), err)
+	}
+	if o, err := csr.Seek(-4, io.SeekEnd); err != nil || o != 6 {
+		t.Fatal(o, err)
+	}
+	// Moving the ChecksummedReader elsewhere doesn't disturb the section.
+	cr.Seek(0, io.SeekStart)
+	v = make([]byte, 2)
+	if _, err = io.ReadFull(csr, v); err != nil || string(v) != "gh" {
+		t.Fatal(string(v), err)
+	}
+	if o, err := csr.Seek(-1, io.SeekStart); err == nil || o != 8 {
+		t.Fatal(o, err)
+	}
+	csr = NewChecksummedSectionReader(cr, 32, 10)
+	if v, err = ioutil.ReadAll(csr); err != io.ErrUnexpectedEOF || string(v) != "stuvwxyz" {
+		t.Fatal(string(v), err)
+	}
+	csr = NewChecksummedSectionReader(cr, 36, 4)
+	if v, err = ioutil.ReadAll(csr); err != nil || string(v) != "wxyz" {
+		t.Fatal(string(v), err)
+	}
+}
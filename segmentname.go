@@ -0,0 +1,61 @@
+package brimio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SegmentName identifies a segment file by its generation, sequence within
+// that generation, and creation time, so tools across a deployment agree on
+// naming.
+//
+// As a string it is formatted as
+// "GGGGGGGGGGGGGGGGGGGG-SSSSSSSSSSSSSSSSSSSS-YYYYMMDDTHHMMSSNNNNNNNNNZ" with
+// each field zero-padded and the time in UTC, so names sort by generation,
+// then sequence, then creation time.
+type SegmentName struct {
+	Generation uint64
+	Sequence   uint64
+	Created    time.Time
+}
+
+const segmentNameTimeLayout = "20060102T150405"
+
+// String returns the formatted name, without any extension.
+func (sn SegmentName) String() string {
+	c := sn.Created.UTC()
+	return fmt.Sprintf("%020d-%020d-%s%09dZ", sn.Generation, sn.Sequence, c.Format(segmentNameTimeLayout), c.Nanosecond())
+}
+
+// ParseSegmentName parses a name formatted by SegmentName.String, ignoring
+// any extension such as ".data" following it.
+func ParseSegmentName(name string) (SegmentName, error) {
+	var sn SegmentName
+	base := name
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	parts := strings.Split(base, "-")
+	if len(parts) != 3 || len(parts[0]) != 20 || len(parts[1]) != 20 || len(parts[2]) != len(segmentNameTimeLayout)+10 || !strings.HasSuffix(parts[2], "Z") {
+		return sn, fmt.Errorf("invalid segment name %q", name)
+	}
+	var err error
+	if sn.Generation, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return sn, fmt.Errorf("invalid generation in segment name %q: %s", name, err)
+	}
+	if sn.Sequence, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return sn, fmt.Errorf("invalid sequence in segment name %q: %s", name, err)
+	}
+	t, err := time.Parse(segmentNameTimeLayout, parts[2][:len(segmentNameTimeLayout)])
+	if err != nil {
+		return sn, fmt.Errorf("invalid time in segment name %q: %s", name, err)
+	}
+	nanos, err := strconv.ParseUint(parts[2][len(segmentNameTimeLayout):len(parts[2])-1], 10, 32)
+	if err != nil {
+		return sn, fmt.Errorf("invalid time in segment name %q: %s", name, err)
+	}
+	sn.Created = t.Add(time.Duration(nanos))
+	return sn, nil
+}
@@ -0,0 +1,43 @@
+package brimio
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSegmentName(t *testing.T) {
+	created := time.Date(2026, 10, 16, 12, 34, 56, 789, time.FixedZone("", 3600))
+	sn := SegmentName{Generation: 3, Sequence: 42, Created: created}
+	s := sn.String()
+	if s != "00000000000000000003-00000000000000000042-20261016T113456000000789Z" {
+		t.Fatal(s)
+	}
+	sn2, err := ParseSegmentName(s + ".data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sn2.Generation != 3 || sn2.Sequence != 42 || !sn2.Created.Equal(created) {
+		t.Fatalf("%#v", sn2)
+	}
+	names := []string{
+		SegmentName{Generation: 10, Sequence: 1, Created: created}.String(),
+		SegmentName{Generation: 2, Sequence: 100, Created: created}.String(),
+		SegmentName{Generation: 2, Sequence: 9, Created: created}.String(),
+	}
+	sort.Strings(names)
+	for i, want := range [][2]uint64{{2, 9}, {2, 100}, {10, 1}} {
+		sn, err := ParseSegmentName(names[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sn.Generation != want[0] || sn.Sequence != want[1] {
+			t.Fatalf("%d %#v", i, sn)
+		}
+	}
+	for _, bad := range []string{"", "1-2-3", "0000000000000000000x-00000000000000000042-20261016T113456000000789Z", "00000000000000000003-00000000000000000042-20261316T113456000000789Z"} {
+		if _, err = ParseSegmentName(bad); err == nil {
+			t.Fatal(bad)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package brimio
+
+// ChecksummedContent is an io.ReadSeeker over the content of a
+// ChecksummedReader guaranteed to suit http.ServeContent, which is given the
+// checksummed blob as it would be a plain file and serves range requests
+// from it: seeking relative to the end is relative to the end of the
+// content, so ServeContent's Seek(0, io.SeekEnd) finds the content's size,
+// and reads only ever return content, never checksums.
+//
+// The reads verify as the ChecksummedReader's options say; WithAutoVerify is
+// recommended so nothing unverified is served. As ServeContent has already
+// sent the headers by the time content is read, an interval found not
+// checksum valid can only cut the response short, which the client sees as
+// a truncated body.
+type ChecksummedContent struct {
+	cr ChecksummedReader
+}
+
+// NewChecksummedContent returns the ChecksummedContent of cr.
+func NewChecksummedContent(cr ChecksummedReader) *ChecksummedContent {
+	return &ChecksummedContent{cr: cr}
+}
+
+// Read implements the io.Reader interface.
+func (cc *ChecksummedContent) Read(v []byte) (int, error) {
+	return cc.cr.Read(v)
+}
+
+// Seek implements the io.Seeker interface over the content's offsets.
+func (cc *ChecksummedContent) Seek(offset int64, whence int) (int64, error) {
+	return cc.cr.Seek(offset, whence)
+}
+
+// Size returns the length of the content, not including checksums.
+func (cc *ChecksummedContent) Size() (int64, error) {
+	return cc.cr.Size()
+}
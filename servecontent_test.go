@@ -0,0 +1,50 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestChecksummedContent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	serve := func(rangeHeader string) *http.Response {
+		cr, err := NewChecksummedReaderWith(bytes.NewReader(buf.Bytes()), WithInterval(16), WithAutoVerify())
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest("GET", "/blob", nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		rec := httptest.NewRecorder()
+		http.ServeContent(rec, req, "blob", time.Time{}, NewChecksummedContent(cr))
+		return rec.Result()
+	}
+	resp := serve("")
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != 40 || string(body) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatal(resp.StatusCode, resp.ContentLength, string(body))
+	}
+	resp = serve("bytes=14-21")
+	body, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("Content-Range") != "bytes 14-21/40" || string(body) != "567890gh" {
+		t.Fatal(resp.StatusCode, resp.Header.Get("Content-Range"), string(body))
+	}
+	resp = serve("bytes=-3")
+	body, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "xyz" {
+		t.Fatal(resp.StatusCode, string(body))
+	}
+	cr := NewChecksummedReader(bytes.NewReader(buf.Bytes()), 16, crc32.NewIEEE)
+	if size, err := NewChecksummedContent(cr).Size(); err != nil || size != 40 {
+		t.Fatal(size, err)
+	}
+}
@@ -0,0 +1,406 @@
+package brimio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+// SlottedFileMagic starts each superblock of a SlottedFile.
+const SlottedFileMagic = "BRIMIOSF"
+
+// SlottedFileVersion is the format version of SlottedFiles written by this
+// package.
+const SlottedFileVersion = 1
+
+// ErrSlottedFileFull is returned by SlottedFile.Append when every slot has
+// been allocated.
+var ErrSlottedFileFull = fmt.Errorf("slotted file full")
+
+// ErrSlotsRelocated is returned by SlottedFile.ReadGeneration when Compact has
+// relocated slots since the generation given.
+var ErrSlotsRelocated = fmt.Errorf("slots relocated")
+
+// slotOverhead is the length prefix and checksum stored with each slot.
+const slotOverhead = 8
+
+// SlottedFile stores many small objects in one large preallocated file of
+// fixed size slots, such as for an object store that wants O(1) lookup by
+// slot number without a file per object. It is safe for concurrent use.
+//
+// The file starts with two copies of a superblock holding the slot layout, a
+// generation counter, and a bitmap of the slots in use, each protected by a
+// CRC32; updates alternate between the copies so a torn write leaves the
+// other intact, and the valid copy with the highest generation is used when
+// opening. The slots follow, each a 4 byte length, up to SlotSize bytes of
+// content, and a CRC32 of the two.
+//
+// Append writes an object to the lowest free slot, and only then marks it in
+// use in the superblock, syncing in between if the underlying ReadWriterAt
+// has a Sync method; a crash before the superblock is written just leaves the
+// slot free. Delete frees a slot for reuse.
+//
+// Compact relocates live slots toward the front, one at a time so other
+// calls can proceed in between, and truncates the file after the last live
+// slot. Each relocation copies the object and then moves its in use bit with
+// a single superblock write, so a crash leaves it at one slot or the other.
+// Since slot numbers change, readers that looked up a slot before the
+// compaction can use ReadGeneration to detect that it may have moved.
+type SlottedFile struct {
+	lock       sync.RWMutex
+	delegate   ReadWriterAt
+	slotSize   int
+	slotCount  int64
+	generation uint64
+	bitmap     []byte
+	// free is the lowest slot that might be free.
+	free int64
+	// relocated counts the slots Compact has relocated.
+	relocated uint64
+	superLen  int64
+}
+
+// CreateSlottedFile initializes a SlottedFile of slotCount slots of up to
+// slotSize bytes each within delegate, such as a new file, preallocating its
+// full length.
+func CreateSlottedFile(delegate ReadWriterAt, slotSize int, slotCount int64) (*SlottedFile, error) {
+	if slotSize < 1 || slotCount < 1 {
+		return nil, fmt.Errorf("invalid slot size %d or count %d", slotSize, slotCount)
+	}
+	sf := newSlottedFile(delegate, slotSize, slotCount)
+	for i := 0; i < 2; i++ {
+		if err := sf.writeSuperblock(); err != nil {
+			return nil, err
+		}
+	}
+	// Writing the last byte extends the file to its full length.
+	if _, err := delegate.WriteAt([]byte{0}, sf.slotOffset(slotCount)-1); err != nil {
+		return nil, err
+	}
+	return sf, syncDelegate(delegate)
+}
+
+// OpenSlottedFile opens a SlottedFile previously created within delegate.
+func OpenSlottedFile(delegate ReadWriterAt) (*SlottedFile, error) {
+	head := make([]byte, len(SlottedFileMagic)+2+4+8)
+	if _, err := delegate.ReadAt(head, 0); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(head[:len(SlottedFileMagic)], []byte(SlottedFileMagic)) {
+		return nil, fmt.Errorf("not a slotted file")
+	}
+	p := head[len(SlottedFileMagic):]
+	if v := binary.BigEndian.Uint16(p); v != SlottedFileVersion {
+		return nil, fmt.Errorf("unsupported slotted file version %d", v)
+	}
+	slotSize := int(binary.BigEndian.Uint32(p[2:]))
+	slotCount := int64(binary.BigEndian.Uint64(p[6:]))
+	if slotSize < 1 || slotCount < 1 || slotCount > 1<<40 {
+		return nil, fmt.Errorf("invalid slot size %d or count %d", slotSize, slotCount)
+	}
+	// The layout is only trusted once a superblock's CRC confirms it, which
+	// is checked while streaming so a corrupt slot count can't cause a huge
+	// allocation; a superblock that checks out is in the file in full, so
+	// the bitmap allocated for it is bounded by the file's size.
+	superLen := slottedSuperLen(slotCount)
+	best := int64(-1)
+	var generation uint64
+	for i := int64(0); i < 2; i++ {
+		if g, ok := checkSuperblock(delegate, i*superLen, superLen, head); ok && (best < 0 || g > generation) {
+			best = i
+			generation = g
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("no valid slotted file superblock")
+	}
+	sf := newSlottedFile(delegate, slotSize, slotCount)
+	b := make([]byte, sf.superLen)
+	if _, err := delegate.ReadAt(b, best*sf.superLen); err != nil {
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(b[len(b)-4:]) != crc32.ChecksumIEEE(b[:len(b)-4]) {
+		return nil, fmt.Errorf("no valid slotted file superblock")
+	}
+	sf.generation = binary.BigEndian.Uint64(b[len(head):])
+	copy(sf.bitmap, b[len(head)+8:])
+	return sf, nil
+}
+
+// checkSuperblock returns the generation of the superblock of superLen bytes
+// at offset and whether it is valid: starting with head and matching its
+// CRC. It reads in pieces rather than all at once.
+func checkSuperblock(delegate ReadWriterAt, offset int64, superLen int64, head []byte) (uint64, bool) {
+	b := make([]byte, len(head)+8)
+	if _, err := delegate.ReadAt(b, offset); err != nil || !bytes.Equal(b[:len(head)], head) {
+		return 0, false
+	}
+	crc := crc32.NewIEEE()
+	if n, err := io.Copy(crc, io.NewSectionReader(delegate, offset, superLen-4)); err != nil || n != superLen-4 {
+		return 0, false
+	}
+	sum := make([]byte, 4)
+	if _, err := delegate.ReadAt(sum, offset+superLen-4); err != nil || binary.BigEndian.Uint32(sum) != crc.Sum32() {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(b[len(head):]), true
+}
+
+// slottedSuperLen returns the length of each superblock copy for slotCount
+// slots.
+func slottedSuperLen(slotCount int64) int64 {
+	return int64(len(SlottedFileMagic)+2+4+8+8) + (slotCount+7)/8 + 4
+}
+
+func newSlottedFile(delegate ReadWriterAt, slotSize int, slotCount int64) *SlottedFile {
+	return &SlottedFile{
+		delegate:  delegate,
+		slotSize:  slotSize,
+		slotCount: slotCount,
+		bitmap:    make([]byte, (slotCount+7)/8),
+		superLen:  slottedSuperLen(slotCount),
+	}
+}
+
+// SlotSize returns the most content a slot can hold.
+func (sf *SlottedFile) SlotSize() int {
+	return sf.slotSize
+}
+
+// SlotCount returns the number of slots.
+func (sf *SlottedFile) SlotCount() int64 {
+	return sf.slotCount
+}
+
+// InUse returns whether the slot given holds an object.
+func (sf *SlottedFile) InUse(slot int64) bool {
+	sf.lock.RLock()
+	defer sf.lock.RUnlock()
+	return slot >= 0 && slot < sf.slotCount && sf.inUse(slot)
+}
+
+func (sf *SlottedFile) inUse(slot int64) bool {
+	return sf.bitmap[slot/8]&(1<<uint(slot%8)) != 0
+}
+
+func (sf *SlottedFile) setInUse(slot int64, inUse bool) {
+	if inUse {
+		sf.bitmap[slot/8] |= 1 << uint(slot%8)
+	} else {
+		sf.bitmap[slot/8] &^= 1 << uint(slot%8)
+	}
+}
+
+func (sf *SlottedFile) slotOffset(slot int64) int64 {
+	return 2*sf.superLen + slot*int64(sf.slotSize+slotOverhead)
+}
+
+// Append stores v in the lowest free slot, returning that slot's number.
+func (sf *SlottedFile) Append(v []byte) (int64, error) {
+	if len(v) > sf.slotSize {
+		return -1, fmt.Errorf("%d bytes exceeds slot size %d", len(v), sf.slotSize)
+	}
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	for sf.free < sf.slotCount && sf.inUse(sf.free) {
+		sf.free++
+	}
+	if sf.free >= sf.slotCount {
+		return -1, ErrSlottedFileFull
+	}
+	slot := sf.free
+	if err := sf.writeSlot(slot, v); err != nil {
+		return -1, err
+	}
+	sf.setInUse(slot, true)
+	if err := sf.writeSuperblock(); err != nil {
+		sf.setInUse(slot, false)
+		return -1, err
+	}
+	sf.free++
+	return slot, nil
+}
+
+// Delete frees the slot given for reuse.
+func (sf *SlottedFile) Delete(slot int64) error {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	if slot < 0 || slot >= sf.slotCount || !sf.inUse(slot) {
+		return fmt.Errorf("slot %d not in use", slot)
+	}
+	sf.setInUse(slot, false)
+	if err := sf.writeSuperblock(); err != nil {
+		sf.setInUse(slot, true)
+		return err
+	}
+	if slot < sf.free {
+		sf.free = slot
+	}
+	return nil
+}
+
+// Generation returns a counter that changes whenever Compact relocates a
+// slot; see ReadGeneration.
+func (sf *SlottedFile) Generation() uint64 {
+	sf.lock.RLock()
+	defer sf.lock.RUnlock()
+	return sf.relocated
+}
+
+// Compact relocates live slots from the end of the file into free slots
+// nearer the front until the live slots are contiguous, then truncates the
+// file after the last of them if the underlying ReadWriterAt has a Truncate
+// method; later Appends extend the file again. It returns the new slot
+// number of each relocated slot, keyed by its old number.
+func (sf *SlottedFile) Compact() (map[int64]int64, error) {
+	moved := make(map[int64]int64)
+	for {
+		done, err := sf.relocateOne(moved)
+		if err != nil {
+			return moved, err
+		}
+		if done {
+			break
+		}
+	}
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	t, ok := sf.delegate.(interface{ Truncate(int64) error })
+	if !ok {
+		return moved, nil
+	}
+	last := sf.slotCount - 1
+	for last >= 0 && !sf.inUse(last) {
+		last--
+	}
+	if err := t.Truncate(sf.slotOffset(last + 1)); err != nil {
+		return moved, err
+	}
+	return moved, syncDelegate(sf.delegate)
+}
+
+// relocateOne moves the last live slot into the lowest free slot, if that is
+// nearer the front, recording the move; it returns true once there is
+// nothing left to move.
+func (sf *SlottedFile) relocateOne(moved map[int64]int64) (bool, error) {
+	sf.lock.Lock()
+	defer sf.lock.Unlock()
+	for sf.free < sf.slotCount && sf.inUse(sf.free) {
+		sf.free++
+	}
+	from := sf.slotCount - 1
+	for from > sf.free && !sf.inUse(from) {
+		from--
+	}
+	if from <= sf.free {
+		return true, nil
+	}
+	v, err := sf.read(from)
+	if err != nil {
+		return false, err
+	}
+	to := sf.free
+	if err = sf.writeSlot(to, v); err != nil {
+		return false, err
+	}
+	sf.setInUse(to, true)
+	sf.setInUse(from, false)
+	if err = sf.writeSuperblock(); err != nil {
+		sf.setInUse(to, false)
+		sf.setInUse(from, true)
+		return false, err
+	}
+	sf.free++
+	sf.relocated++
+	// A slot moved more than once is reported by its original number.
+	orig := from
+	for o, n := range moved {
+		if n == from {
+			orig = o
+			break
+		}
+	}
+	moved[orig] = to
+	return false, nil
+}
+
+// writeSlot writes v to slot and syncs it. The whole slot is written, so it
+// can be read back in full even once Compact has truncated the file before
+// it.
+func (sf *SlottedFile) writeSlot(slot int64, v []byte) error {
+	b := make([]byte, sf.slotSize+slotOverhead)
+	binary.BigEndian.PutUint32(b, uint32(len(v)))
+	copy(b[4:], v)
+	binary.BigEndian.PutUint32(b[4+len(v):], crc32.ChecksumIEEE(b[:4+len(v)]))
+	if _, err := sf.delegate.WriteAt(b, sf.slotOffset(slot)); err != nil {
+		return err
+	}
+	return syncDelegate(sf.delegate)
+}
+
+// writeSuperblock writes the next generation of the superblock over the
+// older of the two copies and syncs it.
+func (sf *SlottedFile) writeSuperblock() error {
+	sf.generation++
+	b := make([]byte, 0, sf.superLen)
+	b = append(b, SlottedFileMagic...)
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	p := b[len(SlottedFileMagic):]
+	binary.BigEndian.PutUint16(p, SlottedFileVersion)
+	binary.BigEndian.PutUint32(p[2:], uint32(sf.slotSize))
+	binary.BigEndian.PutUint64(p[6:], uint64(sf.slotCount))
+	binary.BigEndian.PutUint64(p[14:], sf.generation)
+	b = append(b, sf.bitmap...)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[len(b)-4:], crc32.ChecksumIEEE(b[:len(b)-4]))
+	if _, err := sf.delegate.WriteAt(b, int64(sf.generation%2)*sf.superLen); err != nil {
+		return err
+	}
+	return syncDelegate(sf.delegate)
+}
+
+// Read returns the object stored in slot, or ErrChecksumMismatch if the slot
+// is corrupt.
+func (sf *SlottedFile) Read(slot int64) ([]byte, error) {
+	sf.lock.RLock()
+	defer sf.lock.RUnlock()
+	return sf.read(slot)
+}
+
+// ReadGeneration is Read, but returns ErrSlotsRelocated if Compact has
+// relocated slots since Generation returned the generation given, in which
+// case the slot number may no longer refer to the same object.
+func (sf *SlottedFile) ReadGeneration(slot int64, generation uint64) ([]byte, error) {
+	sf.lock.RLock()
+	defer sf.lock.RUnlock()
+	if sf.relocated != generation {
+		return nil, ErrSlotsRelocated
+	}
+	return sf.read(slot)
+}
+
+func (sf *SlottedFile) read(slot int64) ([]byte, error) {
+	if slot < 0 || slot >= sf.slotCount || !sf.inUse(slot) {
+		return nil, fmt.Errorf("slot %d not in use", slot)
+	}
+	b := make([]byte, sf.slotSize+slotOverhead)
+	if _, err := sf.delegate.ReadAt(b, sf.slotOffset(slot)); err != nil {
+		return nil, err
+	}
+	l := int(binary.BigEndian.Uint32(b))
+	if l > sf.slotSize || binary.BigEndian.Uint32(b[4+l:]) != crc32.ChecksumIEEE(b[:4+l]) {
+		return nil, ErrChecksumMismatch
+	}
+	return b[4 : 4+l], nil
+}
+
+// syncDelegate syncs delegate if it has a Sync method, such as *os.File.
+func syncDelegate(delegate interface{}) error {
+	if s, ok := delegate.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package brimio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestSlottedFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio-slotted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	sf, err := CreateSlottedFile(f, 16, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range []string{"zero", "", "two is the last"} {
+		slot, err := sf.Append([]byte(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slot != int64(i) {
+			t.Fatalf("%#v", slot)
+		}
+	}
+	if _, err = sf.Append([]byte("x")); err != ErrSlottedFileFull {
+		t.Fatal(err)
+	}
+	if _, err = sf.Append(make([]byte, 17)); err == nil {
+		t.Fatal("expected error for oversized content")
+	}
+	sf, err = OpenSlottedFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.SlotSize() != 16 || sf.SlotCount() != 3 {
+		t.Fatalf("%#v %#v", sf.SlotSize(), sf.SlotCount())
+	}
+	v, err := sf.Read(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "two is the last" {
+		t.Fatalf("%#v", string(v))
+	}
+	if v, err = sf.Read(1); err != nil || len(v) != 0 {
+		t.Fatalf("%#v %#v", v, err)
+	}
+	// Corrupting slot 0's content is detected.
+	if _, err = f.WriteAt([]byte("Z"), sf.slotOffset(0)+4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sf.Read(0); err != ErrChecksumMismatch {
+		t.Fatal(err)
+	}
+}
+
+func TestSlottedFileTornSuperblock(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio-slotted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	sf, err := CreateSlottedFile(f, 8, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sf.Append([]byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = sf.Append([]byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	// Tear the newest superblock copy; the older one, lacking the second
+	// slot, is used instead and the slot is allocated again.
+	newest := int64(sf.generation%2) * sf.superLen
+	if _, err = f.WriteAt(bytes.Repeat([]byte{0xff}, 4), newest+sf.superLen-4); err != nil {
+		t.Fatal(err)
+	}
+	sf, err = OpenSlottedFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sf.InUse(0) || sf.InUse(1) {
+		t.Fatalf("%#v %#v", sf.InUse(0), sf.InUse(1))
+	}
+	slot, err := sf.Append([]byte("again"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot != 1 {
+		t.Fatalf("%#v", slot)
+	}
+	if v, err := sf.Read(0); err != nil || string(v) != "first" {
+		t.Fatalf("%#v %#v", string(v), err)
+	}
+}
+
+func TestSlottedFileCorruptSlotCount(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio-slotted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err = CreateSlottedFile(f, 8, 20); err != nil {
+		t.Fatal(err)
+	}
+	// Claim the most slots allowed, whose bitmap would be 128GiB; opening
+	// must fail on the CRC without allocating for it.
+	count := make([]byte, 8)
+	binary.BigEndian.PutUint64(count, 1<<40)
+	if _, err = f.WriteAt(count, int64(len(SlottedFileMagic)+2+4)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenSlottedFile(f); err == nil {
+		t.Fatal("expected invalid superblock")
+	}
+}
+
+func TestSlottedFileDeleteAndCompact(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio-slotted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	sf, err := CreateSlottedFile(f, 8, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"a", "b", "c", "d", "e", "f"} {
+		if _, err = sf.Append([]byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, slot := range []int64{1, 3, 4} {
+		if err = sf.Delete(slot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = sf.Delete(3); err == nil {
+		t.Fatal("expected error deleting a free slot")
+	}
+	// The lowest free slot is reused.
+	if slot, err := sf.Append([]byte("g")); err != nil || slot != 1 {
+		t.Fatalf("%#v %#v", slot, err)
+	}
+	gen := sf.Generation()
+	moved, err := sf.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[5] != 3 {
+		t.Fatalf("%#v", moved)
+	}
+	if _, err = sf.ReadGeneration(3, gen); err != ErrSlotsRelocated {
+		t.Fatal(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != sf.slotOffset(4) {
+		t.Fatalf("%#v", fi.Size())
+	}
+	sf, err = OpenSlottedFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for slot, want := range []string{"a", "g", "c", "f"} {
+		v, err := sf.ReadGeneration(int64(slot), sf.Generation())
+		if err != nil || string(v) != want {
+			t.Fatalf("%d %#v %#v", slot, string(v), err)
+		}
+	}
+	// Appends extend the file again after truncation.
+	slot, err := sf.Append([]byte("h"))
+	if err != nil || slot != 4 {
+		t.Fatalf("%#v %#v", slot, err)
+	}
+	if v, err := sf.Read(4); err != nil || string(v) != "h" {
+		t.Fatalf("%#v %#v", string(v), err)
+	}
+}
@@ -0,0 +1,148 @@
+package brimio
+
+import (
+	"hash"
+	"io"
+)
+
+// NewStreamingChecksummedReader returns an io.ReadCloser that reads content
+// written by ChecksummedWriter from a plain io.Reader, such as a network
+// socket, that cannot Seek.
+//
+// Content is read and verified an interval at a time, with any interval that
+// is not checksum valid resulting in ErrChecksumMismatch from Read rather than
+// its content. Any trailing partial interval has no checksum and is returned
+// unverified.
+//
+// Intervals ended early by ChecksummedWriter.Flush are padded out to whole
+// intervals and marked as such by their checksums, so they are followed
+// without any searching and return just the content written before the
+// Flush.
+func NewStreamingChecksummedReader(delegate io.Reader, interval int, newHash func() hash.Hash32) io.ReadCloser {
+	return newStreamingChecksummedReader(delegate, interval, hash32(newHash))
+}
+
+// NewStreamingChecksummedReaderHash is the same as
+// NewStreamingChecksummedReader but for checksums of newHash().Size() bytes
+// using the hashing function given.
+func NewStreamingChecksummedReaderHash(delegate io.Reader, interval int, newHash func() hash.Hash) io.ReadCloser {
+	return newStreamingChecksummedReader(delegate, interval, newHash)
+}
+
+type streamingChecksummedReader struct {
+	delegate         io.Reader
+	checksumInterval int
+	newHash          func() hash.Hash
+	checksum         []byte
+	block            []byte
+	// content is the verified but not yet returned content of block.
+	content []byte
+	// index is that of the interval read next, for hashes from
+	// NewIndexedHash.
+	index int64
+	err   error
+}
+
+func newStreamingChecksummedReader(delegate io.Reader, interval int, newHash func() hash.Hash) *streamingChecksummedReader {
+	mustValidateChecksummed(interval, newHash)
+	checksumSize := newHash().Size()
+	return &streamingChecksummedReader{
+		delegate:         delegate,
+		checksumInterval: interval,
+		newHash:          newHash,
+		checksum:         make([]byte, checksumSize),
+		block:            make([]byte, interval+checksumSize),
+	}
+}
+
+func (scr *streamingChecksummedReader) Read(v []byte) (int, error) {
+	if len(scr.content) == 0 {
+		if scr.err != nil {
+			return 0, scr.err
+		}
+		n, err := io.ReadFull(scr.delegate, scr.block)
+		switch err {
+		case nil:
+			content, ok := checkInterval(scr.newHash(), scr.checksumInterval, scr.block, scr.index, scr.checksum)
+			if !ok {
+				scr.err = ErrChecksumMismatch
+				return 0, scr.err
+			}
+			scr.content = scr.block[:content]
+			scr.index++
+		case io.ErrUnexpectedEOF:
+			if n > scr.checksumInterval {
+				// The checksum itself was cut short, so the content can't
+				// be trusted.
+				scr.err = ErrChecksumMismatch
+				return 0, scr.err
+			}
+			scr.content = scr.block[:n]
+			scr.err = io.EOF
+		default:
+			scr.err = err
+			return 0, err
+		}
+	}
+	n := copy(v, scr.content)
+	scr.content = scr.content[n:]
+	return n, nil
+}
+
+// flushMark is hashed after the padded content of an interval ended early
+// by ChecksummedWriter.Flush, so its checksum differs from the one the same
+// bytes would have as a whole interval and the two can't be confused.
+var flushMark = []byte{0x80}
+
+// appendFlushPadding appends n bytes of the padding that completes an
+// interval ended early by ChecksummedWriter.Flush: 0x80 and then zeros, so
+// the content's end can be found again by the last nonzero byte.
+func appendFlushPadding(b []byte, n int) []byte {
+	b = append(b, 0x80)
+	for i := 1; i < n; i++ {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// checkInterval verifies block, a whole interval and its checksum, at index
+// within the underlying content, returning the length of its content: the
+// whole interval, or less for one ended early by ChecksummedWriter.Flush
+// and padded. It returns false if the checksum is for neither. The hash
+// must be new or reset; scratch is overwritten.
+func checkInterval(hash hash.Hash, interval int, block []byte, index int64, scratch []byte) (int, bool) {
+	setChecksumIndex(hash, index)
+	hash.Write(block[:interval])
+	if checksumMatches(hash, block[interval:], scratch[:0]) {
+		return interval, true
+	}
+	hash.Write(flushMark)
+	if !checksumMatches(hash, block[interval:], scratch[:0]) {
+		return 0, false
+	}
+	n := interval - 1
+	for n > 0 && block[n] == 0 {
+		n--
+	}
+	if n == 0 || block[n] != 0x80 {
+		// Flush never pads an empty interval, so this was not written by
+		// one.
+		return 0, false
+	}
+	return n, true
+}
+
+func (scr *streamingChecksummedReader) Close() error {
+	var err error
+	if c, ok := scr.delegate.(io.Closer); ok {
+		err = c.Close()
+	}
+	scr.delegate = errDelegate
+	scr.content = nil
+	scr.err = nil
+	return err
+}
+
+func (scr *streamingChecksummedReader) Unwrap() interface{} {
+	return unwrapDelegate(scr.delegate)
+}
@@ -0,0 +1,151 @@
+package brimio
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// NewSynchronizedChecksummedReader returns a ChecksummedReader that guards
+// every call to cr with a mutex, so a single reader can be shared by
+// goroutines that coordinate their positions at a higher level, such as by
+// always seeking before reading under their own locking, without racing on
+// cr's internal state.
+//
+// Every call, including long running ones like VerifyAll, holds the mutex
+// throughout, so calls are serialized and contend with one another. Where
+// concurrency matters, separate readers of the same content, or reading an
+// io.ReaderAt with RangeDigestAt or a BatchPlanner, scale far better.
+func NewSynchronizedChecksummedReader(cr ChecksummedReader) ChecksummedReader {
+	return &synchronizedChecksummedReader{cr: cr}
+}
+
+type synchronizedChecksummedReader struct {
+	lock sync.Mutex
+	cr   ChecksummedReader
+}
+
+func (scr *synchronizedChecksummedReader) Read(v []byte) (int, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Read(v)
+}
+
+func (scr *synchronizedChecksummedReader) ReadByte() (byte, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.ReadByte()
+}
+
+func (scr *synchronizedChecksummedReader) Seek(offset int64, whence int) (int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Seek(offset, whence)
+}
+
+// WriteTo implements the io.WriterTo interface, holding the mutex until all
+// the content is written.
+func (scr *synchronizedChecksummedReader) WriteTo(w io.Writer) (int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	if wt, ok := scr.cr.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, struct{ io.Reader }{scr.cr})
+}
+
+func (scr *synchronizedChecksummedReader) Verify() (bool, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Verify()
+}
+
+func (scr *synchronizedChecksummedReader) VerifyDetail() (bool, Range, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.VerifyDetail()
+}
+
+func (scr *synchronizedChecksummedReader) VerifyAll(progress func(verified int64, total int64)) ([]int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.VerifyAll(progress)
+}
+
+func (scr *synchronizedChecksummedReader) VerifyRange(offset int64, length int64) ([]int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.VerifyRange(offset, length)
+}
+
+func (scr *synchronizedChecksummedReader) ReadContext(ctx context.Context, v []byte) (int, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.ReadContext(ctx, v)
+}
+
+func (scr *synchronizedChecksummedReader) VerifyAllContext(ctx context.Context, progress func(verified int64, total int64)) ([]int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.VerifyAllContext(ctx, progress)
+}
+
+func (scr *synchronizedChecksummedReader) VerifyRangeContext(ctx context.Context, offset int64, length int64) ([]int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.VerifyRangeContext(ctx, offset, length)
+}
+
+// Warm only holds the mutex while starting; the warming itself reads with
+// io.ReaderAt and is already safe alongside other calls.
+func (scr *synchronizedChecksummedReader) Warm(ranges []Range) <-chan error {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Warm(ranges)
+}
+
+func (scr *synchronizedChecksummedReader) Close() error {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Close()
+}
+
+func (scr *synchronizedChecksummedReader) Size() (int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Size()
+}
+
+func (scr *synchronizedChecksummedReader) Stats() ChecksummedReaderStats {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Stats()
+}
+
+func (scr *synchronizedChecksummedReader) Describe() ChecksummedOverhead {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Describe()
+}
+
+func (scr *synchronizedChecksummedReader) VerifyAt(generation ChecksummedGeneration) ([]int64, error) {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.VerifyAt(generation)
+}
+
+func (scr *synchronizedChecksummedReader) CloseWithoutDelegate() error {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.CloseWithoutDelegate()
+}
+
+func (scr *synchronizedChecksummedReader) Skipped() []Range {
+	scr.lock.Lock()
+	defer scr.lock.Unlock()
+	return scr.cr.Skipped()
+}
+
+func (scr *synchronizedChecksummedReader) Unwrap() interface{} {
+	return scr.cr.Unwrap()
+}
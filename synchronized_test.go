@@ -0,0 +1,57 @@
+package brimio
+
+import (
+	"bytes"
+	"hash/crc32"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func TestSynchronizedChecksummedReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	cr, err := NewChecksummedReaderWith(bytes.NewReader(buf.Bytes()), WithInterval(16), WithSynchronized(), WithAutoVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cr.(*synchronizedChecksummedReader); !ok {
+		t.Fatalf("%T", cr)
+	}
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, 16)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if corrupted, err := cr.VerifyAll(nil); err != nil || corrupted != nil {
+					errs <- err
+					return
+				}
+				if size, err := cr.Size(); err != nil || size != 40 {
+					errs <- err
+					return
+				}
+				cr.Verify()
+				cr.Seek(int64(j%40), 0)
+				cr.ReadByte()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal("concurrent use failed", err)
+	}
+	cr.Seek(0, 0)
+	v, err := ioutil.ReadAll(cr)
+	if err != nil || string(v) != "12345678901234567890ghijklmnopqrstuvwxyz" {
+		t.Fatal(string(v), err)
+	}
+	if cr.Stats().BlocksVerified == 0 {
+		t.Fatal(cr.Stats())
+	}
+}
@@ -0,0 +1,56 @@
+package brimio
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Unwrapper is implemented by the package's readers and writers that wrap
+// another, such as ChecksummedReader, returning the one they delegate to, or
+// nil once closed. Stacking wrappers hides the capabilities of those
+// beneath, such as an *os.File's Fd or Stat; DelegateAs finds them again.
+type Unwrapper interface {
+	Unwrap() interface{}
+}
+
+// DelegateAs finds the first of v and the delegates reached through
+// successive Unwrap calls that is assignable to the value target points to,
+// setting target to it and returning true; it returns false if there is
+// none. It is to layered readers and writers as errors.As is to errors:
+//
+//	var f *os.File
+//	if brimio.DelegateAs(cr, &f) {
+//		fi, err := f.Stat()
+//		...
+//	}
+//
+// It panics if target is not a non-nil pointer to an interface or other
+// type.
+func DelegateAs(v interface{}, target interface{}) bool {
+	t := reflect.ValueOf(target)
+	if !t.IsValid() || t.Kind() != reflect.Ptr || t.IsNil() {
+		panic(fmt.Sprintf("target must be a non-nil pointer, not %T", target))
+	}
+	want := t.Type().Elem()
+	for v != nil {
+		if reflect.TypeOf(v).AssignableTo(want) {
+			t.Elem().Set(reflect.ValueOf(v))
+			return true
+		}
+		u, ok := v.(Unwrapper)
+		if !ok {
+			return false
+		}
+		v = u.Unwrap()
+	}
+	return false
+}
+
+// unwrapDelegate returns delegate for an Unwrap method, or nil if it has been
+// replaced by errDelegate on closing.
+func unwrapDelegate(delegate interface{}) interface{} {
+	if delegate == errDelegate {
+		return nil
+	}
+	return delegate
+}
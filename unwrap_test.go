@@ -0,0 +1,69 @@
+package brimio
+
+import (
+	"hash"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDelegateAs(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	reg := NewIORegistry()
+	cw, err := NewChecksummedWriterWithHeader(NewLabeledWriter(f, reg, "x"), 16, "crc32-ieee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cw.Write([]byte("12345678901234567890"))
+	if err = cw.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	var lw *LabeledWriter
+	if !DelegateAs(cw, &lw) {
+		t.Fatal("no LabeledWriter found")
+	}
+	var found *os.File
+	if !DelegateAs(cw, &found) || found != f {
+		t.Fatalf("%#v", found)
+	}
+	cw.CloseWithoutDelegate()
+	if DelegateAs(cw, &found) {
+		t.Fatal("found a delegate after closing")
+	}
+	// Through the header's and the leading placement's internal layers too.
+	cr, err := OpenChecksummed(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found = nil
+	if !DelegateAs(cr, &found) || found != f {
+		t.Fatalf("%#v", found)
+	}
+	cr = NewChecksummedReaderWithOptions(f, 16, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{Placement: ChecksumLeading})
+	found = nil
+	if !DelegateAs(cr, &found) || found != f {
+		t.Fatalf("%#v", found)
+	}
+	// The outermost match wins.
+	var c io.Closer
+	if !DelegateAs(cr, &c) || c != cr {
+		t.Fatalf("%#v", c)
+	}
+	var pw *PacedWriter
+	if DelegateAs(cr, &pw) {
+		t.Fatal("found a PacedWriter")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	DelegateAs(cr, pw)
+}
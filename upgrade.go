@@ -0,0 +1,188 @@
+package brimio
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UpgradeProgressName is the file UpgradeDirectory keeps in the directory it
+// is upgrading, listing the segments converted so far, so an interrupted
+// upgrade can resume. It is removed once the upgrade completes.
+const UpgradeProgressName = ".brimio-upgrade"
+
+// upgradeSuffix is appended to a segment's name for its converted copy until
+// that copy is swapped into place.
+const upgradeSuffix = ".upgrading"
+
+// UpgradeDirectory converts each segment in the directory root, those files
+// named as by SegmentName, from the checksummed layout from to the layout to,
+// such as a new interval or hashing function.
+//
+// Segments are converted one at a time, each verified as it is read and
+// written to a copy that then atomically replaces it, so readers can keep
+// working throughout: those with a segment already open keep reading the old
+// content, and those opening it afterward find it whole in the new layout.
+// Progress is recorded in UpgradeProgressName within root; calling
+// UpgradeDirectory again after an interruption resumes where it left off.
+// Segments must not be written to while being upgraded.
+//
+// A segment's trailing partial interval has no checksum in the layout from,
+// so it is carried over unverified. It must also end up as, or within, the
+// trailing partial interval of the layout to, rather than gain a checksum it
+// never had; a segment where it would not, such as when shrinking the
+// interval, is an error.
+//
+// If progress is not nil it is called after each segment with the count of
+// segments upgraded so far and the total. Conversion stops at the first
+// error, such as a segment that is not checksum valid, which is returned
+// wrapped with the segment's name.
+func UpgradeDirectory(root string, from ChecksummedConfig, to ChecksummedConfig, progress func(upgraded int, total int)) error {
+	if err := from.Validate(); err != nil {
+		return err
+	}
+	if err := to.Validate(); err != nil {
+		return err
+	}
+	logPath := filepath.Join(root, UpgradeProgressName)
+	upgraded, err := readUpgradeProgress(logPath)
+	if err != nil {
+		return err
+	}
+	// A segment recorded as converted may not have been swapped into place
+	// yet.
+	for name := range upgraded {
+		tmp := filepath.Join(root, name+upgradeSuffix)
+		if _, err = os.Stat(tmp); err == nil {
+			if err = os.Rename(tmp, filepath.Join(root, name)); err != nil {
+				return err
+			}
+		}
+	}
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, fi := range infos {
+		if !fi.Mode().IsRegular() || strings.HasSuffix(fi.Name(), upgradeSuffix) {
+			continue
+		}
+		if _, err = ParseSegmentName(fi.Name()); err == nil {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+	var done int
+	for _, name := range names {
+		if upgraded[name] {
+			done++
+		}
+	}
+	log, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+	for _, name := range names {
+		if upgraded[name] {
+			continue
+		}
+		path := filepath.Join(root, name)
+		if err = upgradeSegment(path, path+upgradeSuffix, from, to); err != nil {
+			return fmt.Errorf("error upgrading %s: %w", name, err)
+		}
+		// Recording the conversion before the swap means a resumed upgrade
+		// finishes the swap rather than converting the segment again.
+		if _, err = fmt.Fprintln(log, name); err != nil {
+			return err
+		}
+		if err = log.Sync(); err != nil {
+			return err
+		}
+		if err = os.Rename(path+upgradeSuffix, path); err != nil {
+			return err
+		}
+		syncDir(root)
+		done++
+		if progress != nil {
+			progress(done, len(names))
+		}
+	}
+	if err = log.Close(); err != nil {
+		return err
+	}
+	return os.Remove(logPath)
+}
+
+func readUpgradeProgress(path string) (map[string]bool, error) {
+	upgraded := make(map[string]bool)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return upgraded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if name := scanner.Text(); name != "" {
+			upgraded[name] = true
+		}
+	}
+	return upgraded, scanner.Err()
+}
+
+// upgradeSegment writes the content of the segment at path, verified against
+// the layout from, to a new file at tmp in the layout to, synced to stable
+// storage.
+func upgradeSegment(path string, tmp string, from ChecksummedConfig, to ChecksummedConfig) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	blockSize := int64(from.Interval + from.NewHash().Size())
+	if tail := fi.Size() % blockSize; tail > 0 && tail <= int64(from.Interval) {
+		length := fi.Size()/blockSize*int64(from.Interval) + tail
+		if tail > length%int64(to.Interval) {
+			return fmt.Errorf("unverified trailing %d bytes would be checksummed by interval %d", tail, to.Interval)
+		}
+	}
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	cw := NewChecksummedWriterHash(dst, to.Interval, to.NewHash)
+	if _, err = StripChecksums(cw, src, from.Interval, from.NewHash); err == nil {
+		err = cw.CloseWithoutDelegate()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if err2 := dst.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// syncDir makes a best effort to sync the directory at path, persisting
+// renames within it; not every platform supports this.
+func syncDir(path string) {
+	if d, err := os.Open(path); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
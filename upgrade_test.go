@@ -0,0 +1,133 @@
+package brimio
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpgradeDirectory(t *testing.T) {
+	root, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	from := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	to := ChecksummedConfig{Interval: 32, NewHash: func() hash.Hash { return NewCRC32C() }}
+	content := []string{"12345678901234567890ghijklmnopqrstuvwxyz", "abcdefghijklmnopqrstuvwxyz1234567890"}
+	var names []string
+	for i, c := range content {
+		name := SegmentName{Generation: 1, Sequence: uint64(i), Created: time.Unix(1000, 0)}.String() + ".data"
+		names = append(names, name)
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+		cw.Write([]byte(c))
+		cw.Close()
+		if err = ioutil.WriteFile(filepath.Join(root, name), buf.Bytes(), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = ioutil.WriteFile(filepath.Join(root, "other"), []byte("untouched"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an interrupted upgrade that converted the first segment but
+	// didn't swap it into place.
+	path := filepath.Join(root, names[0])
+	if err = upgradeSegment(path, path+upgradeSuffix, from, to); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(root, UpgradeProgressName), []byte(names[0]+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var calls [][2]int
+	err = UpgradeDirectory(root, from, to, func(upgraded int, total int) {
+		calls = append(calls, [2]int{upgraded, total})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] != [2]int{2, 2} {
+		t.Fatal(calls)
+	}
+	for i, name := range names {
+		f, err := os.Open(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		_, err = StripChecksums(out, f, to.Interval, to.NewHash)
+		f.Close()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if out.String() != content[i] {
+			t.Fatalf("%#v", out.String())
+		}
+	}
+	infos, err := ioutil.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 {
+		t.Fatal(infos)
+	}
+	if v, _ := ioutil.ReadFile(filepath.Join(root, "other")); string(v) != "untouched" {
+		t.Fatal(string(v))
+	}
+	// Upgrading again from the wrong layout fails without losing anything.
+	if err = UpgradeDirectory(root, from, to, nil); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(root, names[0]+upgradeSuffix)); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
+
+func TestUpgradeDirectoryTrailingPartial(t *testing.T) {
+	root, err := ioutil.TempDir("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	from := ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	to := ChecksummedConfig{Interval: 8, NewHash: func() hash.Hash { return crc32.NewIEEE() }}
+	name := SegmentName{Generation: 1, Created: time.Unix(1000, 0)}.String()
+	write := func(content string) {
+		buf := &bytes.Buffer{}
+		cw := NewChecksummedWriter(buf, 16, crc32.NewIEEE)
+		cw.Write([]byte(content))
+		cw.Close()
+		if err := ioutil.WriteFile(filepath.Join(root, name), buf.Bytes(), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The unverified trailing 8 bytes would be a whole interval of 8.
+	write("12345678901234567890ghijklmnopqrstuvwxyz")
+	if err = UpgradeDirectory(root, from, to, nil); err == nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(root, name+upgradeSuffix)); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(root, UpgradeProgressName))
+	// Trailing 4 bytes stay a partial interval of 8.
+	write("12345678901234567890ghijklmnopqrstuv")
+	if err = UpgradeDirectory(root, from, to, nil); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(root, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	out := &bytes.Buffer{}
+	if _, err = StripChecksums(out, f, to.Interval, to.NewHash); err != nil || out.String() != "12345678901234567890ghijklmnopqrstuv" {
+		t.Fatal(out.String(), err)
+	}
+}
@@ -0,0 +1,113 @@
+package brimio
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Range is a span of Length bytes of content starting at Offset.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// VerifiedBlockCache holds the content of recently verified intervals of a
+// single checksummed stream, keyed by interval index, so they may be served
+// again without rereading or reverifying them. It is safe for concurrent use.
+//
+// Given to a ChecksummedReader through ChecksummedReaderOptions; since
+// entries are keyed by index alone, a cache must not be shared by readers of
+// different content.
+type VerifiedBlockCache struct {
+	lock      sync.Mutex
+	maxBlocks int
+	blocks    map[int64]*list.Element
+	lru       *list.List
+}
+
+type verifiedBlock struct {
+	index   int64
+	content []byte
+}
+
+// NewVerifiedBlockCache returns a VerifiedBlockCache that will hold up to
+// maxBlocks intervals, discarding the least recently used beyond that.
+func NewVerifiedBlockCache(maxBlocks int) *VerifiedBlockCache {
+	if maxBlocks < 1 {
+		maxBlocks = 1
+	}
+	return &VerifiedBlockCache{
+		maxBlocks: maxBlocks,
+		blocks:    make(map[int64]*list.Element, maxBlocks),
+		lru:       list.New(),
+	}
+}
+
+// Len returns the number of intervals currently held.
+func (c *VerifiedBlockCache) Len() int {
+	c.lock.Lock()
+	n := c.lru.Len()
+	c.lock.Unlock()
+	return n
+}
+
+// get copies the content of the interval at index into v, returning the
+// length copied and whether the interval was held.
+func (c *VerifiedBlockCache) get(index int64, v []byte) (int, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e := c.blocks[index]
+	if e == nil {
+		return 0, false
+	}
+	c.lru.MoveToFront(e)
+	return copy(v, e.Value.(*verifiedBlock).content), true
+}
+
+func (c *VerifiedBlockCache) has(index int64) bool {
+	c.lock.Lock()
+	_, ok := c.blocks[index]
+	c.lock.Unlock()
+	return ok
+}
+
+// put stores a copy of the content of the interval at index.
+func (c *VerifiedBlockCache) put(index int64, content []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e := c.blocks[index]; e != nil {
+		c.lru.MoveToFront(e)
+		return
+	}
+	var b *verifiedBlock
+	if c.lru.Len() >= c.maxBlocks {
+		e := c.lru.Back()
+		b = e.Value.(*verifiedBlock)
+		delete(c.blocks, b.index)
+		c.lru.Remove(e)
+		b.index = index
+		b.content = append(b.content[:0], content...)
+	} else {
+		b = &verifiedBlock{index: index, content: append([]byte(nil), content...)}
+	}
+	c.blocks[index] = c.lru.PushFront(b)
+}
+
+// Invalidate discards the interval at index, if held, such as after its
+// content is changed.
+func (c *VerifiedBlockCache) Invalidate(index int64) {
+	c.lock.Lock()
+	if e := c.blocks[index]; e != nil {
+		delete(c.blocks, index)
+		c.lru.Remove(e)
+	}
+	c.lock.Unlock()
+}
+
+// Clear discards every interval held.
+func (c *VerifiedBlockCache) Clear() {
+	c.lock.Lock()
+	c.blocks = make(map[int64]*list.Element, c.maxBlocks)
+	c.lru.Init()
+	c.lock.Unlock()
+}
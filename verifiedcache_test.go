@@ -0,0 +1,103 @@
+package brimio
+
+import (
+	"bytes"
+	"hash"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+func TestVerifiedBlockCacheWarm(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 4, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	b[9] = 'X'
+	var corrupted []int64
+	cache := NewVerifiedBlockCache(3)
+	opts := &ChecksummedReaderOptions{
+		Cache:        cache,
+		OnCorruption: func(blockIndex int64, offset int64) { corrupted = append(corrupted, blockIndex) },
+	}
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 4, func() hash.Hash { return crc32.NewIEEE() }, opts)
+	if err := <-cr.Warm([]Range{{2, 8}, {100, 4}}); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 2 {
+		t.Fatal(cache.Len())
+	}
+	if len(corrupted) != 1 || corrupted[0] != 1 {
+		t.Fatal(corrupted)
+	}
+	// Clobber the underlying content of a cached interval to prove Read is
+	// served from the cache.
+	b[16] = 'Y'
+	cr.Seek(8, 0)
+	v := make([]byte, 4)
+	if _, err := io.ReadFull(cr, v); err != nil {
+		t.Fatal(err)
+	}
+	if string(v) != "9012" {
+		t.Fatalf("%#v", string(v))
+	}
+	cr.Seek(24, 0)
+	if _, err := io.ReadFull(cr, v); err != nil {
+		t.Fatal(err)
+	}
+	cr.Seek(28, 0)
+	if _, err := io.ReadFull(cr, v); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 3 {
+		t.Fatal(cache.Len())
+	}
+	cr = NewChecksummedReader(bytes.NewReader(b), 4, crc32.NewIEEE)
+	if err := <-cr.Warm([]Range{{0, 4}}); err == nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifiedBlockCacheVerify(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewChecksummedWriter(buf, 4, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	b := buf.Bytes()
+	cache := NewVerifiedBlockCache(3)
+	cr := NewChecksummedReaderWithOptions(bytes.NewReader(b), 4, func() hash.Hash { return crc32.NewIEEE() }, &ChecksummedReaderOptions{Cache: cache})
+	cr.Seek(9, 0)
+	if ok, err := cr.Verify(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if cache.Len() != 1 {
+		t.Fatal(cache.Len())
+	}
+	// Clobber the underlying content to prove Verify short-circuits.
+	b[16] = 'Y'
+	if ok, err := cr.Verify(); err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	if s := cr.Stats(); s.BlocksVerified != 1 {
+		t.Fatal(s.BlocksVerified)
+	}
+	cache.Invalidate(2)
+	if ok, err := cr.Verify(); err != nil || ok {
+		t.Fatal(ok, err)
+	}
+	if o, _ := cr.Seek(0, 1); o != 9 {
+		t.Fatal(o)
+	}
+	cr.Seek(0, 0)
+	cr.Verify()
+	cr.Seek(4, 0)
+	cr.Verify()
+	if cache.Len() != 2 {
+		t.Fatal(cache.Len())
+	}
+	cache.Clear()
+	if cache.Len() != 0 {
+		t.Fatal(cache.Len())
+	}
+}
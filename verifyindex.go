@@ -0,0 +1,105 @@
+package brimio
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// FileFingerprint identifies a version of a file's content cheaply, by its
+// size and modification time, so a recorded verification can be discarded
+// once the file changes.
+type FileFingerprint struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// FingerprintFile returns the FileFingerprint of the file at path.
+func FingerprintFile(path string) (FileFingerprint, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return FileFingerprint{}, err
+	}
+	return FileFingerprint{Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+// VerificationStatus is the outcome of verifying a file, as recorded in a
+// VerificationIndex.
+type VerificationStatus struct {
+	Fingerprint FileFingerprint
+	Verified    time.Time
+	// Valid is whether every interval was checksum valid.
+	Valid bool
+}
+
+// VerificationIndex records when files were last verified, and with what
+// result, so code opening a file can skip verifying it again if it was
+// verified recently, such as by a Scrubber. It is safe for concurrent use and
+// favors lookups, which only take a read lock.
+type VerificationIndex struct {
+	// TTL is how long a recorded verification is trusted; forever if 0.
+	TTL time.Duration
+	// Clock is the source of time for recording and expiry; SystemClock if
+	// nil.
+	Clock    Clock
+	lock     sync.RWMutex
+	statuses map[string]VerificationStatus
+}
+
+// NewVerificationIndex returns an empty VerificationIndex trusting recorded
+// verifications for ttl.
+func NewVerificationIndex(ttl time.Duration) *VerificationIndex {
+	return &VerificationIndex{TTL: ttl, statuses: make(map[string]VerificationStatus)}
+}
+
+// Record notes that the file at path, as of fingerprint, was verified just
+// now with the result given.
+func (vi *VerificationIndex) Record(path string, fingerprint FileFingerprint, valid bool) {
+	s := VerificationStatus{Fingerprint: fingerprint, Verified: clockOrSystem(vi.Clock).Now(), Valid: valid}
+	vi.lock.Lock()
+	vi.statuses[path] = s
+	vi.lock.Unlock()
+}
+
+// Lookup returns the recorded VerificationStatus of the file at path if it
+// was recorded with the same fingerprint and within the TTL; otherwise the
+// bool is false and the file should be verified again.
+func (vi *VerificationIndex) Lookup(path string, fingerprint FileFingerprint) (VerificationStatus, bool) {
+	vi.lock.RLock()
+	s, ok := vi.statuses[path]
+	vi.lock.RUnlock()
+	if !ok || s.Fingerprint.Size != fingerprint.Size || !s.Fingerprint.ModTime.Equal(fingerprint.ModTime) {
+		return VerificationStatus{}, false
+	}
+	if vi.TTL > 0 && clockOrSystem(vi.Clock).Now().Sub(s.Verified) > vi.TTL {
+		return VerificationStatus{}, false
+	}
+	return s, true
+}
+
+// Invalidate forgets any recorded verification of the file at path, such as
+// after repairing or rewriting it.
+func (vi *VerificationIndex) Invalidate(path string) {
+	vi.lock.Lock()
+	delete(vi.statuses, path)
+	vi.lock.Unlock()
+}
+
+// Prune forgets recorded verifications older than the TTL, returning how
+// many were removed.
+func (vi *VerificationIndex) Prune() int {
+	if vi.TTL <= 0 {
+		return 0
+	}
+	now := clockOrSystem(vi.Clock).Now()
+	vi.lock.Lock()
+	defer vi.lock.Unlock()
+	var n int
+	for path, s := range vi.statuses {
+		if now.Sub(s.Verified) > vi.TTL {
+			delete(vi.statuses, path)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package brimio
+
+import (
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVerificationIndex(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	vi := NewVerificationIndex(time.Minute)
+	vi.Clock = clock
+	fp := FileFingerprint{Size: 10, ModTime: time.Unix(500, 0)}
+	if _, ok := vi.Lookup("a", fp); ok {
+		t.Fatal(ok)
+	}
+	vi.Record("a", fp, true)
+	vi.Record("b", fp, false)
+	if s, ok := vi.Lookup("a", fp); !ok || !s.Valid || !s.Verified.Equal(time.Unix(1000, 0)) {
+		t.Fatal(s, ok)
+	}
+	if s, ok := vi.Lookup("b", fp); !ok || s.Valid {
+		t.Fatal(s, ok)
+	}
+	if _, ok := vi.Lookup("a", FileFingerprint{Size: 11, ModTime: fp.ModTime}); ok {
+		t.Fatal(ok)
+	}
+	vi.Invalidate("b")
+	if _, ok := vi.Lookup("b", fp); ok {
+		t.Fatal(ok)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, ok := vi.Lookup("a", fp); ok {
+		t.Fatal(ok)
+	}
+	if n := vi.Prune(); n != 1 {
+		t.Fatal(n)
+	}
+}
+
+func TestScrubberVerificationIndex(t *testing.T) {
+	f, err := ioutil.TempFile("", "brimio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	cw := NewChecksummedWriter(f, 16, crc32.NewIEEE)
+	cw.Write([]byte("12345678901234567890ghijklmnopqrstuvwxyz"))
+	cw.Close()
+	vi := NewVerificationIndex(time.Hour)
+	s, err := NewFileScrubber(f.Name(), ChecksummedConfig{Interval: 16, NewHash: func() hash.Hash { return crc32.NewIEEE() }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Index = vi
+	s.Start()
+	if err = s.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := FingerprintFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := vi.Lookup(f.Name(), fp); !ok || !st.Valid {
+		t.Fatal(st, ok)
+	}
+}